@@ -0,0 +1,162 @@
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Format is an archive format, detected from the archive's first bytes rather than its file name
+type Format string
+
+const (
+	FormatZip     Format = "zip"
+	FormatTar     Format = "tar"
+	FormatTarGzip Format = "tar.gz"
+	FormatTarZstd Format = "tar.zst"
+)
+
+var (
+	zipMagic      = []byte("PK\x03\x04")
+	emptyZipMagic = []byte("PK\x05\x06")
+	gzipMagic     = []byte{0x1f, 0x8b}
+	zstdMagic     = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	// tarMagic is at offset 257 of the first header, in both the POSIX ("ustar\x00") and GNU ("ustar ") forms
+	tarMagic       = []byte("ustar")
+	tarMagicOffset = 257
+)
+
+// Detect identifies the format of the archive br reads without consuming any of it. A gzip or zstd stream
+// is taken to be a compressed tar; FromTar rejects it if it turns out not to be.
+func Detect(br *bufio.Reader) (Format, error) {
+	head, err := br.Peek(tarMagicOffset + len(tarMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read archive: %v", err)
+	}
+
+	switch {
+	case bytes.HasPrefix(head, zipMagic), bytes.HasPrefix(head, emptyZipMagic):
+		return FormatZip, nil
+	case bytes.HasPrefix(head, gzipMagic):
+		return FormatTarGzip, nil
+	case bytes.HasPrefix(head, zstdMagic):
+		return FormatTarZstd, nil
+	case len(head) >= tarMagicOffset+len(tarMagic) && bytes.Equal(head[tarMagicOffset:], tarMagic):
+		return FormatTar, nil
+	}
+	return "", fmt.Errorf("%w: expected a zip, tar, tar.gz or tar.zst archive", ErrInvalidArchive)
+}
+
+// FromTar converts the tar stream in r, compressed as format says, to a zip written to dst, applying the
+// same checks and exclude rules as Sanitize. The archive is checked as it streams by, so dst holds partial
+// output when it is rejected.
+func FromTar(r io.Reader, format Format, dst io.Writer, options Options) (*Report, error) {
+	in := &countingReader{r: r}
+
+	var stream io.Reader
+	switch format {
+	case FormatTar:
+		stream = in
+	case FormatTarGzip:
+		gz, err := gzip.NewReader(in)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		defer gz.Close()
+		stream = gz
+	case FormatTarZstd:
+		zr, err := zstd.NewReader(in, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		defer zr.Close()
+		stream = zr
+	default:
+		return nil, fmt.Errorf("%w: %s is not a tar format", ErrInvalidArchive, format)
+	}
+
+	// Excluded entries are decompressed too, on their way past, so the ratio is checked over the whole
+	// stream rather than only over the kept files
+	tr := tar.NewReader(&ratioReader{r: stream, in: in, maxRatio: options.MaxRatio})
+	s := newSanitizer(format, options)
+	cw := &countingWriter{w: dst}
+	zw := newZipWriter(cw)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if errors.Is(err, ErrUnsafeArchive) {
+				return nil, err
+			}
+			// Keep the reader's own error visible, it may be the upload's size limit rather than the archive
+			return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+		}
+
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		mode := header.FileInfo().Mode()
+		if header.Typeflag == tar.TypeLink {
+			mode |= fs.ModeSymlink
+		}
+
+		name, keep, err := s.admit(header.Name, mode, uint64(max(header.Size, 0)))
+		if err != nil {
+			return nil, err
+		}
+		if !keep {
+			continue
+		}
+
+		w, err := createEntry(zw, name, header.ModTime, mode)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(w, tr); err != nil {
+			return nil, copyError(name, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %v", err)
+	}
+	return s.finish(in.n, cw.n), nil
+}
+
+// ratioReader fails once the bytes decompressed from in outgrow it by more than maxRatio
+type ratioReader struct {
+	r        io.Reader
+	in       *countingReader
+	maxRatio float64
+	n        uint64
+}
+
+func (rr *ratioReader) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	rr.n += uint64(n)
+	if exceedsRatio(rr.n, uint64(rr.in.n), rr.maxRatio) {
+		return n, fmt.Errorf("%w: %d compressed bytes expand to more than %d bytes", ErrUnsafeArchive, rr.in.n, rr.n)
+	}
+	return n, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,220 @@
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// buildTar writes entries as a tar archive compressed for format
+func buildTar(t *testing.T, format Format, entries ...testEntry) []byte {
+	t.Helper()
+
+	var raw bytes.Buffer
+	tw := tar.NewWriter(&raw)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: int64(e.mode.Perm()), ModTime: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+		switch {
+		case e.mode.IsDir():
+			header.Typeflag = tar.TypeDir
+		case e.mode&fs.ModeSymlink != 0:
+			header.Typeflag, header.Linkname = tar.TypeSymlink, e.body
+		case e.mode&fs.ModeNamedPipe != 0:
+			header.Typeflag = tar.TypeFifo
+		default:
+			header.Typeflag, header.Size = tar.TypeReg, int64(len(e.body))
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := io.WriteString(tw, e.body); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return compress(t, format, raw.Bytes())
+}
+
+func compress(t *testing.T, format Format, data []byte) []byte {
+	t.Helper()
+
+	var out bytes.Buffer
+	var w io.WriteCloser
+	switch format {
+	case FormatTar:
+		return data
+	case FormatTarGzip:
+		w = gzip.NewWriter(&out)
+	case FormatTarZstd:
+		zw, err := zstd.NewWriter(&out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w = zw
+	default:
+		t.Fatalf("cannot build a %s archive", format)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+var tarFormats = []Format{FormatTar, FormatTarGzip, FormatTarZstd}
+
+func TestDetect(t *testing.T) {
+	archives := map[Format][]byte{
+		FormatZip: buildZip(t, file("main.go", "package main")),
+		// An empty zip is only its end of central directory record
+		FormatZip + " (empty)": buildZip(t),
+	}
+	for _, format := range tarFormats {
+		archives[format] = buildTar(t, format, file("main.go", "package main"))
+	}
+
+	for name, data := range archives {
+		want := name
+		if name == FormatZip+" (empty)" {
+			want = FormatZip
+		}
+		br := bufio.NewReader(bytes.NewReader(data))
+		got, err := Detect(br)
+		if err != nil || got != want {
+			t.Errorf("Detect(%s) = %q, %v; want %q", name, got, err, want)
+			continue
+		}
+		// Nothing was consumed
+		if rest, _ := io.ReadAll(br); !bytes.Equal(rest, data) {
+			t.Errorf("Detect(%s) consumed the archive", name)
+		}
+	}
+
+	for name, data := range map[string][]byte{"empty": nil, "text": []byte("package main\n"), "pdf": []byte("%PDF-1.7")} {
+		if _, err := Detect(bufio.NewReader(bytes.NewReader(data))); !errors.Is(err, ErrInvalidArchive) {
+			t.Errorf("Detect(%s) = %v, want %v", name, err, ErrInvalidArchive)
+		}
+	}
+}
+
+func TestFromTar(t *testing.T) {
+	entries := []testEntry{
+		{name: "./", mode: fs.ModeDir | 0o755},
+		file("./src/main.go", "package main"),
+		file("src/build/build.go", "package build"),
+		file("build/output.txt", "out"),
+		file("web/node_modules/left-pad/index.js", "module.exports = 1"),
+		{name: "web/node_modules/.bin/left-pad", mode: fs.ModeSymlink | 0o777, body: "../left-pad/index.js"},
+	}
+	want := map[string]string{
+		"src/main.go":        "package main",
+		"src/build/build.go": "package build",
+	}
+
+	for _, format := range tarFormats {
+		t.Run(string(format), func(t *testing.T) {
+			data := buildTar(t, format, entries...)
+
+			var out bytes.Buffer
+			report, err := FromTar(bytes.NewReader(data), format, &out, testOptions(DefaultExcludes...))
+			if err != nil {
+				t.Fatalf("FromTar() = %v", err)
+			}
+
+			if got := readZip(t, out.Bytes()); !reflect.DeepEqual(got, want) {
+				t.Fatalf("converted files = %v, want %v", got, want)
+			}
+			if report.Format != format || report.Entries != 6 || report.KeptFiles != 2 || report.StrippedFiles != 3 {
+				t.Fatalf("report = %+v", report)
+			}
+			if report.OriginalSize != int64(len(data)) || report.Size != int64(out.Len()) {
+				t.Fatalf("report sizes = %d -> %d, want %d -> %d", report.OriginalSize, report.Size, len(data), out.Len())
+			}
+		})
+	}
+}
+
+func TestFromTarRejects(t *testing.T) {
+	zeros := string(make([]byte, 4<<20))
+
+	tests := []struct {
+		name    string
+		entries []testEntry
+		wantErr error
+	}{
+		{name: "parent directory", entries: []testEntry{file("../evil.sh", "x")}, wantErr: ErrUnsafeArchive},
+		{name: "absolute path", entries: []testEntry{file("/etc/cron.d/evil", "x")}, wantErr: ErrUnsafeArchive},
+		{name: "symlink", entries: []testEntry{{name: "src/passwd", mode: fs.ModeSymlink | 0o777, body: "/etc/passwd"}}, wantErr: ErrUnsafeArchive},
+		{name: "fifo", entries: []testEntry{{name: "src/pipe", mode: fs.ModeNamedPipe | 0o644}}, wantErr: ErrUnsafeArchive},
+		{name: "duplicate entry", entries: []testEntry{file("a.go", "1"), file("./a.go", "2")}, wantErr: ErrUnsafeArchive},
+		// The bomb is stripped, but it is decompressed on its way past all the same
+		{name: "compression ratio", entries: []testEntry{file("node_modules/bomb.txt", zeros)}, wantErr: ErrUnsafeArchive},
+	}
+
+	for _, format := range tarFormats {
+		for _, tt := range tests {
+			if format == FormatTar && tt.name == "compression ratio" {
+				// An uncompressed tar cannot expand
+				continue
+			}
+			t.Run(string(format)+" "+tt.name, func(t *testing.T) {
+				data := buildTar(t, format, tt.entries...)
+				_, err := FromTar(bytes.NewReader(data), format, io.Discard, testOptions(DefaultExcludes...))
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("FromTar() = %v, want %v", err, tt.wantErr)
+				}
+			})
+		}
+	}
+}
+
+func TestFromTarHardLink(t *testing.T) {
+	var raw bytes.Buffer
+	tw := tar.NewWriter(&raw)
+	tw.WriteHeader(&tar.Header{Name: "src/shadow", Typeflag: tar.TypeLink, Linkname: "/etc/shadow", Mode: 0o644})
+	tw.Close()
+
+	if _, err := FromTar(&raw, FormatTar, io.Discard, testOptions()); !errors.Is(err, ErrUnsafeArchive) {
+		t.Fatalf("FromTar() = %v, want %v", err, ErrUnsafeArchive)
+	}
+}
+
+func TestFromTarInvalidArchive(t *testing.T) {
+	tarball := buildTar(t, FormatTar, file("main.go", "package main"))
+	tests := []struct {
+		name   string
+		format Format
+		data   []byte
+	}{
+		{name: "gzip of a text file", format: FormatTarGzip, data: compress(t, FormatTarGzip, []byte("not a tar archive, just some text that is long enough"))},
+		{name: "truncated gzip", format: FormatTarGzip, data: compress(t, FormatTarGzip, tarball)[:30]},
+		{name: "not gzip", format: FormatTarGzip, data: []byte("plain text")},
+		{name: "not zstd", format: FormatTarZstd, data: []byte("plain text")},
+		{name: "truncated tar", format: FormatTar, data: tarball[:515]},
+		{name: "zip", format: FormatZip, data: buildZip(t, file("main.go", "package main"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := FromTar(bytes.NewReader(tt.data), tt.format, io.Discard, testOptions()); !errors.Is(err, ErrInvalidArchive) {
+				t.Fatalf("FromTar() = %v, want %v", err, ErrInvalidArchive)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package archive
+
+import (
+	"path"
+	"strings"
+)
+
+// rule is one exclude glob. A glob prefixed with "!" re-includes what an earlier rule excluded.
+type rule struct {
+	pattern  string
+	negate   bool
+	anchored bool
+	segments []string
+}
+
+func parseRule(glob string) (rule, bool) {
+	glob = strings.TrimSpace(glob)
+	r := rule{}
+	if strings.HasPrefix(glob, "!") {
+		r.negate = true
+		glob = glob[1:]
+	}
+	glob = strings.ReplaceAll(glob, "\\", "/")
+	rooted := strings.HasPrefix(glob, "/")
+	glob = strings.Trim(glob, "/")
+	if glob == "" {
+		return rule{}, false
+	}
+	if _, err := path.Match(glob, ""); err != nil {
+		return rule{}, false
+	}
+
+	r.pattern = glob
+	r.anchored = rooted || strings.Contains(glob, "/")
+	r.segments = strings.Split(glob, "/")
+	if rooted {
+		// Only segments are matched against once anchored; the pattern is kept as written for reports
+		r.pattern = "/" + glob
+	}
+	return r, true
+}
+
+// match reports whether the rule matches name or one of its parent directories, returning the matched
+// prefix: "web/node_modules" for "node_modules" against "web/node_modules/left-pad/index.js".
+//
+// A glob without a slash, like "node_modules" or "*.exe", matches a file or directory of that name at any
+// depth. A glob with a slash, including a leading one as in "/build", is anchored at the archive root, and
+// "**" in it matches any number of directories.
+func (r rule) match(name string) (string, bool) {
+	parts := strings.Split(name, "/")
+
+	if !r.anchored {
+		for i, part := range parts {
+			if ok, _ := path.Match(r.pattern, part); ok {
+				return strings.Join(parts[:i+1], "/"), true
+			}
+		}
+		return "", false
+	}
+
+	for i := 1; i <= len(parts); i++ {
+		if matchSegments(r.segments, parts[:i]) {
+			return strings.Join(parts[:i], "/"), true
+		}
+	}
+	return "", false
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(parts); skip++ {
+				if matchSegments(pattern[1:], parts[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// excluded applies rules in order, the last matching one deciding, and returns the rule and prefix that excluded name
+func excluded(rules []rule, name string) (rule, string, bool) {
+	var last rule
+	var prefix string
+	matched := false
+	for _, r := range rules {
+		if p, ok := r.match(name); ok {
+			last, prefix, matched = r, p, true
+		}
+	}
+	if !matched || last.negate {
+		return rule{}, "", false
+	}
+	return last, prefix, true
+}
@@ -0,0 +1,91 @@
+package archive
+
+import "testing"
+
+func TestRuleMatch(t *testing.T) {
+	tests := []struct {
+		glob       string
+		name       string
+		wantPrefix string
+		wantMatch  bool
+	}{
+		{glob: "node_modules", name: "node_modules/left-pad/index.js", wantPrefix: "node_modules", wantMatch: true},
+		{glob: "node_modules", name: "web/node_modules/left-pad/index.js", wantPrefix: "web/node_modules", wantMatch: true},
+		{glob: "*.exe", name: "tools/setup.exe", wantPrefix: "tools/setup.exe", wantMatch: true},
+		{glob: "*.exe", name: "tools/setup.exe.md"},
+		{glob: "/build", name: "build/app.jar", wantPrefix: "build", wantMatch: true},
+		{glob: "/build", name: "src/build/Gradle.java"},
+		{glob: "/build", name: "cmd/build"},
+		{glob: "/bin", name: "bin", wantPrefix: "bin", wantMatch: true},
+		{glob: "/vendor", name: "app/vendor/autoload.php"},
+		{glob: "docs/*.pdf", name: "docs/guide.pdf", wantPrefix: "docs/guide.pdf", wantMatch: true},
+		{glob: "docs/*.pdf", name: "web/docs/guide.pdf"},
+		{glob: "**/testdata", name: "pkg/parser/testdata/input.txt", wantPrefix: "pkg/parser/testdata", wantMatch: true},
+		{glob: "**/testdata", name: "testdata/input.txt", wantPrefix: "testdata", wantMatch: true},
+		{glob: "\\out\\", name: "out/report.html", wantPrefix: "out", wantMatch: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.glob+" "+tt.name, func(t *testing.T) {
+			r, ok := parseRule(tt.glob)
+			if !ok {
+				t.Fatalf("parseRule(%q) rejected the glob", tt.glob)
+			}
+			prefix, matched := r.match(tt.name)
+			if matched != tt.wantMatch || prefix != tt.wantPrefix {
+				t.Fatalf("match(%q) = %q, %t; want %q, %t", tt.name, prefix, matched, tt.wantPrefix, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	for _, glob := range []string{"", "/", "!", "[", "  "} {
+		if _, ok := parseRule(glob); ok {
+			t.Errorf("parseRule(%q) accepted the glob", glob)
+		}
+	}
+
+	r, ok := parseRule("!/vendor")
+	if !ok || !r.negate || !r.anchored || r.pattern != "/vendor" {
+		t.Fatalf("parseRule(%q) = %+v, %t; want a negated rule anchored at the root", "!/vendor", r, ok)
+	}
+}
+
+func TestDefaultExcludes(t *testing.T) {
+	var rules []rule
+	for _, glob := range DefaultExcludes {
+		r, ok := parseRule(glob)
+		if !ok {
+			t.Fatalf("DefaultExcludes holds an invalid glob %q", glob)
+		}
+		rules = append(rules, r)
+	}
+
+	tests := []struct {
+		name         string
+		wantExcluded bool
+	}{
+		{name: "src/main.go"},
+		{name: "pkg/build/build.go"},
+		{name: "cmd/bin/main.go"},
+		{name: "src/main/java/com/example/target/Target.java"},
+		{name: "internal/out/writer.go"},
+		{name: "web/src/dist/index.ts"},
+		{name: "services/obj/model.cs"},
+		{name: "lib/vendor/adapter.rb"},
+		{name: "build/libs/app.jar", wantExcluded: true},
+		{name: "target/classes/App.class", wantExcluded: true},
+		{name: "vendor/github.com/gin-gonic/gin/gin.go", wantExcluded: true},
+		{name: "dist/bundle.js", wantExcluded: true},
+		{name: "web/node_modules/react/index.js", wantExcluded: true},
+		{name: "services/api/.git/config", wantExcluded: true},
+		{name: "tools/helper.exe", wantExcluded: true},
+	}
+
+	for _, tt := range tests {
+		if _, _, got := excluded(rules, tt.name); got != tt.wantExcluded {
+			t.Errorf("excluded(%q) = %t, want %t", tt.name, got, tt.wantExcluded)
+		}
+	}
+}
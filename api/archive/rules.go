@@ -0,0 +1,100 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultMaxEntries          = 100000
+	defaultMaxRatio            = 100
+	defaultMaxUncompressedSize = 10 << 30 // 10 GiB
+)
+
+// DefaultExcludes strips dependencies, build output and binaries, none of which the scanners need. Names
+// that are just as often source packages, like "build" or "bin", are only stripped at the archive root.
+var DefaultExcludes = []string{
+	// Dependencies
+	"node_modules", "bower_components", ".venv", "__pycache__", "/vendor", "/venv",
+	// Build output
+	".next", ".gradle", "/dist", "/build", "/target", "/bin", "/obj", "/out",
+	// VCS metadata
+	".git", ".svn", ".hg",
+	// Binaries
+	"*.exe", "*.dll", "*.so", "*.dylib", "*.o", "*.a", "*.lib", "*.class", "*.jar", "*.war", "*.ear", "*.pyc", "*.wasm",
+}
+
+// Options controls what Sanitize accepts and what it strips
+type Options struct {
+	// MaxEntries is the most entries, files and directories, the archive may hold
+	MaxEntries int
+	// MaxRatio is the highest uncompressed to compressed size ratio allowed, per entry and for the whole archive
+	MaxRatio float64
+	// MaxUncompressedSize is the most bytes the kept entries may expand to
+	MaxUncompressedSize int64
+	// Excludes are globs of entries to strip, applied in order; see rule.match for the syntax
+	Excludes []string
+}
+
+// Rules are the sanitization options of every project, read from the environment
+type Rules struct {
+	limits   Options
+	projects map[string][]string
+}
+
+// RulesFromEnv reads ARCHIVE_MAX_ENTRIES, ARCHIVE_MAX_RATIO and ARCHIVE_MAX_UNCOMPRESSED_BYTES, the limits;
+// ARCHIVE_EXCLUDES, comma-separated globs added to DefaultExcludes for every project; and
+// ARCHIVE_PROJECT_EXCLUDES, a JSON object of project name to globs, e.g. {"payments-api": ["!vendor", "testdata"]}.
+// On a malformed setting it returns the rules without it along with the error.
+func RulesFromEnv() (*Rules, error) {
+	rules := &Rules{
+		limits: Options{
+			MaxEntries:          defaultMaxEntries,
+			MaxRatio:            defaultMaxRatio,
+			MaxUncompressedSize: defaultMaxUncompressedSize,
+			Excludes:            append([]string{}, DefaultExcludes...),
+		},
+		projects: make(map[string][]string),
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("ARCHIVE_MAX_ENTRIES")); err == nil && v > 0 {
+		rules.limits.MaxEntries = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("ARCHIVE_MAX_RATIO"), 64); err == nil && v > 0 {
+		rules.limits.MaxRatio = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv("ARCHIVE_MAX_UNCOMPRESSED_BYTES"), 10, 64); err == nil && v > 0 {
+		rules.limits.MaxUncompressedSize = v
+	}
+	rules.limits.Excludes = append(rules.limits.Excludes, SplitGlobs(os.Getenv("ARCHIVE_EXCLUDES"))...)
+
+	if v := os.Getenv("ARCHIVE_PROJECT_EXCLUDES"); v != "" {
+		if err := json.Unmarshal([]byte(v), &rules.projects); err != nil {
+			return rules, fmt.Errorf("invalid ARCHIVE_PROJECT_EXCLUDES: %v", err)
+		}
+	}
+
+	return rules, nil
+}
+
+// For returns the options for an archive of projectName, with extra globs, such as those sent with the
+// request, applied last
+func (r *Rules) For(projectName string, extra ...string) Options {
+	options := r.limits
+	options.Excludes = append(append(append([]string{}, r.limits.Excludes...), r.projects[projectName]...), extra...)
+	return options
+}
+
+// SplitGlobs splits a comma-separated list of globs, dropping empty ones
+func SplitGlobs(value string) []string {
+	var globs []string
+	for _, glob := range strings.Split(value, ",") {
+		if glob = strings.TrimSpace(glob); glob != "" {
+			globs = append(globs, glob)
+		}
+	}
+	return globs
+}
@@ -0,0 +1,296 @@
+// Package archive inspects source archives before they are sent to Cx1 and re-packs them without the
+// entries the scanners have no use for.
+//
+// Sanitize rejects archives that are unsafe to unpack: entries that escape the archive root (zip-slip),
+// links, and zip bombs, detected by entry count, compression ratio and total uncompressed size. FromTar
+// applies the same checks while converting tar, tar.gz and tar.zst archives to zip.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// ratioThreshold is the size below which an entry's compression ratio is not checked; small files of
+	// repeated bytes legitimately compress far beyond any sensible limit
+	ratioThreshold = 1 << 20
+	// maxReportedPaths bounds the stripped paths listed in a report
+	maxReportedPaths = 100
+)
+
+var (
+	ErrInvalidArchive = errors.New("invalid or unsupported archive")
+	ErrUnsafeArchive  = errors.New("unsafe archive")
+)
+
+// Report describes what Sanitize kept and stripped
+type Report struct {
+	// Format is the format the archive was received in; it is always sent to Cx1 as a zip
+	Format        Format `json:"format"`
+	Entries       int    `json:"entries"`
+	KeptFiles     int    `json:"kept_files"`
+	OriginalSize  int64  `json:"original_size"`
+	Size          int64  `json:"size"`
+	StrippedFiles int    `json:"stripped_files"`
+	// StrippedBytes is the uncompressed size of the stripped files
+	StrippedBytes int64      `json:"stripped_bytes"`
+	Stripped      []Stripped `json:"stripped,omitempty"`
+	// Truncated is set when more paths were stripped than are listed
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Stripped is a file or directory removed from the archive and the rule that removed it
+type Stripped struct {
+	Path  string `json:"path"`
+	Rule  string `json:"rule"`
+	Files int    `json:"files"`
+	Bytes int64  `json:"bytes"`
+}
+
+type entry struct {
+	file *zip.File
+	name string
+}
+
+// Sanitize checks the zip archive in src and writes it to dst without the entries matching
+// options.Excludes. Every entry is checked before anything is written, but dst holds partial output
+// when an entry turns out to be corrupt while it is repacked.
+func Sanitize(src io.ReaderAt, size int64, dst io.Writer, options Options) (*Report, error) {
+	zr, err := zip.NewReader(src, size)
+	if err != nil && !errors.Is(err, zip.ErrInsecurePath) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	s := newSanitizer(FormatZip, options)
+	var kept []entry
+	for _, f := range zr.File {
+		name, keep, err := s.admit(f.Name, f.Mode(), f.UncompressedSize64)
+		if err != nil {
+			return nil, err
+		}
+		if !keep {
+			continue
+		}
+		if exceedsRatio(f.UncompressedSize64, f.CompressedSize64, options.MaxRatio) {
+			return nil, fmt.Errorf("%w: entry %q expands from %d to %d bytes", ErrUnsafeArchive, name, f.CompressedSize64, f.UncompressedSize64)
+		}
+		kept = append(kept, entry{file: f, name: name})
+	}
+
+	if exceedsRatio(s.total, uint64(size), options.MaxRatio) {
+		return nil, fmt.Errorf("%w: %d byte archive expands to %d bytes", ErrUnsafeArchive, size, s.total)
+	}
+
+	written, err := repack(kept, dst)
+	if err != nil {
+		return nil, err
+	}
+	return s.finish(size, written), nil
+}
+
+// sanitizer applies the checks and exclude rules shared by every archive format, one entry at a time
+type sanitizer struct {
+	options  Options
+	rules    []rule
+	report   *Report
+	stripped map[string]*Stripped
+	seen     map[string]bool
+	// total is the uncompressed size of the kept files
+	total uint64
+}
+
+func newSanitizer(format Format, options Options) *sanitizer {
+	s := &sanitizer{
+		options:  options,
+		report:   &Report{Format: format},
+		stripped: make(map[string]*Stripped),
+		seen:     make(map[string]bool),
+	}
+	for _, glob := range options.Excludes {
+		if r, ok := parseRule(glob); ok {
+			s.rules = append(s.rules, r)
+		}
+	}
+	return s
+}
+
+// admit checks an entry and reports whether it is kept, returning its normalized name. Directories are
+// dropped, they are implied by the files in them; links and special files are rejected unless excluded.
+func (s *sanitizer) admit(name string, mode fs.FileMode, size uint64) (string, bool, error) {
+	s.report.Entries++
+	if s.options.MaxEntries > 0 && s.report.Entries > s.options.MaxEntries {
+		return "", false, fmt.Errorf("%w: more than the %d entries allowed", ErrUnsafeArchive, s.options.MaxEntries)
+	}
+
+	clean, err := cleanName(name)
+	if err != nil {
+		return "", false, err
+	}
+	if mode.IsDir() {
+		return "", false, nil
+	}
+	if s.seen[clean] {
+		return "", false, fmt.Errorf("%w: entry %q appears more than once", ErrUnsafeArchive, clean)
+	}
+	s.seen[clean] = true
+
+	if r, prefix, ok := excluded(s.rules, clean); ok {
+		stripped := s.stripped[prefix]
+		if stripped == nil {
+			stripped = &Stripped{Path: prefix, Rule: r.pattern}
+			s.stripped[prefix] = stripped
+		}
+		stripped.Files++
+		stripped.Bytes += int64(size)
+		s.report.StrippedFiles++
+		s.report.StrippedBytes += int64(size)
+		return clean, false, nil
+	}
+
+	if mode&fs.ModeSymlink != 0 {
+		return "", false, fmt.Errorf("%w: entry %q is a link", ErrUnsafeArchive, name)
+	}
+	if !mode.IsRegular() {
+		return "", false, fmt.Errorf("%w: entry %q is not a regular file", ErrUnsafeArchive, name)
+	}
+
+	s.total += size
+	if s.options.MaxUncompressedSize > 0 && s.total > uint64(s.options.MaxUncompressedSize) {
+		return "", false, fmt.Errorf("%w: contents expand to more than %d bytes", ErrUnsafeArchive, s.options.MaxUncompressedSize)
+	}
+	s.report.KeptFiles++
+	return clean, true, nil
+}
+
+func (s *sanitizer) finish(originalSize, size int64) *Report {
+	s.report.OriginalSize = originalSize
+	s.report.Size = size
+	s.report.Stripped, s.report.Truncated = listStripped(s.stripped)
+	return s.report
+}
+
+func repack(entries []entry, dst io.Writer) (int64, error) {
+	cw := &countingWriter{w: dst}
+	zw := newZipWriter(cw)
+
+	for _, e := range entries {
+		w, err := createEntry(zw, e.name, e.file.Modified, e.file.Mode())
+		if err != nil {
+			return 0, err
+		}
+
+		rc, err := e.file.Open()
+		if err != nil {
+			return 0, fmt.Errorf("%w: entry %s: %v", ErrInvalidArchive, e.name, err)
+		}
+		_, err = io.Copy(w, rc)
+		rc.Close()
+		if err != nil {
+			// The zip reader stops an entry that inflates past its declared size with ErrFormat
+			return 0, copyError(e.name, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return 0, fmt.Errorf("failed to finish archive: %v", err)
+	}
+	return cw.n, nil
+}
+
+func newZipWriter(w io.Writer) *zip.Writer {
+	zw := zip.NewWriter(w)
+	// Speed matters more than size here, the archive is unpacked again as soon as Cx1 receives it
+	zw.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, flate.BestSpeed)
+	})
+	return zw
+}
+
+func createEntry(zw *zip.Writer, name string, modified time.Time, mode fs.FileMode) (io.Writer, error) {
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	}
+	header.SetMode(mode.Perm())
+
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write entry %s: %v", name, err)
+	}
+	return w, nil
+}
+
+// copyError tells an entry whose data is corrupt, an archive error, from a failure to write it
+func copyError(name string, err error) error {
+	var corrupt flate.CorruptInputError
+	if errors.Is(err, ErrUnsafeArchive) {
+		return err
+	}
+	if errors.Is(err, zip.ErrFormat) || errors.Is(err, zip.ErrChecksum) || errors.Is(err, tar.ErrHeader) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &corrupt) {
+		return fmt.Errorf("%w: entry %s: %v", ErrInvalidArchive, name, err)
+	}
+	return fmt.Errorf("failed to repack entry %s: %w", name, err)
+}
+
+// cleanName normalizes an entry name and rejects those that would be unpacked outside the target directory
+func cleanName(name string) (string, error) {
+	n := strings.ReplaceAll(name, "\\", "/")
+	if strings.ContainsRune(n, 0) || strings.HasPrefix(n, "/") || (len(n) > 1 && n[1] == ':') {
+		return "", fmt.Errorf("%w: entry %q has an absolute path", ErrUnsafeArchive, name)
+	}
+
+	clean := path.Clean(n)
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("%w: entry %q escapes the archive root", ErrUnsafeArchive, name)
+	}
+	return clean, nil
+}
+
+func exceedsRatio(uncompressed, compressed uint64, maxRatio float64) bool {
+	if maxRatio <= 0 || uncompressed <= ratioThreshold {
+		return false
+	}
+	return float64(uncompressed) > maxRatio*float64(max(compressed, 1))
+}
+
+// listStripped orders stripped paths by size, largest first, keeping at most maxReportedPaths
+func listStripped(stripped map[string]*Stripped) ([]Stripped, bool) {
+	list := make([]Stripped, 0, len(stripped))
+	for _, s := range stripped {
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Bytes != list[j].Bytes {
+			return list[i].Bytes > list[j].Bytes
+		}
+		return list[i].Path < list[j].Path
+	})
+
+	if len(list) > maxReportedPaths {
+		return list[:maxReportedPaths], true
+	}
+	return list, false
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
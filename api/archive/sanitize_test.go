@@ -0,0 +1,231 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+)
+
+// testEntry is a file, directory or link of a test archive
+type testEntry struct {
+	name string
+	mode fs.FileMode
+	body string
+}
+
+func file(name, body string) testEntry { return testEntry{name: name, mode: 0o644, body: body} }
+
+func testOptions(excludes ...string) Options {
+	return Options{MaxEntries: 100, MaxRatio: defaultMaxRatio, MaxUncompressedSize: 64 << 20, Excludes: excludes}
+}
+
+func buildZip(t *testing.T, entries ...testEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		header.SetMode(e.mode)
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, e.body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// readZip returns the files of a zip archive by name
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("output is not a zip archive: %v", err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(body)
+	}
+	return files
+}
+
+func sanitize(data []byte, options Options) ([]byte, *Report, error) {
+	var out bytes.Buffer
+	report, err := Sanitize(bytes.NewReader(data), int64(len(data)), &out, options)
+	return out.Bytes(), report, err
+}
+
+func TestSanitize(t *testing.T) {
+	data := buildZip(t,
+		testEntry{name: "src/", mode: fs.ModeDir | 0o755},
+		file("src/main.go", "package main"),
+		file("src/build/build.go", "package build"),
+		file("build/output.txt", "out"),
+		file("web/node_modules/left-pad/index.js", "module.exports = 1"),
+		file("web/node_modules/left-pad/package.json", "{}"),
+		file("docs/guide.pdf", "pdf"),
+		file(".\\windows\\path.txt", "backslashes"),
+		// Links are fine where they are stripped anyway
+		testEntry{name: "web/node_modules/.bin/left-pad", mode: fs.ModeSymlink | 0o777, body: "../left-pad/index.js"},
+	)
+
+	out, report, err := sanitize(data, testOptions(append(DefaultExcludes, "docs")...))
+	if err != nil {
+		t.Fatalf("Sanitize() = %v", err)
+	}
+
+	want := map[string]string{
+		"src/main.go":        "package main",
+		"src/build/build.go": "package build",
+		"windows/path.txt":   "backslashes",
+	}
+	if got := readZip(t, out); !reflect.DeepEqual(got, want) {
+		t.Fatalf("kept files = %v, want %v", got, want)
+	}
+
+	if report.Format != FormatZip || report.Entries != 9 || report.KeptFiles != 3 || report.StrippedFiles != 5 {
+		t.Fatalf("report = %+v", report)
+	}
+	if report.OriginalSize != int64(len(data)) || report.Size != int64(len(out)) {
+		t.Fatalf("report sizes = %d -> %d, want %d -> %d", report.OriginalSize, report.Size, len(data), len(out))
+	}
+	wantStripped := []Stripped{
+		{Path: "web/node_modules", Rule: "node_modules", Files: 3, Bytes: 40},
+		{Path: "build", Rule: "/build", Files: 1, Bytes: 3},
+		{Path: "docs", Rule: "docs", Files: 1, Bytes: 3},
+	}
+	if !reflect.DeepEqual(report.Stripped, wantStripped) {
+		t.Fatalf("stripped = %+v, want %+v", report.Stripped, wantStripped)
+	}
+}
+
+func TestSanitizeNegatedExclude(t *testing.T) {
+	data := buildZip(t, file("vendor/lib.go", "package lib"), file("main.go", "package main"))
+
+	out, _, err := sanitize(data, testOptions(append(DefaultExcludes, "!vendor")...))
+	if err != nil {
+		t.Fatalf("Sanitize() = %v", err)
+	}
+	if got := readZip(t, out); len(got) != 2 {
+		t.Fatalf("kept files = %v, want vendor re-included", got)
+	}
+}
+
+func TestSanitizeRejects(t *testing.T) {
+	zeros := string(make([]byte, 4<<20))
+
+	tests := []struct {
+		name    string
+		entries []testEntry
+		limit   func(options *Options)
+		wantErr error
+	}{
+		{name: "parent directory", entries: []testEntry{file("../evil.sh", "x")}, wantErr: ErrUnsafeArchive},
+		{name: "parent directory after cleaning", entries: []testEntry{file("src/../../evil.sh", "x")}, wantErr: ErrUnsafeArchive},
+		{name: "parent directory with backslashes", entries: []testEntry{file("src\\..\\..\\evil.sh", "x")}, wantErr: ErrUnsafeArchive},
+		{name: "absolute path", entries: []testEntry{file("/etc/cron.d/evil", "x")}, wantErr: ErrUnsafeArchive},
+		{name: "drive letter", entries: []testEntry{file("C:/Windows/evil.dll", "x")}, wantErr: ErrUnsafeArchive},
+		{name: "symlink", entries: []testEntry{{name: "src/passwd", mode: fs.ModeSymlink | 0o777, body: "/etc/passwd"}}, wantErr: ErrUnsafeArchive},
+		{name: "device", entries: []testEntry{{name: "src/null", mode: fs.ModeDevice | fs.ModeCharDevice | 0o666}}, wantErr: ErrUnsafeArchive},
+		{name: "duplicate entry", entries: []testEntry{file("src/a.go", "1"), file("src/./a.go", "2")}, wantErr: ErrUnsafeArchive},
+		{
+			name:    "too many entries",
+			entries: []testEntry{file("a", "1"), file("b", "2"), file("c", "3")},
+			limit:   func(options *Options) { options.MaxEntries = 2 },
+			wantErr: ErrUnsafeArchive,
+		},
+		{name: "compression ratio", entries: []testEntry{file("bomb.txt", zeros)}, wantErr: ErrUnsafeArchive},
+		{
+			name:    "uncompressed size",
+			entries: []testEntry{file("a.txt", "0123456789"), file("b.txt", "0123456789")},
+			limit:   func(options *Options) { options.MaxUncompressedSize = 15 },
+			wantErr: ErrUnsafeArchive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			if tt.limit != nil {
+				tt.limit(&options)
+			}
+
+			_, _, err := sanitize(buildZip(t, tt.entries...), options)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Sanitize() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSanitizeSmallFilesIgnoreRatio(t *testing.T) {
+	// Below ratioThreshold a file of repeated bytes is no bomb, however well it compresses
+	data := buildZip(t, file("zeros.bin", string(make([]byte, ratioThreshold))))
+	if _, _, err := sanitize(data, testOptions()); err != nil {
+		t.Fatalf("Sanitize() = %v", err)
+	}
+}
+
+func TestSanitizeUnderstatedSize(t *testing.T) {
+	// An entry that declares a small size but inflates to far more is stopped while it is copied
+	var compressed bytes.Buffer
+	fw, err := flate.NewWriter(&compressed, flate.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(make([]byte, 1<<20))
+	fw.Close()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	header := &zip.FileHeader{
+		Name:               "small.txt",
+		Method:             zip.Deflate,
+		CompressedSize64:   uint64(compressed.Len()),
+		UncompressedSize64: 10,
+	}
+	header.SetMode(0o644)
+	w, err := zw.CreateRaw(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(compressed.Bytes())
+	zw.Close()
+
+	if _, _, err := sanitize(buf.Bytes(), testOptions()); !errors.Is(err, ErrInvalidArchive) {
+		t.Fatalf("Sanitize() = %v, want %v", err, ErrInvalidArchive)
+	}
+}
+
+func TestSanitizeInvalidArchive(t *testing.T) {
+	data := buildZip(t, file("main.go", "package main"))
+	for name, corrupt := range map[string][]byte{
+		"not a zip": []byte("hello, world"),
+		"truncated": data[:len(data)-10],
+	} {
+		if _, _, err := sanitize(corrupt, testOptions()); !errors.Is(err, ErrInvalidArchive) {
+			t.Errorf("Sanitize(%s) = %v, want %v", name, err, ErrInvalidArchive)
+		}
+	}
+}
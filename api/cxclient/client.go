@@ -0,0 +1,65 @@
+// Package cxclient defines the part of the Checkmarx One client the wrapper depends on.
+//
+// Services take a Client instead of a concrete *cx1.Cx1Client so they can run against a live tenant or
+// against Fake, an in-memory backend with scripted scan progressions used by tests and by sandbox mode.
+package cxclient
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	cx1 "github.com/madhatkul/CxWrapper-v2/Cx1ClientGo"
+)
+
+// Client is the set of Cx1 calls made by the wrapper's services
+type Client interface {
+	// Projects
+	GetProjectsByName(name string) ([]cx1.Project, error)
+	CreateProject(name string, groups []string, tags map[string]string) (cx1.Project, error)
+	GetScanConfigurationByProjectID(projectID string) ([]cx1.ConfigurationSetting, error)
+	UpdateProjectConfigurationByID(projectID string, settings []cx1.ConfigurationSetting) error
+
+	// Applications
+	GetApplicationByName(name string) (cx1.Application, error)
+	CreateApplication(name string) (cx1.Application, error)
+	UpdateApplication(application *cx1.Application) error
+
+	// Scans
+	UploadStreamForProjectByID(projectID string, r io.Reader, size int64) (string, error)
+	ScanProjectZipByID(projectID, sourceURL, branch string, settings []cx1.ScanConfiguration, tags map[string]string) (cx1.Scan, error)
+	ScanPolling(scan *cx1.Scan) (cx1.Scan, error)
+	GetScanByID(scanID string) (cx1.Scan, error)
+	GetLastScansFiltered(filter cx1.ScanFilter) ([]cx1.Scan, error)
+	GetScanConfigurationByID(projectID, scanID string) ([]cx1.ConfigurationSetting, error)
+	CancelScanByID(scanID string) error
+
+	// Results
+	GetAllScanResultsByID(scanID string) (cx1.ScanResultSet, error)
+	RetrievePolicyViolationInfo(projectID, scanID string) (bool, error)
+}
+
+var _ Client = (*cx1.Cx1Client)(nil)
+
+// SandboxEnabled reports whether the wrapper was started with --sandbox or CX1_SANDBOX=true, in which
+// case it should be given NewSandbox() instead of a client connected to a tenant
+func SandboxEnabled() bool {
+	for _, arg := range os.Args[1:] {
+		if arg == "--sandbox" || arg == "-sandbox" {
+			return true
+		}
+	}
+	enabled, _ := strconv.ParseBool(os.Getenv("CX1_SANDBOX"))
+	return enabled
+}
+
+// NewSandbox returns a Fake for running the wrapper without a tenant. Scans follow DefaultProgression,
+// stretched or shortened by CX1_SANDBOX_SCAN_DURATION (e.g. "30s"), and return synthetic results.
+func NewSandbox() *Fake {
+	fake := NewFake()
+	if duration, err := time.ParseDuration(os.Getenv("CX1_SANDBOX_SCAN_DURATION")); err == nil && duration > 0 {
+		fake.DefaultScript.Steps = ScaledProgression(duration)
+	}
+	return fake
+}
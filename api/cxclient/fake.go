@@ -0,0 +1,621 @@
+// api/cxclient/fake.go
+package cxclient
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cx1 "github.com/madhatkul/CxWrapper-v2/Cx1ClientGo"
+)
+
+const defaultScanDuration = 10 * time.Second
+
+// Step is a point in a scripted scan progression. The scan enters it once After has elapsed since the scan
+// was created. The first CompletedEngines engines report Completed, the others report Status.
+type Step struct {
+	After            time.Duration
+	Status           string
+	CompletedEngines int
+}
+
+// ScanScript scripts how a fake scan progresses and what it returns
+type ScanScript struct {
+	Steps []Step
+	// Results are returned once the scan is completed; nil means SyntheticResults for the scan's engines
+	Results       *cx1.ScanResultSet
+	PolicyBlocked bool
+}
+
+// DefaultProgression queues a scan, runs its engines and completes it within ten seconds
+func DefaultProgression() []Step {
+	return ScaledProgression(defaultScanDuration)
+}
+
+// ScaledProgression is DefaultProgression completing after the given duration
+func ScaledProgression(duration time.Duration) []Step {
+	return []Step{
+		{After: 0, Status: "Queued"},
+		{After: duration / 5, Status: "Running"},
+		{After: duration * 3 / 5, Status: "Running", CompletedEngines: 1},
+		{After: duration, Status: "Completed", CompletedEngines: -1},
+	}
+}
+
+type fakeScan struct {
+	scan     cx1.Scan
+	created  time.Time
+	script   ScanScript
+	settings []cx1.ScanConfiguration
+	canceled bool
+}
+
+// Fake is a stateful in-memory Client. Projects, applications, uploads and scans live in memory; scans
+// progress through a ScanScript on the fake's clock, so every caller polling a scan sees the same status.
+type Fake struct {
+	// DefaultScript is used for scans whose commit_id tag has no script of its own
+	DefaultScript ScanScript
+
+	mu            sync.Mutex
+	now           func() time.Time
+	seq           int
+	projects      []cx1.Project
+	projectConfig map[string][]cx1.ConfigurationSetting
+	applications  map[string]cx1.Application
+	uploads       map[string]int64
+	scans         []*fakeScan
+	scripts       map[string]ScanScript
+	failures      map[string][]error
+}
+
+func NewFake() *Fake {
+	return &Fake{
+		DefaultScript: ScanScript{Steps: DefaultProgression()},
+		now:           time.Now,
+		projectConfig: make(map[string][]cx1.ConfigurationSetting),
+		applications:  make(map[string]cx1.Application),
+		uploads:       make(map[string]int64),
+		scripts:       make(map[string]ScanScript),
+		failures:      make(map[string][]error),
+	}
+}
+
+// SetClock replaces the clock scan progressions are measured with
+func (f *Fake) SetClock(now func() time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
+
+// Script sets how scans tagged with the given commit_id progress
+func (f *Fake) Script(commitID string, script ScanScript) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.scripts[commitID] = script
+}
+
+// FailNext makes the next call to method (e.g. "GetScanByID") return err. Calls queue up.
+func (f *Fake) FailNext(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[method] = append(f.failures[method], err)
+}
+
+// Complete moves a scan straight to the last step of its script
+func (f *Fake) Complete(scanID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, err := f.scanLocked(scanID)
+	if err != nil {
+		return err
+	}
+	if len(s.script.Steps) > 0 {
+		s.created = f.now().Add(-s.script.Steps[len(s.script.Steps)-1].After)
+	}
+	return nil
+}
+
+// injected returns the next error queued for method. It must be called with mu held.
+func (f *Fake) injected(method string) error {
+	queued := f.failures[method]
+	if len(queued) == 0 {
+		return nil
+	}
+	f.failures[method] = queued[1:]
+	return queued[0]
+}
+
+// newID returns a UUID-shaped ID that is unique within the fake. It must be called with mu held.
+func (f *Fake) newID() string {
+	f.seq++
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", f.seq)
+}
+
+func (f *Fake) GetProjectsByName(name string) ([]cx1.Project, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.injected("GetProjectsByName"); err != nil {
+		return nil, err
+	}
+
+	var projects []cx1.Project
+	for _, project := range f.projects {
+		if project.Name == name {
+			projects = append(projects, project)
+		}
+	}
+	return projects, nil
+}
+
+func (f *Fake) CreateProject(name string, groups []string, tags map[string]string) (cx1.Project, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.injected("CreateProject"); err != nil {
+		return cx1.Project{}, err
+	}
+
+	for _, project := range f.projects {
+		if project.Name == name {
+			return cx1.Project{}, fmt.Errorf("project %s already exists", name)
+		}
+	}
+
+	now := f.now().UTC().Format(time.RFC3339)
+	project := cx1.Project{
+		ProjectID: f.newID(),
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Groups:    groups,
+		Tags:      tags,
+		Origin:    "sandbox",
+	}
+	f.projects = append(f.projects, project)
+	return project, nil
+}
+
+func (f *Fake) GetScanConfigurationByProjectID(projectID string) ([]cx1.ConfigurationSetting, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.injected("GetScanConfigurationByProjectID"); err != nil {
+		return nil, err
+	}
+	if !f.hasProject(projectID) {
+		return nil, fmt.Errorf("project %s not found", projectID)
+	}
+
+	settings := defaultConfiguration()
+	for _, override := range f.projectConfig[projectID] {
+		replaced := false
+		for i := range settings {
+			if settings[i].Key == override.Key {
+				settings[i] = override
+				replaced = true
+			}
+		}
+		if !replaced {
+			settings = append(settings, override)
+		}
+	}
+	return settings, nil
+}
+
+func (f *Fake) UpdateProjectConfigurationByID(projectID string, settings []cx1.ConfigurationSetting) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.injected("UpdateProjectConfigurationByID"); err != nil {
+		return err
+	}
+	if !f.hasProject(projectID) {
+		return fmt.Errorf("project %s not found", projectID)
+	}
+
+	f.projectConfig[projectID] = append(f.projectConfig[projectID], settings...)
+	return nil
+}
+
+func (f *Fake) GetApplicationByName(name string) (cx1.Application, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.injected("GetApplicationByName"); err != nil {
+		return cx1.Application{}, err
+	}
+
+	application, ok := f.applications[name]
+	if !ok {
+		return cx1.Application{}, fmt.Errorf("application %s not found", name)
+	}
+	return application, nil
+}
+
+func (f *Fake) CreateApplication(name string) (cx1.Application, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.injected("CreateApplication"); err != nil {
+		return cx1.Application{}, err
+	}
+
+	if _, ok := f.applications[name]; ok {
+		return cx1.Application{}, fmt.Errorf("application %s already exists", name)
+	}
+	application := cx1.Application{ApplicationID: f.newID(), Name: name, ProjectIds: &[]string{}}
+	f.applications[name] = application
+	return application, nil
+}
+
+func (f *Fake) UpdateApplication(application *cx1.Application) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.injected("UpdateApplication"); err != nil {
+		return err
+	}
+
+	if _, ok := f.applications[application.Name]; !ok {
+		return fmt.Errorf("application %s not found", application.Name)
+	}
+	f.applications[application.Name] = *application
+	return nil
+}
+
+func (f *Fake) UploadStreamForProjectByID(projectID string, r io.Reader, size int64) (string, error) {
+	f.mu.Lock()
+	if err := f.injected("UploadStreamForProjectByID"); err != nil {
+		f.mu.Unlock()
+		return "", err
+	}
+	known := f.hasProject(projectID)
+	f.mu.Unlock()
+
+	if !known {
+		return "", fmt.Errorf("project %s not found", projectID)
+	}
+
+	// Read outside the lock, uploads can be large
+	n, err := io.Copy(io.Discard, r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read upload: %v", err)
+	}
+	if size > 0 && n != size {
+		return "", fmt.Errorf("upload size mismatch: expected %d bytes, read %d", size, n)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	uploadURL := "sandbox://uploads/" + f.newID()
+	f.uploads[uploadURL] = n
+	return uploadURL, nil
+}
+
+func (f *Fake) ScanProjectZipByID(projectID, sourceURL, branch string, settings []cx1.ScanConfiguration, tags map[string]string) (cx1.Scan, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.injected("ScanProjectZipByID"); err != nil {
+		return cx1.Scan{}, err
+	}
+
+	project, ok := f.project(projectID)
+	if !ok {
+		return cx1.Scan{}, fmt.Errorf("project %s not found", projectID)
+	}
+	if _, ok := f.uploads[sourceURL]; !ok {
+		return cx1.Scan{}, fmt.Errorf("upload %s not found", sourceURL)
+	}
+
+	var engines []string
+	for _, setting := range settings {
+		engines = append(engines, setting.ScanType)
+	}
+	if len(engines) == 0 {
+		return cx1.Scan{}, fmt.Errorf("no scan types requested")
+	}
+
+	scanTags := make(map[string]string, len(tags))
+	for k, v := range tags {
+		scanTags[k] = v
+	}
+
+	script, ok := f.scripts[scanTags["commit_id"]]
+	if !ok {
+		script = f.DefaultScript
+	}
+	if len(script.Steps) == 0 {
+		script.Steps = DefaultProgression()
+	}
+
+	now := f.now()
+	s := &fakeScan{
+		scan: cx1.Scan{
+			ScanID:       f.newID(),
+			Branch:       branch,
+			CreatedAt:    now.UTC().Format(time.RFC3339),
+			ProjectID:    project.ProjectID,
+			ProjectName:  project.Name,
+			Initiator:    "sandbox",
+			Tags:         scanTags,
+			Engines:      engines,
+			SourceType:   "zip",
+			SourceOrigin: "sandbox",
+		},
+		created:  now,
+		script:   script,
+		settings: settings,
+	}
+	f.scans = append(f.scans, s)
+
+	return f.current(s), nil
+}
+
+// ScanPolling blocks until the scan reaches a terminal status
+func (f *Fake) ScanPolling(scan *cx1.Scan) (cx1.Scan, error) {
+	for {
+		current, err := f.GetScanByID(scan.ScanID)
+		if err != nil {
+			return current, err
+		}
+		if isTerminal(current.Status) {
+			return current, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func (f *Fake) GetScanByID(scanID string) (cx1.Scan, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.injected("GetScanByID"); err != nil {
+		return cx1.Scan{}, err
+	}
+
+	s, err := f.scanLocked(scanID)
+	if err != nil {
+		return cx1.Scan{}, err
+	}
+	return f.current(s), nil
+}
+
+// GetLastScansFiltered returns the scans matching the filter, newest first
+func (f *Fake) GetLastScansFiltered(filter cx1.ScanFilter) ([]cx1.Scan, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.injected("GetLastScansFiltered"); err != nil {
+		return nil, err
+	}
+
+	var scans []cx1.Scan
+	for i := len(f.scans) - 1; i >= 0; i-- {
+		scan := f.current(f.scans[i])
+		if matchesFilter(scan, filter) {
+			scans = append(scans, scan)
+		}
+	}
+
+	if filter.Offset >= uint64(len(scans)) {
+		return []cx1.Scan{}, nil
+	}
+	scans = scans[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < uint64(len(scans)) {
+		scans = scans[:filter.Limit]
+	}
+	return scans, nil
+}
+
+func (f *Fake) GetScanConfigurationByID(projectID, scanID string) ([]cx1.ConfigurationSetting, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.injected("GetScanConfigurationByID"); err != nil {
+		return nil, err
+	}
+
+	s, err := f.scanLocked(scanID)
+	if err != nil {
+		return nil, err
+	}
+	if s.scan.ProjectID != projectID {
+		return nil, fmt.Errorf("scan %s not found in project %s", scanID, projectID)
+	}
+
+	var settings []cx1.ConfigurationSetting
+	for _, config := range s.settings {
+		names := make([]string, 0, len(config.Values))
+		for name := range config.Values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			settings = append(settings, cx1.ConfigurationSetting{
+				Key:         fmt.Sprintf("scan.config.%s.%s", config.ScanType, name),
+				Name:        name,
+				Category:    config.ScanType,
+				OriginLevel: "Scan",
+				Value:       config.Values[name],
+			})
+		}
+	}
+	return settings, nil
+}
+
+func (f *Fake) CancelScanByID(scanID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.injected("CancelScanByID"); err != nil {
+		return err
+	}
+
+	s, err := f.scanLocked(scanID)
+	if err != nil {
+		return err
+	}
+	if status := f.current(s).Status; isTerminal(status) {
+		return fmt.Errorf("scan %s cannot be canceled, status: %s", scanID, status)
+	}
+	s.canceled = true
+	s.scan.UpdatedAt = f.now().UTC().Format(time.RFC3339)
+	return nil
+}
+
+// GetAllScanResultsByID returns the scripted or synthetic results of a completed scan and an empty set otherwise
+func (f *Fake) GetAllScanResultsByID(scanID string) (cx1.ScanResultSet, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.injected("GetAllScanResultsByID"); err != nil {
+		return cx1.ScanResultSet{}, err
+	}
+
+	s, err := f.scanLocked(scanID)
+	if err != nil {
+		return cx1.ScanResultSet{}, err
+	}
+	if status := f.current(s).Status; status != "Completed" && status != "Partial" {
+		return cx1.ScanResultSet{}, nil
+	}
+	if s.script.Results != nil {
+		return *s.script.Results, nil
+	}
+	return SyntheticResults(s.scan.ProjectID, s.scan.Engines), nil
+}
+
+func (f *Fake) RetrievePolicyViolationInfo(projectID, scanID string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.injected("RetrievePolicyViolationInfo"); err != nil {
+		return false, err
+	}
+
+	s, err := f.scanLocked(scanID)
+	if err != nil {
+		return false, err
+	}
+	return s.script.PolicyBlocked && f.current(s).Status == "Completed", nil
+}
+
+// current returns the scan as it stands on the fake's clock. It must be called with mu held.
+func (f *Fake) current(s *fakeScan) cx1.Scan {
+	scan := s.scan
+	scan.Tags = make(map[string]string, len(s.scan.Tags))
+	for k, v := range s.scan.Tags {
+		scan.Tags[k] = v
+	}
+	scan.Engines = append([]string(nil), s.scan.Engines...)
+
+	if s.canceled {
+		scan.Status = "Canceled"
+		scan.StatusDetails = engineStatuses(scan.Engines, "Canceled", 0)
+		return scan
+	}
+
+	elapsed := f.now().Sub(s.created)
+	step := s.script.Steps[0]
+	for _, next := range s.script.Steps[1:] {
+		if next.After > elapsed {
+			break
+		}
+		step = next
+	}
+
+	scan.Status = step.Status
+	scan.StatusDetails = engineStatuses(scan.Engines, step.Status, step.CompletedEngines)
+	scan.UpdatedAt = s.created.Add(step.After).UTC().Format(time.RFC3339)
+	return scan
+}
+
+func (f *Fake) scanLocked(scanID string) (*fakeScan, error) {
+	for _, s := range f.scans {
+		if s.scan.ScanID == scanID {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("scan %s not found", scanID)
+}
+
+func (f *Fake) project(projectID string) (cx1.Project, bool) {
+	for _, project := range f.projects {
+		if project.ProjectID == projectID {
+			return project, true
+		}
+	}
+	return cx1.Project{}, false
+}
+
+func (f *Fake) hasProject(projectID string) bool {
+	_, ok := f.project(projectID)
+	return ok
+}
+
+// engineStatuses reports the first completed engines as Completed and the rest as status; a negative
+// completed count completes them all
+func engineStatuses(engines []string, status string, completed int) []cx1.ScanStatusDetails {
+	details := make([]cx1.ScanStatusDetails, 0, len(engines))
+	for i, engine := range engines {
+		engineStatus := status
+		if completed < 0 || i < completed {
+			engineStatus = "Completed"
+		}
+		details = append(details, cx1.ScanStatusDetails{Name: engine, Status: engineStatus})
+	}
+	return details
+}
+
+func matchesFilter(scan cx1.Scan, filter cx1.ScanFilter) bool {
+	if filter.ProjectID != "" && scan.ProjectID != filter.ProjectID {
+		return false
+	}
+	for i, key := range filter.TagKeys {
+		value, ok := scan.Tags[key]
+		if !ok || (i < len(filter.TagValues) && value != filter.TagValues[i]) {
+			return false
+		}
+	}
+	if len(filter.Statuses) > 0 && !containsFold(filter.Statuses, scan.Status) {
+		return false
+	}
+	if len(filter.Branches) > 0 && !containsFold(filter.Branches, scan.Branch) {
+		return false
+	}
+
+	if !filter.FromDate.IsZero() || !filter.ToDate.IsZero() {
+		created, err := time.Parse(time.RFC3339, scan.CreatedAt)
+		if err != nil {
+			return false
+		}
+		if !filter.FromDate.IsZero() && created.Before(filter.FromDate) {
+			return false
+		}
+		if !filter.ToDate.IsZero() && created.After(filter.ToDate) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTerminal(status string) bool {
+	switch status {
+	case "Completed", "Partial", "Failed", "Canceled":
+		return true
+	}
+	return false
+}
+
+// defaultConfiguration is the tenant-level configuration every fake project starts with
+func defaultConfiguration() []cx1.ConfigurationSetting {
+	return []cx1.ConfigurationSetting{
+		{Key: "scan.config.sast.presetName", Name: "presetName", Category: "sast", OriginLevel: "Tenant", Value: "ASA Premium", ValueType: "RESTList", AllowOverride: true},
+		{Key: "scan.config.sast.fastScanMode", Name: "fastScanMode", Category: "sast", OriginLevel: "Tenant", Value: "false", ValueType: "Bool", AllowOverride: true},
+		{Key: "scan.config.sast.incremental", Name: "incremental", Category: "sast", OriginLevel: "Tenant", Value: "false", ValueType: "Bool", AllowOverride: true},
+		{Key: "scan.config.sca.exploitablePath", Name: "exploitablePath", Category: "sca", OriginLevel: "Tenant", Value: "false", ValueType: "Bool", AllowOverride: true},
+		{Key: "scan.config.kics.platforms", Name: "platforms", Category: "kics", OriginLevel: "Tenant", Value: "", ValueType: "MultiList", AllowOverride: true},
+	}
+}
@@ -0,0 +1,107 @@
+// api/cxclient/synthetic.go
+package cxclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	cx1 "github.com/madhatkul/CxWrapper-v2/Cx1ClientGo"
+)
+
+// SyntheticResults returns a small, fixed set of findings for each requested engine. Similarity IDs are
+// derived from the project so repeated scans of a project report the same findings.
+func SyntheticResults(projectID string, engines []string) cx1.ScanResultSet {
+	var results cx1.ScanResultSet
+
+	for _, engine := range engines {
+		switch engine {
+		case "sast":
+			results.SAST = append(results.SAST,
+				cx1.ScanSASTResult{
+					ScanResultBase: syntheticBase(projectID, "sast-sqli", "sast", "HIGH", "The application builds an SQL query from user input."),
+					Data: cx1.ScanSASTResultData{
+						QueryID:      4710234987261829012,
+						QueryName:    "SQL_Injection",
+						Group:        "Java_High_Risk",
+						ResultHash:   syntheticID(projectID, "sast-sqli-hash"),
+						LanguageName: "Java",
+						Nodes: []cx1.ScanSASTResultNodes{
+							{Name: "getParameter", FileName: "/src/main/java/com/example/UserController.java", Line: 42, Column: 27},
+							{Name: "executeQuery", FileName: "/src/main/java/com/example/UserRepository.java", Line: 88, Column: 19},
+						},
+					},
+					VulnerabilityDetails: cx1.ScanSASTResultDetails{CweId: 89},
+				},
+				cx1.ScanSASTResult{
+					ScanResultBase: syntheticBase(projectID, "sast-log", "sast", "LOW", "User input is written to the log without sanitization."),
+					Data: cx1.ScanSASTResultData{
+						QueryID:      1802344875123645671,
+						QueryName:    "Log_Forging",
+						Group:        "Java_Low_Visibility",
+						ResultHash:   syntheticID(projectID, "sast-log-hash"),
+						LanguageName: "Java",
+						Nodes: []cx1.ScanSASTResultNodes{
+							{Name: "getHeader", FileName: "/src/main/java/com/example/AuditFilter.java", Line: 17, Column: 33},
+						},
+					},
+					VulnerabilityDetails: cx1.ScanSASTResultDetails{CweId: 117},
+				},
+			)
+		case "sca":
+			results.SCA = append(results.SCA, cx1.ScanSCAResult{
+				ScanResultBase: syntheticBase(projectID, "sca-log4j", "sca", "CRITICAL", "Remote code execution through JNDI lookups in log messages."),
+				Data: cx1.ScanSCAResultData{
+					PackageIdentifier: "Maven-org.apache.logging.log4j:log4j-core-2.14.1",
+					Recommendation:    "2.17.1",
+				},
+				VulnerabilityDetails: cx1.ScanSCAResultDetails{CweId: "CWE-502", CVSSScore: 10.0, CveName: "CVE-2021-44228"},
+			})
+		case "kics":
+			results.KICS = append(results.KICS, cx1.ScanKICSResult{
+				ScanResultBase: syntheticBase(projectID, "kics-root", "kics", "MEDIUM", "The container runs as root."),
+				Data: cx1.ScanKICSResultData{
+					QueryID:       "fd54f200-402c-4333-a5a4-36ef6709af2f",
+					QueryName:     "Missing User Instruction",
+					Group:         "Build Process",
+					QueryURL:      "https://docs.docker.com/engine/reference/builder/#user",
+					FileName:      "/Dockerfile",
+					Line:          1,
+					Platform:      "Dockerfile",
+					IssueType:     "MissingAttribute",
+					ExpectedValue: "The 'Dockerfile' contains the 'USER' instruction",
+					Value:         "The 'Dockerfile' does not contain any 'USER' instruction",
+				},
+			})
+		case "secrets":
+			results.Secrets = append(results.Secrets, cx1.ScanSecretsResult{
+				ScanResultBase: syntheticBase(projectID, "secrets-aws", "secrets", "HIGH", "An AWS access key is committed to the repository."),
+				Data: cx1.ScanSecretsResultData{
+					RuleID:          "aws-access-token",
+					RuleName:        "AWS Access Token",
+					RuleDescription: "AWS access keys allow programmatic access to an AWS account.",
+					FileName:        "/config/application.properties",
+					Line:            12,
+				},
+			})
+		}
+	}
+
+	return results
+}
+
+func syntheticBase(projectID, name, resultType, severity, description string) cx1.ScanResultBase {
+	return cx1.ScanResultBase{
+		Type:         resultType,
+		ResultID:     syntheticID(projectID, name),
+		SimilarityID: syntheticID(projectID, name+"-similarity"),
+		Status:       "RECURRENT",
+		State:        "TO_VERIFY",
+		Severity:     severity,
+		Description:  description,
+	}
+}
+
+func syntheticID(projectID, name string) string {
+	sum := sha256.Sum256([]byte(projectID + "/" + name))
+	return hex.EncodeToString(sum[:16])
+}
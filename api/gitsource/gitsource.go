@@ -0,0 +1,493 @@
+// Package gitsource fetches a single commit of a git repository and archives it, so a scan can be started
+// from a repository reference instead of an uploaded archive.
+//
+// It shells out to the git CLI, which makes it work with any server git can talk to. Only the requested
+// commit is fetched, at depth 1, so the server must allow fetching a commit by ID, as protocol v2 servers
+// do; submodules are not included.
+package gitsource
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultFetchTimeout = 10 * time.Minute
+	// fetchSizeInterval is how often the objects received by a fetch are measured against the size limit
+	fetchSizeInterval = 500 * time.Millisecond
+)
+
+var (
+	ErrInvalidSource      = errors.New("invalid git source")
+	ErrUnknownCredentials = errors.New("unknown credentials reference")
+	ErrCommitNotFound     = errors.New("commit not found in repository")
+	ErrFetchFailed        = errors.New("failed to fetch repository")
+	ErrTooLarge           = errors.New("repository archive exceeds the maximum allowed size")
+)
+
+var (
+	// commitPattern matches full SHA-1 and SHA-256 object IDs; abbreviations and ref names are not
+	// accepted since the commit scanned must be exactly the one named
+	commitPattern         = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+	credentialsRefPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+)
+
+// Source is a commit of a git repository
+type Source struct {
+	RepoURL string
+	Commit  string
+	// CredentialsRef names credentials configured on the wrapper's host, see Fetcher.credentials
+	CredentialsRef string
+}
+
+// FileName names the archive of the source, "<repository>-<short commit>.zip"
+func (s Source) FileName() string {
+	name := strings.TrimSuffix(path.Base(strings.TrimRight(strings.ReplaceAll(s.RepoURL, "\\", "/"), "/")), ".git")
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" || name == "." {
+		name = "repository"
+	}
+	return fmt.Sprintf("%s-%s.zip", name, s.Commit[:min(12, len(s.Commit))])
+}
+
+// AddressPolicy decides which addresses repositories may be fetched from, see webhooks.DestinationPolicy
+type AddressPolicy interface {
+	// ResolveAllowed returns the addresses of host, or an error if any of them is not allowed
+	ResolveAllowed(ctx context.Context, host string) ([]net.IP, error)
+}
+
+// Fetcher fetches and archives commits with the git CLI
+type Fetcher struct {
+	git        string
+	timeout    time.Duration
+	maxSize    int64
+	allowLocal bool
+	policy     AddressPolicy
+}
+
+// NewFetcher returns a Fetcher whose archives are limited to maxSize bytes and whose remotes must resolve
+// to addresses policy allows, normally one read from the GIT_SOURCE_ALLOWED_HOSTS, GIT_SOURCE_ALLOWED_NETWORKS
+// and GIT_SOURCE_ALLOW_PRIVATE_NETWORKS settings; a nil policy allows any. It reads GIT_SOURCE_TIMEOUT, the time allowed to
+// fetch and archive a commit (default 10m), and GIT_SOURCE_ALLOW_LOCAL, which lets repo_url be a path or
+// file:// URL on the wrapper's host.
+func NewFetcher(maxSize int64, policy AddressPolicy) *Fetcher {
+	f := &Fetcher{
+		git:     "git",
+		timeout: defaultFetchTimeout,
+		maxSize: maxSize,
+		policy:  policy,
+	}
+	if v, err := time.ParseDuration(os.Getenv("GIT_SOURCE_TIMEOUT")); err == nil && v > 0 {
+		f.timeout = v
+	}
+	if v, err := strconv.ParseBool(os.Getenv("GIT_SOURCE_ALLOW_LOCAL")); err == nil {
+		f.allowLocal = v
+	}
+	return f
+}
+
+// Validate checks a source without contacting the repository, though its host is resolved to apply the
+// address policy
+func (f *Fetcher) Validate(src Source) error {
+	_, err := f.check(context.Background(), src)
+	return err
+}
+
+// check validates a source and returns the git configuration to fetch it with
+func (f *Fetcher) check(ctx context.Context, src Source) (map[string]string, error) {
+	if src.RepoURL == "" {
+		return nil, fmt.Errorf("%w: repo_url is required", ErrInvalidSource)
+	}
+	if strings.HasPrefix(src.RepoURL, "-") || strings.ContainsAny(src.RepoURL, "\x00\r\n") {
+		return nil, fmt.Errorf("%w: malformed repo_url", ErrInvalidSource)
+	}
+	if !commitPattern.MatchString(src.Commit) {
+		return nil, fmt.Errorf("%w: commit_id must be a full, lower-case commit hash to scan from a repository", ErrInvalidSource)
+	}
+
+	if u, err := url.Parse(src.RepoURL); err == nil && u.Scheme != "" && len(u.Scheme) > 1 {
+		switch u.Scheme {
+		case "https", "http", "ssh", "git":
+		case "file":
+			if !f.allowLocal {
+				return nil, fmt.Errorf("%w: local repositories are not allowed", ErrInvalidSource)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unsupported scheme %s", ErrInvalidSource, u.Scheme)
+		}
+		if _, ok := u.User.Password(); ok {
+			return nil, fmt.Errorf("%w: put credentials in a credentials reference, not in repo_url", ErrInvalidSource)
+		}
+	} else if isLocalPath(src.RepoURL) && !f.allowLocal {
+		return nil, fmt.Errorf("%w: local repositories are not allowed", ErrInvalidSource)
+	}
+
+	config, err := f.credentials(src)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.checkDestination(ctx, src.RepoURL, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Archive fetches the source's commit and writes it as a zip to a temporary file, positioned at its start.
+// The caller must close and remove it.
+func (f *Fetcher) Archive(ctx context.Context, src Source) (*os.File, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	config, err := f.check(ctx, src)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	dir, err := os.MkdirTemp("", "cxwrapper-git-*")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create temporary repository: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	env := f.environment(dir, config)
+
+	if err := f.run(ctx, env, nil, "init", "--quiet", "--bare"); err != nil {
+		return nil, 0, err
+	}
+
+	if err := f.fetch(ctx, env, dir, src); err != nil {
+		return nil, 0, err
+	}
+	if err := f.run(ctx, env, nil, "cat-file", "-e", src.Commit+"^{commit}"); err != nil {
+		return nil, 0, fmt.Errorf("%w: %s", ErrCommitNotFound, src.Commit)
+	}
+
+	out, err := os.CreateTemp("", "cxwrapper-git-*.zip")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	lw := &limitedWriter{w: out, limit: f.maxSize}
+	err = f.run(ctx, env, lw, "archive", "--format=zip", src.Commit)
+	if err == nil {
+		_, err = out.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		if errors.Is(err, ErrTooLarge) {
+			return nil, 0, err
+		}
+		return nil, 0, fmt.Errorf("failed to archive commit %s: %w", src.Commit, err)
+	}
+
+	return out, lw.n, nil
+}
+
+// fetch fetches only the source's commit, at depth 1, and fails if the server does not serve it by ID
+// rather than fetching anything more. It is stopped once the objects received exceed the size limit.
+func (f *Fetcher) fetch(ctx context.Context, env []string, dir string, src Source) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	objects := filepath.Join(dir, "objects")
+	var tooLarge atomic.Bool
+	if f.maxSize > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			ticker := time.NewTicker(fetchSizeInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					if dirSize(objects) > f.maxSize {
+						tooLarge.Store(true)
+						cancel()
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	err := f.run(ctx, env, nil, "fetch", "--quiet", "--no-tags", "--depth=1", "--", src.RepoURL, src.Commit)
+	if tooLarge.Load() || (err == nil && f.maxSize > 0 && dirSize(objects) > f.maxSize) {
+		return fmt.Errorf("%w: the fetch received more than %d bytes", ErrTooLarge, f.maxSize)
+	}
+	if err != nil && commitNotServed(err) {
+		return fmt.Errorf("%w: %s, or the server does not allow fetching it by ID: %v", ErrCommitNotFound, src.Commit, err)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFetchFailed, err)
+	}
+	return nil
+}
+
+// credentials resolves the source's CredentialsRef into git configuration, with REF below upper-cased and
+// dashes replaced by underscores. GIT_SOURCE_URL_<REF> is the repository URL prefix the credentials are bound
+// to, such as "https://git.example.com/payments/"; repo_url must be under it. GIT_CREDENTIALS_<REF> holds
+// "username:token" for HTTP(S) remotes, sent only to URLs under the prefix, and GIT_SSH_KEY_<REF> the path
+// of a private key for SSH remotes.
+func (f *Fetcher) credentials(src Source) (map[string]string, error) {
+	config := make(map[string]string)
+	if src.CredentialsRef == "" {
+		return config, nil
+	}
+	if !credentialsRefPattern.MatchString(src.CredentialsRef) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCredentials, src.CredentialsRef)
+	}
+
+	name := strings.ToUpper(strings.ReplaceAll(src.CredentialsRef, "-", "_"))
+	credentials, key := os.Getenv("GIT_CREDENTIALS_"+name), os.Getenv("GIT_SSH_KEY_"+name)
+	if credentials == "" && key == "" {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCredentials, src.CredentialsRef)
+	}
+	prefix := os.Getenv("GIT_SOURCE_URL_" + name)
+	if prefix == "" {
+		return nil, fmt.Errorf("%w: GIT_SOURCE_URL_%s must name the repositories %q may be used for", ErrUnknownCredentials, name, src.CredentialsRef)
+	}
+	if !withinPrefix(src.RepoURL, prefix) {
+		return nil, fmt.Errorf("%w: credentials %q may only be used for repositories under %s", ErrInvalidSource, src.CredentialsRef, prefix)
+	}
+
+	if credentials != "" {
+		username, token, ok := strings.Cut(credentials, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: GIT_CREDENTIALS_%s must be username:token", ErrUnknownCredentials, name)
+		}
+		if u, err := url.Parse(prefix); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("%w: GIT_SOURCE_URL_%s must be an http(s) URL to use GIT_CREDENTIALS_%s", ErrUnknownCredentials, name, name)
+		}
+		// Sent as a header rather than in the URL, so it never shows up in git's output or the process list,
+		// and scoped to the prefix, so git drops it if the fetch ends up anywhere else
+		basic := base64.StdEncoding.EncodeToString([]byte(username + ":" + token))
+		config["http."+prefix+".extraHeader"] = "Authorization: Basic " + basic
+	}
+	if key != "" {
+		config["core.sshCommand"] = fmt.Sprintf("ssh -i '%s' -o IdentitiesOnly=yes -o BatchMode=yes", strings.ReplaceAll(key, "'", `'\''`))
+	}
+	return config, nil
+}
+
+// checkDestination applies the address policy to the host of a remote repository. HTTP(S) fetches are
+// pinned to the addresses checked, so a second lookup cannot point git at an internal address; ssh and git
+// remotes are only checked.
+func (f *Fetcher) checkDestination(ctx context.Context, repoURL string, config map[string]string) error {
+	if f.policy == nil {
+		return nil
+	}
+
+	var host, port string
+	pin := false
+	if u, err := url.Parse(repoURL); err == nil && len(u.Scheme) > 1 {
+		if u.Scheme == "file" {
+			return nil
+		}
+		host, port = u.Hostname(), u.Port()
+		pin = u.Scheme == "https" || u.Scheme == "http"
+		if port == "" {
+			port = map[string]string{"https": "443", "http": "80"}[u.Scheme]
+		}
+	} else if isLocalPath(repoURL) {
+		return nil
+	} else {
+		host = scpHost(repoURL)
+	}
+	if host == "" {
+		return fmt.Errorf("%w: repo_url has no host", ErrInvalidSource)
+	}
+
+	ips, err := f.policy.ResolveAllowed(ctx, strings.ToLower(host))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSource, err)
+	}
+	if pin && net.ParseIP(host) == nil {
+		addrs := make([]string, len(ips))
+		for i, ip := range ips {
+			addrs[i] = ip.String()
+			if ip.To4() == nil {
+				addrs[i] = "[" + addrs[i] + "]"
+			}
+		}
+		config["http.curloptResolve"] = fmt.Sprintf("%s:%s:%s", host, port, strings.Join(addrs, ","))
+	}
+	return nil
+}
+
+// environment is the wrapper's environment without any git variables, plus config passed through
+// GIT_CONFIG_COUNT so none of it appears on the command line. Transports other than http(s), ssh and
+// git, and file unless local repositories are allowed, are disabled.
+func (f *Fetcher) environment(dir string, config map[string]string) []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, "GIT_") {
+			env = append(env, kv)
+		}
+	}
+
+	fileProtocol := "never"
+	if f.allowLocal {
+		fileProtocol = "always"
+	}
+	settings := [][2]string{
+		{"protocol.allow", "never"},
+		{"protocol.https.allow", "always"},
+		{"protocol.http.allow", "always"},
+		{"protocol.ssh.allow", "always"},
+		{"protocol.git.allow", "always"},
+		{"protocol.file.allow", fileProtocol},
+		// A redirect would leave the host the address policy was applied to
+		{"http.followRedirects", "false"},
+		// Version 2 lets upload-pack serve any commit by ID
+		{"protocol.version", "2"},
+	}
+	for key, value := range config {
+		settings = append(settings, [2]string{key, value})
+	}
+
+	env = append(env,
+		"GIT_DIR="+dir,
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_CONFIG_COUNT="+strconv.Itoa(len(settings)),
+	)
+	for i, setting := range settings {
+		env = append(env, fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i, setting[0]), fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i, setting[1]))
+	}
+	return env
+}
+
+func (f *Fetcher) run(ctx context.Context, env []string, stdout *limitedWriter, args ...string) error {
+	cmd := exec.CommandContext(ctx, f.git, args...)
+	cmd.Env = env
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if stdout != nil {
+		cmd.Stdout = stdout
+	}
+
+	if err := cmd.Run(); err != nil {
+		if stdout != nil && stdout.exceeded {
+			return fmt.Errorf("%w: more than %d bytes", ErrTooLarge, stdout.limit)
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("git %s did not finish in time: %v", args[0], ctx.Err())
+		}
+		return fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// isLocalPath reports whether git would treat repoURL as a path rather than a remote: scp-like
+// "host:path" remotes have a colon before the first slash
+func isLocalPath(repoURL string) bool {
+	colon := strings.Index(repoURL, ":")
+	slash := strings.IndexAny(repoURL, "/\\")
+	if colon < 0 || (slash >= 0 && slash < colon) {
+		return true
+	}
+	// A drive letter, C:\repo
+	return colon == 1
+}
+
+// commitNotServed reports whether a fetch failed because the server has no such commit or refuses to
+// serve it by ID, which git does not tell apart
+func commitNotServed(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"not our ref", "unadvertised object", "couldn't find remote ref", "no such remote ref"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// dirSize is the total size of the files under dir, ignoring any it cannot read
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// scpHost returns the host of an scp-like "[user@]host:path" remote
+func scpHost(repoURL string) string {
+	rest := repoURL
+	if at := strings.Index(rest, "@"); at >= 0 && at < strings.IndexAny(rest, ":[") {
+		rest = rest[at+1:]
+	}
+	if strings.HasPrefix(rest, "[") {
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return ""
+		}
+		// "[user@host:port]:path" names a port, "[::1]:path" an IPv6 address
+		host := rest[1:end]
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		return host[strings.LastIndex(host, "@")+1:]
+	}
+	host, _, _ := strings.Cut(rest, ":")
+	return host
+}
+
+// withinPrefix reports whether repoURL is prefix or a path beneath it. Dot segments are rejected rather
+// than resolved, since they could climb out of the prefix.
+func withinPrefix(repoURL, prefix string) bool {
+	if !strings.HasPrefix(repoURL, prefix) {
+		return false
+	}
+	if rest := repoURL[len(prefix):]; rest != "" && !strings.HasSuffix(prefix, "/") && !strings.HasSuffix(prefix, ":") && rest[0] != '/' {
+		return false
+	}
+	for _, segment := range strings.Split(strings.ReplaceAll(strings.ToLower(repoURL), "%2e", "."), "/") {
+		if segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+type limitedWriter struct {
+	w        *os.File
+	limit    int64
+	n        int64
+	exceeded bool
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	if lw.limit > 0 && lw.n+int64(len(p)) > lw.limit {
+		lw.exceeded = true
+		return 0, ErrTooLarge
+	}
+	n, err := lw.w.Write(p)
+	lw.n += int64(n)
+	return n, err
+}
@@ -1 +1 @@
-package api
+package api
@@ -1,6 +1,6 @@
-package application
-
-type AssignProjectRequest struct {
-	AppName     string `json:"app_name" validate:"required"`
-	ProjectName string `json:"project_name" validate:"required"`
+package application
+
+type AssignProjectRequest struct {
+	AppName     string `json:"app_name" validate:"required"`
+	ProjectName string `json:"project_name" validate:"required"`
 }
\ No newline at end of file
@@ -1 +1 @@
-package configuration
+package configuration
@@ -0,0 +1,270 @@
+// api/v1/scans/compare.go
+package scans
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	cx1 "github.com/madhatkul/CxWrapper-v2/Cx1ClientGo"
+	"github.com/madhatkul/CxWrapper-v2/api/v1/scans/reports"
+)
+
+// errNoCompletedScan is returned when a commit or branch has no completed scan to compare with
+var errNoCompletedScan = errors.New("no completed scan found")
+
+// CompareScans diffs the findings of a scan against a baseline scan of the same project
+func (ss *ScanService) CompareScans(req CompareScansRequest) (*CompareScansResponse, error) {
+	scan, err := ss.resolveCompareScan(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var baseline *cx1.Scan
+	switch {
+	case req.BaselineScanID != "":
+		baseline, err = ss.completedScanByID(req.BaselineScanID)
+		if err != nil {
+			return nil, err
+		}
+		if baseline.ProjectID != scan.ProjectID {
+			return nil, fmt.Errorf("baseline scan %s belongs to a different project than scan %s", baseline.ScanID, scan.ScanID)
+		}
+	case req.BaselineBranch != "":
+		baseline, err = ss.latestCompletedScan(scan.ProjectID, req.BaselineBranch, scan.ScanID)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("baseline_scan_id or baseline_branch is required")
+	}
+
+	current, err := ss.cx1Client.GetAllScanResultsByID(scan.ScanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get results for scan %s: %v", scan.ScanID, err)
+	}
+	previous, err := ss.cx1Client.GetAllScanResultsByID(baseline.ScanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get results for baseline scan %s: %v", baseline.ScanID, err)
+	}
+
+	diff := reports.Compare(reports.Flatten(current), reports.Flatten(previous))
+
+	ss.logger.Infof("✅ Compared scan ID %s with baseline %s: %d new, %d fixed, %d unchanged",
+		scan.ScanID, baseline.ScanID, len(diff.New), len(diff.Fixed), len(diff.Unchanged))
+
+	return &CompareScansResponse{
+		Scan:     toScanRef(scan),
+		Baseline: toScanRef(baseline),
+		Summary: CompareSummary{
+			New:       len(diff.New),
+			Fixed:     len(diff.Fixed),
+			Unchanged: len(diff.Unchanged),
+		},
+		New:       diff.New,
+		Fixed:     diff.Fixed,
+		Unchanged: diff.Unchanged,
+	}, nil
+}
+
+// resolveCompareScan finds the scan to compare: the given scan ID, or the latest completed scan of the commit
+func (ss *ScanService) resolveCompareScan(req CompareScansRequest) (*cx1.Scan, error) {
+	if req.ScanID != "" {
+		return ss.completedScanByID(req.ScanID)
+	}
+	if req.CommitID == "" {
+		return nil, fmt.Errorf("scan_id or commit_id is required")
+	}
+
+	filter := cx1.ScanFilter{
+		TagKeys:   []string{"commit_id"},
+		TagValues: []string{req.CommitID},
+		Statuses:  []string{"Completed"},
+	}
+
+	if req.ProjectName != "" {
+		projects, err := ss.cx1Client.GetProjectsByName(req.ProjectName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find project '%s': %v", req.ProjectName, err)
+		}
+		if len(projects) == 0 {
+			return nil, fmt.Errorf("project '%s' not found", req.ProjectName)
+		}
+		filter.ProjectID = projects[0].ProjectID
+	}
+
+	scans, err := ss.cx1Client.GetLastScansFiltered(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scans: %v", err)
+	}
+	if len(scans) == 0 {
+		return nil, fmt.Errorf("%w for commit_id: %s", errNoCompletedScan, req.CommitID)
+	}
+
+	return &scans[0], nil
+}
+
+func (ss *ScanService) completedScanByID(scanID string) (*cx1.Scan, error) {
+	scan, err := ss.cx1Client.GetScanByID(scanID)
+	if err != nil {
+		return nil, fmt.Errorf("scan %s not found: %v", scanID, err)
+	}
+	if scan.Status != "Completed" {
+		return nil, fmt.Errorf("scan %s is not completed yet (current status: %s)", scanID, scan.Status)
+	}
+	return &scan, nil
+}
+
+// latestCompletedScan returns the most recent completed scan of a project's branch, skipping excludeScanID
+func (ss *ScanService) latestCompletedScan(projectID, branch, excludeScanID string) (*cx1.Scan, error) {
+	filter := cx1.ScanFilter{
+		ProjectID: projectID,
+		Branches:  []string{branch},
+		Statuses:  []string{"Completed"},
+	}
+
+	scans, err := ss.cx1Client.GetLastScansFiltered(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scans for branch %s: %v", branch, err)
+	}
+
+	for i := range scans {
+		if scans[i].ScanID != excludeScanID {
+			return &scans[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w on branch %s", errNoCompletedScan, branch)
+}
+
+func toScanRef(scan *cx1.Scan) ScanRef {
+	return ScanRef{
+		ScanID:    scan.ScanID,
+		ProjectID: scan.ProjectID,
+		Branch:    scan.Branch,
+		CommitID:  scan.Tags["commit_id"],
+		Status:    scan.Status,
+		CreatedAt: scan.CreatedAt,
+	}
+}
+
+// annotateNewFindings adds is_new to every result of a scan whose tags name a target_branch, comparing
+// it with the latest completed scan on that branch. When the target branch has no completed scan every
+// finding is new. Results becomes a JSON object per engine; the typed set stays available to the renderers.
+func (ss *ScanService) annotateNewFindings(response *ScanResultResponse, scan *cx1.Scan, results cx1.ScanResultSet) {
+	targetBranch := scan.Tags["target_branch"]
+	if targetBranch == "" || targetBranch == scan.Branch {
+		return
+	}
+	response.TargetBranch = targetBranch
+
+	baselineKeys := make(map[string]bool)
+	baseline, err := ss.latestCompletedScan(scan.ProjectID, targetBranch, scan.ScanID)
+	switch {
+	case err == nil:
+		baselineResults, err := ss.cx1Client.GetAllScanResultsByID(baseline.ScanID)
+		if err != nil {
+			ss.logger.Warnf("Failed to get results of baseline scan %s for scan ID %s: %v", baseline.ScanID, scan.ScanID, err)
+			msg := fmt.Sprintf("Failed to get results of baseline scan %s: %v", baseline.ScanID, err)
+			response.BaselineError = &msg
+			return
+		}
+		for _, finding := range reports.Flatten(baselineResults) {
+			baselineKeys[finding.Key()] = true
+		}
+		response.BaselineScanID = baseline.ScanID
+	case errors.Is(err, errNoCompletedScan):
+		ss.logger.Infof("No completed scan on target branch %s, every finding of scan ID %s is new", targetBranch, scan.ScanID)
+	default:
+		ss.logger.Warnf("Failed to find baseline scan on %s for scan ID %s: %v", targetBranch, scan.ScanID, err)
+		msg := fmt.Sprintf("Failed to find baseline scan on %s: %v", targetBranch, err)
+		response.BaselineError = &msg
+		return
+	}
+
+	annotated, newCount, err := annotateResults(results, baselineKeys)
+	if err != nil {
+		ss.logger.Warnf("Failed to annotate results of scan ID %s: %v", scan.ScanID, err)
+		msg := fmt.Sprintf("Failed to annotate results: %v", err)
+		response.BaselineError = &msg
+		return
+	}
+
+	response.Results = annotated
+	response.resultSet = &results
+	response.Summary.NewResults = &newCount
+}
+
+// annotateResults marshals the result set and sets is_new on each result. Results are matched to their
+// finding by position, since each engine's list keeps the order Flatten walks it in.
+func annotateResults(results cx1.ScanResultSet, baselineKeys map[string]bool) (map[string]interface{}, int, error) {
+	byEngine := make(map[string][]reports.Finding)
+	for _, finding := range reports.Flatten(results) {
+		byEngine[finding.Engine] = append(byEngine[finding.Engine], finding)
+	}
+
+	engineLists := map[string]interface{}{
+		reports.EngineSAST:    results.SAST,
+		reports.EngineSCA:     results.SCA,
+		reports.EngineKICS:    results.KICS,
+		reports.EngineSecrets: results.Secrets,
+	}
+
+	// Keep the field names the results are normally serialised with
+	raw, err := json.Marshal(results)
+	if err != nil {
+		return nil, 0, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, 0, err
+	}
+
+	annotated := make(map[string]interface{}, len(fields))
+	for name, value := range fields {
+		annotated[name] = value
+	}
+
+	newCount := 0
+	for engine, list := range engineLists {
+		name := fieldNameFor(fields, engine)
+		if name == "" {
+			continue
+		}
+
+		data, err := json.Marshal(list)
+		if err != nil {
+			return nil, 0, err
+		}
+		var items []map[string]interface{}
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, 0, err
+		}
+
+		findings := byEngine[engine]
+		for i, item := range items {
+			if i >= len(findings) {
+				break
+			}
+			isNew := !baselineKeys[findings[i].Key()]
+			item["is_new"] = isNew
+			if isNew {
+				newCount++
+			}
+		}
+		annotated[name] = items
+	}
+
+	return annotated, newCount, nil
+}
+
+// fieldNameFor finds the serialised name of an engine's result list, e.g. "SAST" or "secrets"
+func fieldNameFor(fields map[string]json.RawMessage, engine string) string {
+	for name := range fields {
+		if strings.EqualFold(name, engine) {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,135 @@
+// api/v1/scans/formats.go
+package scans
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	cx1 "github.com/madhatkul/CxWrapper-v2/Cx1ClientGo"
+	"github.com/madhatkul/CxWrapper-v2/api/v1/scans/reports"
+)
+
+// Output formats of the results endpoint
+const (
+	FormatJSON  = "json"
+	FormatSARIF = "sarif"
+	FormatJUnit = "junit"
+
+	// GitLab security reports, named after the artifact files GitLab expects
+	FormatGitLabSAST               = "gl-sast"
+	FormatGitLabDependencyScanning = "gl-dependency-scanning"
+	FormatGitLabSecretDetection    = "gl-secret-detection"
+)
+
+var resultFormats = map[string]bool{
+	FormatJSON:  true,
+	FormatSARIF: true,
+	FormatJUnit: true,
+
+	FormatGitLabSAST:               true,
+	FormatGitLabDependencyScanning: true,
+	FormatGitLabSecretDetection:    true,
+}
+
+var gitLabReportTypes = map[string]string{
+	FormatGitLabSAST:               reports.GitLabSAST,
+	FormatGitLabDependencyScanning: reports.GitLabDependencyScanning,
+	FormatGitLabSecretDetection:    reports.GitLabSecretDetection,
+}
+
+// reportOptions are the query parameters that control how results are rendered
+type reportOptions struct {
+	Format            string
+	SeverityThreshold string
+}
+
+// parseReportOptions reads the format query parameter, falling back to the Accept header, and the
+// options of the chosen format. JUNIT_SEVERITY_THRESHOLD sets the default JUnit threshold.
+func parseReportOptions(c *gin.Context) (reportOptions, error) {
+	opts := reportOptions{Format: strings.ToLower(c.Query("format"))}
+	if opts.Format == "" {
+		opts.Format = FormatJSON
+		if strings.Contains(c.GetHeader("Accept"), reports.ContentTypeSARIF) {
+			opts.Format = FormatSARIF
+		}
+	}
+	if !resultFormats[opts.Format] {
+		return opts, fmt.Errorf("unsupported format: %s", opts.Format)
+	}
+
+	if opts.Format == FormatJUnit {
+		opts.SeverityThreshold = c.Query("severity_threshold")
+		if opts.SeverityThreshold == "" {
+			opts.SeverityThreshold = os.Getenv("JUNIT_SEVERITY_THRESHOLD")
+		}
+		if opts.SeverityThreshold == "" {
+			opts.SeverityThreshold = reports.DefaultJUnitThreshold
+		}
+		if !reports.ValidSeverity(opts.SeverityThreshold) {
+			return opts, fmt.Errorf("invalid severity_threshold: %s", opts.SeverityThreshold)
+		}
+	}
+
+	return opts, nil
+}
+
+// renderReport converts the results of a commit into a non-JSON format, returning the body and its content type
+func renderReport(opts reportOptions, results *AllScansResponse) ([]byte, string, error) {
+	scans := reportScans(results)
+
+	switch opts.Format {
+	case FormatSARIF:
+		data, err := reports.SARIF(scans)
+		return data, reports.ContentTypeSARIF, err
+	case FormatJUnit:
+		data, err := reports.JUnit(scans, opts.SeverityThreshold)
+		return data, reports.ContentTypeJUnit, err
+	case FormatGitLabSAST, FormatGitLabDependencyScanning, FormatGitLabSecretDetection:
+		data, err := reports.GitLab(gitLabReportTypes[opts.Format], scans)
+		return data, "application/json", err
+	}
+
+	return nil, "", fmt.Errorf("unsupported format: %s", opts.Format)
+}
+
+// reportScans collects the scans of a commit that have results, full scan first
+func reportScans(response *AllScansResponse) []reports.Scan {
+	var scans []reports.Scan
+	for _, result := range []*ScanResultResponse{response.Scans.Full, response.Scans.Fast} {
+		if scan, ok := toReportScan(result, response.ProjectName); ok {
+			scans = append(scans, scan)
+		}
+	}
+	return scans
+}
+
+func toReportScan(result *ScanResultResponse, projectName string) (reports.Scan, bool) {
+	if result == nil {
+		return reports.Scan{}, false
+	}
+	results, ok := result.Results.(cx1.ScanResultSet)
+	if !ok && result.resultSet != nil {
+		results, ok = *result.resultSet, true
+	}
+	if !ok {
+		return reports.Scan{}, false
+	}
+
+	return reports.Scan{
+		Info: reports.ScanInfo{
+			ScanID:          result.ScanID,
+			ProjectID:       result.ProjectID,
+			ProjectName:     projectName,
+			Branch:          result.Branch,
+			CommitID:        result.CommitID,
+			Link:            result.Link,
+			IsFastScan:      result.IsFastScan,
+			IsPolicyBlocked: result.BreakBuild,
+			CreatedAt:       result.CreatedAt,
+			UpdatedAt:       result.UpdatedAt,
+		},
+		Results: results,
+	}, true
+}
@@ -1,539 +1,955 @@
-// api/v1/scans/handlers.go
-package scans
-
-import (
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"strconv"
-	"strings"
-	"time"
-
-	"github.com/gin-gonic/gin"
-	cx1 "github.com/madhatkul/CxWrapper-v2/Cx1ClientGo"
-	"github.com/madhatkul/CxWrapper-v2/util"
-)
-
-type ScanHandler struct {
-	service *ScanService
-	logger  util.Logger
-}
-
-func NewScanHandler(service *ScanService, logger util.Logger) *ScanHandler {
-	return &ScanHandler{
-		service: service,
-		logger:  logger,
-	}
-}
-
-// RegisterRoutes registers all scan routes with the given router group
-func (sh *ScanHandler) RegisterRoutes(v1 *gin.RouterGroup) {
-	scans := v1.Group("/scans")
-	{
-		// Apply file size limit middleware to upload endpoints
-		staticGroup := scans.Group("/static")
-		//uploadGroup.Use(FileSizeLimitMiddleware(500 << 20))
-		{
-			staticGroup.POST("", sh.StartStaticScan)
-			staticGroup.GET("", sh.ListScans)
-			staticGroup.GET("/results", sh.GetScanResults)
-			staticGroup.GET("/status", sh.GetScanStatus)
-			staticGroup.POST("/cancel", sh.CancelScan)
-			staticGroup.GET("/presets", sh.getPreset)
-			// staticGroup.GET("/config", sh.getTempConfig)
-			//staticGroup.GET("PollingStatus", sh.PollingStatus)
-		}
-	}
-}
-
-func (sh *ScanHandler) StartStaticScan(c *gin.Context) {
-	sh.logger.Infof("🚀 Static scan handler reached")
-
-	if err := c.Request.ParseMultipartForm(1 << 30); err != nil {
-		sh.logger.Errorf("❌ Failed to parse multipart form: %v", err)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Failed to parse multipart form", Details: err.Error()})
-		return
-	}
-
-	// Read form values directly for reliability
-	appName := c.Request.FormValue("app_name")
-	projectName := c.Request.FormValue("project_name")
-	branch := c.Request.FormValue("branch")
-	commitID := c.Request.FormValue("commit_id")
-	scanTypesStr := c.Request.FormValue("scan_types")
-	tagsStr := c.Request.FormValue("tags")
-	isFastScanStr := c.Request.FormValue("is_fast_scan")
-	presetStr := c.Request.FormValue("preset")
-
-	sh.logger.Infof("Received raw 'is_fast_scan' value from form: '%s'", isFastScanStr)
-
-	if appName == "" || projectName == "" || branch == "" || commitID == "" || scanTypesStr == "" || isFastScanStr == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Missing required fields: app_name, project_name, branch, commit_id, scan_types,is_fast_scan "})
-		return
-	}
-
-	file, fileHeader, err := c.Request.FormFile("zip_file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "No zip file uploaded", Details: err.Error()})
-		return
-	}
-	defer file.Close()
-
-	// fileContents, err := io.ReadAll(file)
-	// if err != nil {
-	// 	c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to read file contents", Details: err.Error()})
-	// 	return
-	// }
-
-	scanTypes, _ := sh.parseScanTypes(scanTypesStr)
-	tags, _ := sh.parseTags(tagsStr)
-	tags["commit_id"] = commitID
-
-	isFastScan := strings.ToLower(isFastScanStr) == "true"
-	sh.logger.Infof("Parsed 'is_fast_scan' as: %v", isFastScan)
-
-	req := StaticScanRequestWithFile{
-		AppName:     appName,
-		ProjectName: projectName,
-		Branch:      branch,
-		CommitID:    commitID,
-		ScanTypes:   scanTypes,
-		IsFastScan:  isFastScan,
-		Preset:      presetStr,
-		Tags:        tags,
-		File:        file,
-		FileSize:    fileHeader.Size,
-		FileName:    fileHeader.Filename,
-	}
-
-	scan, err := sh.service.StartStaticScanWithFile(req)
-	if err != nil {
-		sh.logger.Errorf("❌ Failed to start static scan: %v", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to start scan", Details: err.Error()})
-		return
-	}
-
-	sh.logger.Infof("✅ Static scan initiated successfully: ScanID=%s", scan.ScanID)
-	c.JSON(http.StatusOK, ScanResponse{
-		ScanID:  scan.ScanID,
-		Status:  "started",
-		Message: "Static scan initiated successfully",
-	})
-}
-
-// GetScanStatus gets scan status by commit_id
-func (sh *ScanHandler) GetScanStatus(c *gin.Context) {
-	commitID := c.Query("commit_id")
-	projectName := c.Query("project_name") // Optional: to narrow down search
-
-	// Validate required parameters
-	if commitID == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:     "Missing required parameter",
-			Details:   "commit_id is required",
-			Timestamp: time.Now().Format(time.RFC3339),
-			Path:      c.Request.URL.Path,
-		})
-		return
-	}
-
-	status, err := sh.service.GetScanStatusByCommitID(commitID, projectName)
-	if err != nil {
-		// Determine appropriate HTTP status code based on error type
-		statusCode := http.StatusInternalServerError
-		if err.Error() == "scan not found" || err.Error() == "no scans found for commit_id" {
-			statusCode = http.StatusNotFound
-		}
-
-		c.JSON(statusCode, ErrorResponse{
-			Error:     "Failed to get scan status",
-			Details:   err.Error(),
-			Timestamp: time.Now().Format(time.RFC3339),
-			Path:      c.Request.URL.Path,
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, status)
-}
-
-// GetScanResults returns the results for a specific scan
-func (sh *ScanHandler) GetScanResults(c *gin.Context) {
-	commitID := c.Query("commit_id")
-	projectName := c.Query("project_name") // Optional: to narrow down search
-
-	// Validate required parameters
-	if commitID == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:     "Missing required parameter",
-			Details:   "commit_id is required",
-			Timestamp: time.Now().Format(time.RFC3339),
-			Path:      c.Request.URL.Path,
-		})
-		return
-	}
-
-	results, err := sh.service.GetAllScanResultsByCommitID(commitID, projectName)
-	if err != nil {
-		// Determine appropriate HTTP status code based on error type
-		statusCode := http.StatusInternalServerError
-		//if contains(err.Error(), "scan not found") ||
-		//	contains(err.Error(), "no scans found") {
-		//	statusCode = http.StatusNotFound
-		//} else if contains(err.Error(), "not completed") {
-		//	statusCode = http.StatusPreconditionFailed // 412 - scan not ready
-		//}
-
-		c.JSON(statusCode, ErrorResponse{
-			Error:     "Failed to get scan results",
-			Details:   err.Error(),
-			Timestamp: time.Now().Format(time.RFC3339),
-			Path:      c.Request.URL.Path,
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, results)
-}
-
-// ListScans lists scans with filtering options
-func (sh *ScanHandler) ListScans(c *gin.Context) {
-	var req ListScansRequest
-
-	// Parse query parameters
-	if projectName := c.Query("project_name"); projectName != "" {
-		req.ProjectName = projectName
-	}
-
-	if commitID := c.Query("commit_id"); commitID != "" {
-		req.CommitID = commitID
-	}
-
-	// Parse pagination parameters
-	if limit := c.Query("limit"); limit != "" {
-		if l, err := strconv.Atoi(limit); err == nil && l > 0 {
-			req.Limit = l
-		}
-	}
-	if req.Limit <= 0 {
-		req.Limit = 20 // Default limit
-	}
-
-	if offset := c.Query("offset"); offset != "" {
-		if o, err := strconv.Atoi(offset); o >= 0 && err == nil {
-			req.Offset = o
-		}
-	}
-
-	// Call service method to get filtered scans
-	response, err := sh.service.ListScansFiltered(req)
-	if err != nil {
-		sh.logger.Errorf("❌ Failed to list scans: %v", err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error:     "Failed to list scans",
-			Details:   err.Error(),
-			Timestamp: time.Now().Format(time.RFC3339),
-			Path:      c.Request.URL.Path,
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, response)
-}
-
-// CancelScan cancels a running scan
-func (sh *ScanHandler) CancelScan(c *gin.Context) {
-	commitID := c.Query("commit_id")
-	projectName := c.Query("project_name") // Optional query parameter
-
-	// Route would be: /api/scans/:commit_id/cancel?project_name=optional
-
-	err := sh.service.CancelScan(commitID, projectName)
-	if err != nil {
-		if err.Error() == "scan not found" {
-			c.JSON(http.StatusNotFound, ErrorResponse{
-				Error:     err.Error(),
-				Timestamp: time.Now().Format(time.RFC3339),
-				Path:      c.Request.URL.Path,
-			})
-		} else {
-			c.JSON(http.StatusBadRequest, ErrorResponse{
-				Error:     err.Error(),
-				Timestamp: time.Now().Format(time.RFC3339),
-				Path:      c.Request.URL.Path,
-			})
-		}
-		return
-	}
-
-	response := gin.H{
-		"commit_id": commitID,
-		"status":    "cancelled",
-		"message":   "SUCCESS",
-	}
-
-	// Only include project_name in response if it was provided
-	if projectName != "" {
-		response["project_name"] = projectName
-	}
-
-	c.JSON(http.StatusOK, response)
-}
-
-func (sh *ScanHandler) parseScanConfigurations(configStr string, scanTypes []string) ([]cx1.ScanConfiguration, error) {
-	var configurations []cx1.ScanConfiguration
-
-	// If no config provided, generate default configurations
-	if configStr == "" {
-		sh.logger.Infof("No scan configuration provided, generating defaults")
-		return sh.generateDefaultConfigurations(scanTypes), nil
-	}
-
-	// Parse JSON configuration
-	if err := json.Unmarshal([]byte(configStr), &configurations); err != nil {
-		return nil, fmt.Errorf("invalid JSON format in config: %v", err)
-	}
-
-	// Validate configurations
-	if err := sh.validateScanConfigurations(configurations, scanTypes); err != nil {
-		return nil, err
-	}
-
-	sh.logger.Infof("Using provided scan configurations: %+v", configurations)
-	return configurations, nil
-}
-
-// validateScanConfigurations validates that provided configurations match requested scan types
-func (sh *ScanHandler) validateScanConfigurations(configurations []cx1.ScanConfiguration, scanTypes []string) error {
-	// Create a map of valid scan types
-	validScanTypes := map[string]bool{
-		"sast":         true,
-		"sca":          true,
-		"secrets":      true,
-		"kics":         true,
-		"containersec": true,
-		"apisec":       true,
-	}
-
-	// Create a map of requested scan types
-	requestedTypes := make(map[string]bool)
-	for _, scanType := range scanTypes {
-		requestedTypes[strings.ToLower(scanType)] = true
-	}
-
-	// Validate each configuration
-	configTypes := make(map[string]bool)
-	for i, config := range configurations {
-		// Check if scan type is valid
-		if !validScanTypes[strings.ToLower(config.ScanType)] {
-			return fmt.Errorf("invalid scan type in configuration[%d]: %s", i, config.ScanType)
-		}
-
-		// Check if scan type was requested
-		if !requestedTypes[strings.ToLower(config.ScanType)] {
-			return fmt.Errorf("configuration provided for unrequested scan type: %s", config.ScanType)
-		}
-
-		// Check for duplicate configurations
-		lowerType := strings.ToLower(config.ScanType)
-		if configTypes[lowerType] {
-			return fmt.Errorf("duplicate configuration for scan type: %s", config.ScanType)
-		}
-		configTypes[lowerType] = true
-
-		// Validate configuration values based on scan type
-		if err := sh.validateConfigurationValues(config); err != nil {
-			return fmt.Errorf("invalid configuration for %s: %v", config.ScanType, err)
-		}
-	}
-
-	return nil
-}
-
-// validateConfigurationValues validates configuration values for specific scan types
-func (sh *ScanHandler) validateConfigurationValues(config cx1.ScanConfiguration) error {
-	switch strings.ToLower(config.ScanType) {
-	case "sast":
-		// Validate SAST-specific configurations
-		if preset, exists := config.Values["presetName"]; exists && preset == "" {
-			return fmt.Errorf("presetName cannot be empty")
-		}
-		if incremental, exists := config.Values["incremental"]; exists {
-			if incremental != "true" && incremental != "false" {
-				return fmt.Errorf("incremental must be 'true' or 'false', got: %s", incremental)
-			}
-		}
-		if verbose, exists := config.Values["engineVerbose"]; exists {
-			if verbose != "true" && verbose != "false" {
-				return fmt.Errorf("engineVerbose must be 'true' or 'false', got: %s", verbose)
-			}
-		}
-
-	case "sca":
-		// Validate SCA-specific configurations
-		if exploitable, exists := config.Values["exploitablePath"]; exists {
-			if exploitable != "true" && exploitable != "false" {
-				return fmt.Errorf("exploitablePath must be 'true' or 'false', got: %s", exploitable)
-			}
-		}
-
-	// Add validation for other scan types as needed
-	case "secrets", "kics", "containersec", "apisec":
-		// These typically don't require specific validation for now
-		break
-	}
-
-	return nil
-}
-
-// generateDefaultConfigurations creates default configurations for scan types
-func (sh *ScanHandler) generateDefaultConfigurations(scanTypes []string) []cx1.ScanConfiguration {
-	var configurations []cx1.ScanConfiguration
-
-	for _, scanType := range scanTypes {
-		switch strings.ToLower(scanType) {
-		case "sast":
-			configurations = append(configurations, cx1.ScanConfiguration{
-				ScanType: "sast",
-				Values: map[string]string{
-					"incremental":   "false",
-					"presetName":    "Checkmarx Default",
-					"engineVerbose": "false",
-				},
-			})
-
-		case "sca":
-			configurations = append(configurations, cx1.ScanConfiguration{
-				ScanType: "sca",
-				Values: map[string]string{
-					"lastSastScanTime": "",
-					"exploitablePath":  "false",
-				},
-			})
-
-		case "secrets":
-			configurations = append(configurations, cx1.ScanConfiguration{
-				ScanType: "secrets",
-				Values:   map[string]string{},
-			})
-
-		case "kics":
-			configurations = append(configurations, cx1.ScanConfiguration{
-				ScanType: "kics",
-				Values:   map[string]string{},
-			})
-
-		case "containersec":
-			configurations = append(configurations, cx1.ScanConfiguration{
-				ScanType: "container",
-				Values:   map[string]string{},
-			})
-
-		case "apisec":
-			configurations = append(configurations, cx1.ScanConfiguration{
-				ScanType: "apisec",
-				Values:   map[string]string{},
-			})
-		}
-	}
-
-	return configurations
-}
-
-// Helper method to parse and validate scan types (unchanged)
-func (sh *ScanHandler) parseScanTypes(scanTypesStr string) ([]string, error) {
-	var scanTypes []string
-
-	if scanTypesStr != "" {
-		scanTypes = strings.Split(strings.ReplaceAll(scanTypesStr, " ", ""), ",")
-	} else {
-		scanTypes = []string{"sast", "sca"} // default
-	}
-
-	// Validate scan types
-	validScanTypes := map[string]bool{
-		"sast":         true,
-		"sca":          true,
-		"microengines": true,
-		"kics":         true,
-		"containers":   true,
-		"apisec":       true,
-	}
-
-	for _, scanType := range scanTypes {
-		if !validScanTypes[strings.ToLower(scanType)] {
-			return nil, fmt.Errorf("invalid scan type: %s. Valid types: sast,sca,secrets,kics,containersec,apisec", scanType)
-		}
-	}
-
-	return scanTypes, nil
-}
-
-// Helper method to parse tags from string format (unchanged)
-func (sh *ScanHandler) parseTags(tagsStr string) (map[string]string, error) {
-	tags := make(map[string]string)
-
-	if tagsStr == "" {
-		return tags, nil
-	}
-
-	tagPairs := strings.Split(tagsStr, ",")
-	for _, pair := range tagPairs {
-		pair = strings.TrimSpace(pair)
-		if pair == "" {
-			continue
-		}
-
-		kv := strings.SplitN(pair, ":", 2)
-		if len(kv) != 2 {
-			return nil, fmt.Errorf("invalid tag format: %s. Expected format: key:value", pair)
-		}
-
-		key := strings.TrimSpace(kv[0])
-		value := strings.TrimSpace(kv[1])
-
-		if key == "" {
-			return nil, fmt.Errorf("empty tag key in: %s", pair)
-		}
-
-		tags[key] = value
-	}
-
-	return tags, nil
-}
-
-// Helper method to validate zip file (unchanged)
-func (sh *ScanHandler) isValidZipFile(filename string) bool {
-	return strings.HasSuffix(strings.ToLower(filename), ".zip")
-}
-
-func (sh *ScanHandler) getPreset(c *gin.Context) {
-	response := gin.H{
-		"presets": []string{"K-Web", "K-API", "K-Mobile"},
-	}
-
-	c.JSON(http.StatusOK, response)
-}
-
-// func (sh *ScanHandler) getTempConfig(c *gin.Context) {
-// 	projectID := c.Query("project_id")
-
-// 	data, err := sh.service.TempGetConfig(projectID)
-// 	if err != nil {
-// 		// Determine appropriate HTTP status code based on error type
-// 		statusCode := http.StatusInternalServerError
-// 		if err.Error() == "scan not found" || err.Error() == "no scans found for commit_id" {
-// 			statusCode = http.StatusNotFound
-// 		}
-
-// 		c.JSON(statusCode, ErrorResponse{
-// 			Error:     "Failed to get scan status",
-// 			Details:   err.Error(),
-// 			Timestamp: time.Now().Format(time.RFC3339),
-// 			Path:      c.Request.URL.Path,
-// 		})
-// 		return
-// 	}
-
-// 	c.JSON(http.StatusOK, data)
-// }
+// api/v1/scans/handlers.go
+package scans
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	cx1 "github.com/madhatkul/CxWrapper-v2/Cx1ClientGo"
+	"github.com/madhatkul/CxWrapper-v2/api/archive"
+	"github.com/madhatkul/CxWrapper-v2/api/gitsource"
+	"github.com/madhatkul/CxWrapper-v2/api/v1/uploads"
+	"github.com/madhatkul/CxWrapper-v2/util"
+)
+
+type ScanHandler struct {
+	service       *ScanService
+	logger        util.Logger
+	maxUploadSize int64
+}
+
+func NewScanHandler(service *ScanService, logger util.Logger) *ScanHandler {
+	return &ScanHandler{
+		service:       service,
+		logger:        logger,
+		maxUploadSize: maxUploadSizeFromEnv(),
+	}
+}
+
+// RegisterRoutes registers all scan routes with the given router group
+func (sh *ScanHandler) RegisterRoutes(v1 *gin.RouterGroup) {
+	scans := v1.Group("/scans")
+	{
+		// Apply file size limit middleware to upload endpoints
+		staticGroup := scans.Group("/static")
+		//uploadGroup.Use(FileSizeLimitMiddleware(500 << 20))
+		{
+			staticGroup.POST("", sh.StartStaticScan)
+			staticGroup.GET("", sh.ListScans)
+			staticGroup.GET("/results", sh.GetScanResults)
+			staticGroup.GET("/compare", sh.CompareScans)
+			staticGroup.GET("/status", sh.GetScanStatus)
+			staticGroup.GET("/status/stream", sh.StreamScanStatus)
+			staticGroup.GET("/wait", sh.WaitForScanResults)
+			staticGroup.POST("/cancel", sh.CancelScan)
+			staticGroup.GET("/presets", sh.getPreset)
+			// staticGroup.GET("/config", sh.getTempConfig)
+			//staticGroup.GET("PollingStatus", sh.PollingStatus)
+		}
+	}
+}
+
+func (sh *ScanHandler) StartStaticScan(c *gin.Context) {
+	sh.logger.Infof("🚀 Static scan handler reached")
+
+	if c.Request.ContentLength > sh.maxUploadSize+maxFormOverhead {
+		c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: "Upload too large", Details: fmt.Sprintf("zip_file must not exceed %d bytes", sh.maxUploadSize)})
+		return
+	}
+
+	// Stream the form instead of parsing it up front: fields are read as they arrive and the zip file is
+	// passed straight on to Cx1, so it must be the last part
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		sh.logger.Errorf("❌ Failed to read multipart form: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Failed to parse multipart form", Details: err.Error()})
+		return
+	}
+
+	form, err := readScanForm(reader, "zip_file")
+	if err != nil {
+		sh.logger.Errorf("❌ Failed to parse multipart form: %v", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Failed to parse multipart form", Details: err.Error()})
+		return
+	}
+
+	// Read form values directly for reliability
+	appName := form.value("app_name")
+	projectName := form.value("project_name")
+	branch := form.value("branch")
+	commitID := form.value("commit_id")
+	scanTypesStr := form.value("scan_types")
+	tagsStr := form.value("tags")
+	isFastScanStr := form.value("is_fast_scan")
+	presetStr := form.value("preset")
+	targetBranch := form.value("target_branch")
+	idempotencyKey := strings.TrimSpace(c.GetHeader("Idempotency-Key"))
+
+	sh.logger.Infof("Received raw 'is_fast_scan' value from form: '%s'", isFastScanStr)
+
+	// Rejected before a byte of the archive is read
+	if appName == "" || projectName == "" || branch == "" || commitID == "" || scanTypesStr == "" || isFastScanStr == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Missing required fields: app_name, project_name, branch, commit_id, scan_types,is_fast_scan ", Details: "all fields must be sent before zip_file"})
+		return
+	}
+
+	if err := validateIdempotencyKey(idempotencyKey); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid Idempotency-Key header", Details: err.Error()})
+		return
+	}
+
+	force := false
+	forceStr := form.value("force")
+	if forceStr == "" {
+		forceStr = c.Query("force")
+	}
+	if forceStr != "" {
+		parsed, err := strconv.ParseBool(forceStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid force value", Details: err.Error()})
+			return
+		}
+		force = parsed
+	}
+
+	// cancel_superseded overrides the project's policy when sent
+	var cancelSuperseded *bool
+	if v := form.value("cancel_superseded"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid cancel_superseded value", Details: err.Error()})
+			return
+		}
+		cancelSuperseded = &parsed
+	}
+
+	// The archive is the zip_file part, a completed resumable upload referenced by upload_id, or commit_id
+	// of the git repository at repo_url, which the wrapper fetches itself
+	uploadID := strings.TrimSpace(form.value("upload_id"))
+	repoURL := strings.TrimSpace(form.value("repo_url"))
+	if form.file != nil {
+		defer form.file.Close()
+	}
+	sources := 0
+	for _, given := range []bool{form.file != nil, uploadID != "", repoURL != ""} {
+		if given {
+			sources++
+		}
+	}
+	switch {
+	case sources > 1:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Ambiguous archive", Details: "send only one of zip_file, upload_id and repo_url"})
+		return
+	case sources == 0:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "No zip file uploaded", Details: "the form has no zip_file part, upload_id or repo_url"})
+		return
+	}
+
+	// Cx1 needs the archive's size up front. Clients that send zip_file_size get their archive streamed
+	// through; otherwise it is spooled to a temporary file, still within the size limit.
+	var file io.Reader
+	var fileSize int64
+	var fileName string
+	var source *gitsource.Source
+	switch {
+	case repoURL != "":
+		// Fetched by the service once it knows the request is not a duplicate
+		source = &gitsource.Source{
+			RepoURL:        repoURL,
+			Commit:         commitID,
+			CredentialsRef: strings.TrimSpace(form.value("credentials_ref")),
+		}
+	case uploadID != "":
+		uploaded, upload, err := sh.service.uploads.Open(uploadID)
+		if err != nil {
+			sh.logger.Errorf("❌ Failed to open upload %s: %v", uploadID, err)
+			statusCode := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, uploads.ErrUploadNotFound), errors.Is(err, uploads.ErrUploadExpired):
+				statusCode = http.StatusNotFound
+			case errors.Is(err, uploads.ErrUploadIncomplete):
+				statusCode = http.StatusConflict
+			}
+			c.JSON(statusCode, ErrorResponse{Error: "Failed to open upload", Details: err.Error()})
+			return
+		}
+		defer uploaded.Close()
+		file, fileSize, fileName = uploaded, upload.Length, upload.FileName
+	case form.value("zip_file_size") != "":
+		fileSize, err = strconv.ParseInt(form.value("zip_file_size"), 10, 64)
+		if err != nil || fileSize <= 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid zip_file_size value", Details: "zip_file_size must be a positive number of bytes"})
+			return
+		}
+		if fileSize > sh.maxUploadSize {
+			c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: "Upload too large", Details: fmt.Sprintf("zip_file must not exceed %d bytes", sh.maxUploadSize)})
+			return
+		}
+		file, fileName = newSizedReader(form.file, fileSize, sh.maxUploadSize), form.file.FileName()
+	default:
+		spooled, size, err := spoolUpload(form.file, sh.maxUploadSize)
+		if err != nil {
+			sh.logger.Errorf("❌ Failed to receive zip file: %v", err)
+			statusCode := http.StatusBadRequest
+			if errors.Is(err, ErrUploadTooLarge) {
+				statusCode = http.StatusRequestEntityTooLarge
+			}
+			c.JSON(statusCode, ErrorResponse{Error: "Failed to receive zip file", Details: err.Error()})
+			return
+		}
+		defer os.Remove(spooled.Name())
+		defer spooled.Close()
+		file, fileSize, fileName = spooled, size, form.file.FileName()
+	}
+
+	scanTypes, _ := sh.parseScanTypes(scanTypesStr)
+	tags, _ := sh.parseTags(tagsStr)
+	tags["commit_id"] = commitID
+
+	isFastScan := strings.ToLower(isFastScanStr) == "true"
+	sh.logger.Infof("Parsed 'is_fast_scan' as: %v", isFastScan)
+
+	req := StaticScanRequestWithFile{
+		AppName:          appName,
+		ProjectName:      projectName,
+		Branch:           branch,
+		CommitID:         commitID,
+		ScanTypes:        scanTypes,
+		IsFastScan:       isFastScan,
+		Preset:           presetStr,
+		TargetBranch:     targetBranch,
+		Tags:             tags,
+		IdempotencyKey:   idempotencyKey,
+		Force:            force,
+		CancelSuperseded: cancelSuperseded,
+		Excludes:         archive.SplitGlobs(form.value("exclude")),
+		Source:           source,
+		File:             file,
+		FileSize:         fileSize,
+		FileName:         fileName,
+	}
+
+	submission, err := sh.service.StartStaticScanWithFile(req)
+	if err != nil {
+		sh.logger.Errorf("❌ Failed to start static scan: %v", err)
+		statusCode := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrUploadTooLarge), errors.Is(err, gitsource.ErrTooLarge):
+			statusCode = http.StatusRequestEntityTooLarge
+		case errors.Is(err, gitsource.ErrInvalidSource), errors.Is(err, gitsource.ErrUnknownCredentials):
+			statusCode = http.StatusBadRequest
+		case errors.Is(err, gitsource.ErrCommitNotFound):
+			statusCode = http.StatusNotFound
+		case errors.Is(err, gitsource.ErrFetchFailed):
+			statusCode = http.StatusBadGateway
+		case errors.Is(err, archive.ErrInvalidArchive):
+			statusCode = http.StatusBadRequest
+		case errors.Is(err, archive.ErrUnsafeArchive), errors.Is(err, ErrNothingToScan):
+			statusCode = http.StatusUnprocessableEntity
+		case errors.Is(err, ErrIdempotencyKeyReused):
+			statusCode = http.StatusUnprocessableEntity
+		case errors.Is(err, ErrIdempotencyKeyInProgress):
+			statusCode = http.StatusConflict
+		}
+		c.JSON(statusCode, ErrorResponse{Error: "Failed to start scan", Details: err.Error()})
+		return
+	}
+
+	if uploadID != "" && !submission.Duplicate && !submission.Replayed {
+		if err := sh.service.uploads.MarkConsumed(uploadID); err != nil {
+			sh.logger.Warnf("⚠️ Failed to mark upload %s as consumed: %v", uploadID, err)
+		}
+	}
+
+	if submission.Replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+
+	if submission.Duplicate {
+		sh.logger.Infof("♻️ Returning existing scan instead of starting a duplicate: ScanID=%s", submission.ScanID)
+		c.JSON(http.StatusOK, ScanResponse{
+			ScanID:    submission.ScanID,
+			Status:    "existing",
+			Message:   "An equivalent scan is already queued or running for this commit; set force=true to start another",
+			Duplicate: true,
+			JobID:     submission.JobID,
+			Queue:     submission.Queue,
+		})
+		return
+	}
+
+	if submission.Queue != nil {
+		sh.logger.Infof("⏳ Static scan queued: JobID=%s, position %d", submission.JobID, submission.Queue.Position)
+		c.JSON(http.StatusAccepted, ScanResponse{
+			Status:     "queued",
+			Message:    fmt.Sprintf("Scan queued at position %d of %d; it starts when a scan slot frees", submission.Queue.Position, submission.Queue.Length),
+			JobID:      submission.JobID,
+			Queue:      submission.Queue,
+			Archive:    submission.Archive,
+			Superseded: submission.Superseded,
+		})
+		return
+	}
+
+	sh.logger.Infof("✅ Static scan initiated successfully: ScanID=%s", submission.ScanID)
+	c.JSON(http.StatusOK, ScanResponse{
+		ScanID:     submission.ScanID,
+		Status:     "started",
+		Message:    "Static scan initiated successfully",
+		Archive:    submission.Archive,
+		Superseded: submission.Superseded,
+	})
+}
+
+// GetScanStatus gets scan status by commit_id
+func (sh *ScanHandler) GetScanStatus(c *gin.Context) {
+	commitID := c.Query("commit_id")
+	projectName := c.Query("project_name") // Optional: to narrow down search
+
+	// Validate required parameters
+	if commitID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:     "Missing required parameter",
+			Details:   "commit_id is required",
+			Timestamp: time.Now().Format(time.RFC3339),
+			Path:      c.Request.URL.Path,
+		})
+		return
+	}
+
+	status, err := sh.service.GetScanStatusByCommitID(commitID, projectName)
+	if err != nil {
+		// Determine appropriate HTTP status code based on error type
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "scan not found" || err.Error() == "no scans found for commit_id" {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, ErrorResponse{
+			Error:     "Failed to get scan status",
+			Details:   err.Error(),
+			Timestamp: time.Now().Format(time.RFC3339),
+			Path:      c.Request.URL.Path,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// StreamScanStatus holds a Server-Sent Events connection open and pushes a "status" event every time a
+// scan of the commit changes status, closing once all of them are terminal. An "error" event is sent
+// when the status can no longer be followed.
+func (sh *ScanHandler) StreamScanStatus(c *gin.Context) {
+	commitID := c.Query("commit_id")
+	projectName := c.Query("project_name") // Optional: to narrow down search
+
+	if commitID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:     "Missing required parameter",
+			Details:   "commit_id is required",
+			Timestamp: time.Now().Format(time.RFC3339),
+			Path:      c.Request.URL.Path,
+		})
+		return
+	}
+
+	updates, snapshot, unsubscribe := sh.service.SubscribeStatus(commitID, projectName)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Keep reverse proxies such as nginx from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// send writes an update and reports whether the stream may go on; an error ends it, and so does
+	// finished once every scan of the commit is terminal and none of its jobs is still to start one
+	progress := newCommitProgress()
+	finished := false
+	checkFinished := func() {
+		var err error
+		if finished, err = sh.service.commitFinished(progress, commitID, projectName); err != nil {
+			sh.logger.Warnf("⚠️ %v", err)
+		}
+	}
+	send := func(update StatusUpdate) bool {
+		if projectName != "" && update.ProjectName != "" && update.ProjectName != projectName {
+			return true
+		}
+		if update.Error != "" {
+			c.SSEvent("error", update)
+			c.Writer.Flush()
+			return false
+		}
+		c.SSEvent("status", update)
+		c.Writer.Flush()
+		progress.observe(update)
+		checkFinished()
+		return true
+	}
+
+	sh.logger.Debugf("📡 Streaming status for commit_id %s", commitID)
+
+	// Start from the last known state, if anything is known about the commit yet
+	open := true
+	for _, update := range snapshot {
+		if open = send(update); !open {
+			break
+		}
+	}
+	if len(snapshot) == 0 {
+		c.Writer.Flush()
+	}
+
+	heartbeat := time.NewTicker(statusHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for open && !finished {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case update, ok := <-updates:
+			if !ok {
+				// Dropped for falling behind; the client reconnects and starts from the current state
+				return
+			}
+			open = send(update)
+		case <-heartbeat.C:
+			// SSE comment line, keeps idle connections from being closed by proxies
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			c.Writer.Flush()
+			// A queued job that fails to start publishes no update
+			checkFinished()
+		}
+	}
+}
+
+// WaitForScanResults blocks until the scans of a commit are terminal or the timeout elapses and returns
+// their results with an exit recommendation. A timed-out wait answers 202 with the results so far.
+func (sh *ScanHandler) WaitForScanResults(c *gin.Context) {
+	commitID := c.Query("commit_id")
+	projectName := c.Query("project_name") // Optional: to narrow down search
+
+	if commitID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:     "Missing required parameter",
+			Details:   "commit_id is required",
+			Timestamp: time.Now().Format(time.RFC3339),
+			Path:      c.Request.URL.Path,
+		})
+		return
+	}
+
+	timeout, err := parseWaitTimeout(c.Query("timeout"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:     "Invalid parameter",
+			Details:   err.Error(),
+			Timestamp: time.Now().Format(time.RFC3339),
+			Path:      c.Request.URL.Path,
+		})
+		return
+	}
+
+	response, err := sh.service.WaitForCommitResults(c.Request.Context(), commitID, projectName, timeout)
+	if err != nil {
+		if c.Request.Context().Err() != nil {
+			// The client went away, nobody is left to answer
+			return
+		}
+
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "no scans found") {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, ErrorResponse{
+			Error:     "Failed to wait for scan results",
+			Details:   err.Error(),
+			Timestamp: time.Now().Format(time.RFC3339),
+			Path:      c.Request.URL.Path,
+		})
+		return
+	}
+
+	statusCode := http.StatusOK
+	if response.TimedOut {
+		statusCode = http.StatusAccepted
+	}
+	c.JSON(statusCode, response)
+}
+
+// GetScanResults returns the results for a specific scan
+func (sh *ScanHandler) GetScanResults(c *gin.Context) {
+	commitID := c.Query("commit_id")
+	projectName := c.Query("project_name") // Optional: to narrow down search
+
+	// Validate required parameters
+	if commitID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:     "Missing required parameter",
+			Details:   "commit_id is required",
+			Timestamp: time.Now().Format(time.RFC3339),
+			Path:      c.Request.URL.Path,
+		})
+		return
+	}
+
+	opts, err := parseReportOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:     "Invalid report options",
+			Details:   err.Error(),
+			Timestamp: time.Now().Format(time.RFC3339),
+			Path:      c.Request.URL.Path,
+		})
+		return
+	}
+
+	results, err := sh.service.GetAllScanResultsByCommitID(commitID, projectName)
+	if err != nil {
+		// Determine appropriate HTTP status code based on error type
+		statusCode := http.StatusInternalServerError
+		//if contains(err.Error(), "scan not found") ||
+		//	contains(err.Error(), "no scans found") {
+		//	statusCode = http.StatusNotFound
+		//} else if contains(err.Error(), "not completed") {
+		//	statusCode = http.StatusPreconditionFailed // 412 - scan not ready
+		//}
+
+		c.JSON(statusCode, ErrorResponse{
+			Error:     "Failed to get scan results",
+			Details:   err.Error(),
+			Timestamp: time.Now().Format(time.RFC3339),
+			Path:      c.Request.URL.Path,
+		})
+		return
+	}
+
+	if opts.Format == FormatJSON {
+		c.JSON(http.StatusOK, results)
+		return
+	}
+
+	data, contentType, err := renderReport(opts, results)
+	if err != nil {
+		sh.logger.Errorf("❌ Failed to render %s report for commit_id %s: %v", opts.Format, commitID, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:     "Failed to render scan results",
+			Details:   err.Error(),
+			Timestamp: time.Now().Format(time.RFC3339),
+			Path:      c.Request.URL.Path,
+		})
+		return
+	}
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// CompareScans returns the findings that are new, fixed and unchanged relative to a baseline scan
+func (sh *ScanHandler) CompareScans(c *gin.Context) {
+	var req CompareScansRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:     "Invalid query parameters",
+			Details:   err.Error(),
+			Timestamp: time.Now().Format(time.RFC3339),
+			Path:      c.Request.URL.Path,
+		})
+		return
+	}
+
+	if req.ScanID == "" && req.CommitID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:     "Missing required parameter",
+			Details:   "scan_id or commit_id is required",
+			Timestamp: time.Now().Format(time.RFC3339),
+			Path:      c.Request.URL.Path,
+		})
+		return
+	}
+	if req.BaselineScanID == "" && req.BaselineBranch == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:     "Missing required parameter",
+			Details:   "baseline_scan_id or baseline_branch is required",
+			Timestamp: time.Now().Format(time.RFC3339),
+			Path:      c.Request.URL.Path,
+		})
+		return
+	}
+
+	response, err := sh.service.CompareScans(req)
+	if err != nil {
+		sh.logger.Errorf("❌ Failed to compare scans: %v", err)
+		statusCode := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, errNoCompletedScan), strings.Contains(err.Error(), "not found"):
+			statusCode = http.StatusNotFound
+		case strings.Contains(err.Error(), "not completed"):
+			statusCode = http.StatusPreconditionFailed // 412 - scan not ready
+		case strings.Contains(err.Error(), "different project"):
+			statusCode = http.StatusBadRequest
+		}
+
+		c.JSON(statusCode, ErrorResponse{
+			Error:     "Failed to compare scans",
+			Details:   err.Error(),
+			Timestamp: time.Now().Format(time.RFC3339),
+			Path:      c.Request.URL.Path,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ListScans lists scans with filtering options
+func (sh *ScanHandler) ListScans(c *gin.Context) {
+	var req ListScansRequest
+
+	// Parse query parameters
+	if projectName := c.Query("project_name"); projectName != "" {
+		req.ProjectName = projectName
+	}
+
+	if commitID := c.Query("commit_id"); commitID != "" {
+		req.CommitID = commitID
+	}
+
+	// Parse pagination parameters
+	if limit := c.Query("limit"); limit != "" {
+		if l, err := strconv.Atoi(limit); err == nil && l > 0 {
+			req.Limit = l
+		}
+	}
+	if req.Limit <= 0 {
+		req.Limit = 20 // Default limit
+	}
+
+	if offset := c.Query("offset"); offset != "" {
+		if o, err := strconv.Atoi(offset); o >= 0 && err == nil {
+			req.Offset = o
+		}
+	}
+
+	// Call service method to get filtered scans
+	response, err := sh.service.ListScansFiltered(req)
+	if err != nil {
+		sh.logger.Errorf("❌ Failed to list scans: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:     "Failed to list scans",
+			Details:   err.Error(),
+			Timestamp: time.Now().Format(time.RFC3339),
+			Path:      c.Request.URL.Path,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CancelScan cancels a running scan
+func (sh *ScanHandler) CancelScan(c *gin.Context) {
+	commitID := c.Query("commit_id")
+	projectName := c.Query("project_name") // Optional query parameter
+
+	// Route would be: /api/scans/:commit_id/cancel?project_name=optional
+
+	err := sh.service.CancelScan(commitID, projectName)
+	if err != nil {
+		if err.Error() == "scan not found" {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error:     err.Error(),
+				Timestamp: time.Now().Format(time.RFC3339),
+				Path:      c.Request.URL.Path,
+			})
+		} else {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:     err.Error(),
+				Timestamp: time.Now().Format(time.RFC3339),
+				Path:      c.Request.URL.Path,
+			})
+		}
+		return
+	}
+
+	response := gin.H{
+		"commit_id": commitID,
+		"status":    "cancelled",
+		"message":   "SUCCESS",
+	}
+
+	// Only include project_name in response if it was provided
+	if projectName != "" {
+		response["project_name"] = projectName
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (sh *ScanHandler) parseScanConfigurations(configStr string, scanTypes []string) ([]cx1.ScanConfiguration, error) {
+	var configurations []cx1.ScanConfiguration
+
+	// If no config provided, generate default configurations
+	if configStr == "" {
+		sh.logger.Infof("No scan configuration provided, generating defaults")
+		return sh.generateDefaultConfigurations(scanTypes), nil
+	}
+
+	// Parse JSON configuration
+	if err := json.Unmarshal([]byte(configStr), &configurations); err != nil {
+		return nil, fmt.Errorf("invalid JSON format in config: %v", err)
+	}
+
+	// Validate configurations
+	if err := sh.validateScanConfigurations(configurations, scanTypes); err != nil {
+		return nil, err
+	}
+
+	sh.logger.Infof("Using provided scan configurations: %+v", configurations)
+	return configurations, nil
+}
+
+// validateScanConfigurations validates that provided configurations match requested scan types
+func (sh *ScanHandler) validateScanConfigurations(configurations []cx1.ScanConfiguration, scanTypes []string) error {
+	// Create a map of valid scan types
+	validScanTypes := map[string]bool{
+		"sast":         true,
+		"sca":          true,
+		"secrets":      true,
+		"kics":         true,
+		"containersec": true,
+		"apisec":       true,
+	}
+
+	// Create a map of requested scan types
+	requestedTypes := make(map[string]bool)
+	for _, scanType := range scanTypes {
+		requestedTypes[strings.ToLower(scanType)] = true
+	}
+
+	// Validate each configuration
+	configTypes := make(map[string]bool)
+	for i, config := range configurations {
+		// Check if scan type is valid
+		if !validScanTypes[strings.ToLower(config.ScanType)] {
+			return fmt.Errorf("invalid scan type in configuration[%d]: %s", i, config.ScanType)
+		}
+
+		// Check if scan type was requested
+		if !requestedTypes[strings.ToLower(config.ScanType)] {
+			return fmt.Errorf("configuration provided for unrequested scan type: %s", config.ScanType)
+		}
+
+		// Check for duplicate configurations
+		lowerType := strings.ToLower(config.ScanType)
+		if configTypes[lowerType] {
+			return fmt.Errorf("duplicate configuration for scan type: %s", config.ScanType)
+		}
+		configTypes[lowerType] = true
+
+		// Validate configuration values based on scan type
+		if err := sh.validateConfigurationValues(config); err != nil {
+			return fmt.Errorf("invalid configuration for %s: %v", config.ScanType, err)
+		}
+	}
+
+	return nil
+}
+
+// validateConfigurationValues validates configuration values for specific scan types
+func (sh *ScanHandler) validateConfigurationValues(config cx1.ScanConfiguration) error {
+	switch strings.ToLower(config.ScanType) {
+	case "sast":
+		// Validate SAST-specific configurations
+		if preset, exists := config.Values["presetName"]; exists && preset == "" {
+			return fmt.Errorf("presetName cannot be empty")
+		}
+		if incremental, exists := config.Values["incremental"]; exists {
+			if incremental != "true" && incremental != "false" {
+				return fmt.Errorf("incremental must be 'true' or 'false', got: %s", incremental)
+			}
+		}
+		if verbose, exists := config.Values["engineVerbose"]; exists {
+			if verbose != "true" && verbose != "false" {
+				return fmt.Errorf("engineVerbose must be 'true' or 'false', got: %s", verbose)
+			}
+		}
+
+	case "sca":
+		// Validate SCA-specific configurations
+		if exploitable, exists := config.Values["exploitablePath"]; exists {
+			if exploitable != "true" && exploitable != "false" {
+				return fmt.Errorf("exploitablePath must be 'true' or 'false', got: %s", exploitable)
+			}
+		}
+
+	// Add validation for other scan types as needed
+	case "secrets", "kics", "containersec", "apisec":
+		// These typically don't require specific validation for now
+		break
+	}
+
+	return nil
+}
+
+// generateDefaultConfigurations creates default configurations for scan types
+func (sh *ScanHandler) generateDefaultConfigurations(scanTypes []string) []cx1.ScanConfiguration {
+	var configurations []cx1.ScanConfiguration
+
+	for _, scanType := range scanTypes {
+		switch strings.ToLower(scanType) {
+		case "sast":
+			configurations = append(configurations, cx1.ScanConfiguration{
+				ScanType: "sast",
+				Values: map[string]string{
+					"incremental":   "false",
+					"presetName":    "Checkmarx Default",
+					"engineVerbose": "false",
+				},
+			})
+
+		case "sca":
+			configurations = append(configurations, cx1.ScanConfiguration{
+				ScanType: "sca",
+				Values: map[string]string{
+					"lastSastScanTime": "",
+					"exploitablePath":  "false",
+				},
+			})
+
+		case "secrets":
+			configurations = append(configurations, cx1.ScanConfiguration{
+				ScanType: "secrets",
+				Values:   map[string]string{},
+			})
+
+		case "kics":
+			configurations = append(configurations, cx1.ScanConfiguration{
+				ScanType: "kics",
+				Values:   map[string]string{},
+			})
+
+		case "containersec":
+			configurations = append(configurations, cx1.ScanConfiguration{
+				ScanType: "container",
+				Values:   map[string]string{},
+			})
+
+		case "apisec":
+			configurations = append(configurations, cx1.ScanConfiguration{
+				ScanType: "apisec",
+				Values:   map[string]string{},
+			})
+		}
+	}
+
+	return configurations
+}
+
+// Helper method to parse and validate scan types (unchanged)
+func (sh *ScanHandler) parseScanTypes(scanTypesStr string) ([]string, error) {
+	var scanTypes []string
+
+	if scanTypesStr != "" {
+		scanTypes = strings.Split(strings.ReplaceAll(scanTypesStr, " ", ""), ",")
+	} else {
+		scanTypes = []string{"sast", "sca"} // default
+	}
+
+	// Validate scan types
+	validScanTypes := map[string]bool{
+		"sast":         true,
+		"sca":          true,
+		"microengines": true,
+		"kics":         true,
+		"containers":   true,
+		"apisec":       true,
+	}
+
+	for _, scanType := range scanTypes {
+		if !validScanTypes[strings.ToLower(scanType)] {
+			return nil, fmt.Errorf("invalid scan type: %s. Valid types: sast,sca,secrets,kics,containersec,apisec", scanType)
+		}
+	}
+
+	return scanTypes, nil
+}
+
+// Helper method to parse tags from string format (unchanged)
+func (sh *ScanHandler) parseTags(tagsStr string) (map[string]string, error) {
+	tags := make(map[string]string)
+
+	if tagsStr == "" {
+		return tags, nil
+	}
+
+	tagPairs := strings.Split(tagsStr, ",")
+	for _, pair := range tagPairs {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, ":", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid tag format: %s. Expected format: key:value", pair)
+		}
+
+		key := strings.TrimSpace(kv[0])
+		value := strings.TrimSpace(kv[1])
+
+		if key == "" {
+			return nil, fmt.Errorf("empty tag key in: %s", pair)
+		}
+
+		tags[key] = value
+	}
+
+	return tags, nil
+}
+
+// Helper method to validate zip file (unchanged)
+func (sh *ScanHandler) isValidZipFile(filename string) bool {
+	return strings.HasSuffix(strings.ToLower(filename), ".zip")
+}
+
+func (sh *ScanHandler) getPreset(c *gin.Context) {
+	response := gin.H{
+		"presets": []string{"K-Web", "K-API", "K-Mobile"},
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// func (sh *ScanHandler) getTempConfig(c *gin.Context) {
+// 	projectID := c.Query("project_id")
+
+// 	data, err := sh.service.TempGetConfig(projectID)
+// 	if err != nil {
+// 		// Determine appropriate HTTP status code based on error type
+// 		statusCode := http.StatusInternalServerError
+// 		if err.Error() == "scan not found" || err.Error() == "no scans found for commit_id" {
+// 			statusCode = http.StatusNotFound
+// 		}
+
+// 		c.JSON(statusCode, ErrorResponse{
+// 			Error:     "Failed to get scan status",
+// 			Details:   err.Error(),
+// 			Timestamp: time.Now().Format(time.RFC3339),
+// 			Path:      c.Request.URL.Path,
+// 		})
+// 		return
+// 	}
+
+// 	c.JSON(http.StatusOK, data)
+// }
@@ -0,0 +1,250 @@
+// api/v1/scans/idempotency.go
+package scans
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// idempotencyKeyTTL is how long a key keeps returning the scan it started
+	idempotencyKeyTTL = 24 * time.Hour
+	// idempotencyReservationTimeout frees a key whose request never finished, e.g. because the wrapper restarted
+	idempotencyReservationTimeout = 30 * time.Minute
+	maxIdempotencyKeyLength       = 255
+)
+
+var (
+	// ErrIdempotencyKeyReused is returned when a key is sent again with a different request
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+	// ErrIdempotencyKeyInProgress is returned when the first request with a key is still being processed
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+)
+
+var idempotencyKeysBucket = []byte("scan_idempotency_keys")
+
+// IdempotencyRecord maps an Idempotency-Key to the job its request created. JobID is empty while the
+// first request is still being processed.
+type IdempotencyRecord struct {
+	Key         string    `json:"key"`
+	RequestHash string    `json:"request_hash"`
+	JobID       string    `json:"job_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (r *IdempotencyRecord) expired(now time.Time) bool {
+	if r.JobID == "" {
+		return now.Sub(r.CreatedAt) > idempotencyReservationTimeout
+	}
+	return now.Sub(r.CreatedAt) > idempotencyKeyTTL
+}
+
+// ReserveIdempotencyKey claims key for a request. If the key is already held, the existing record is
+// returned and nothing is stored.
+func (js *JobStore) ReserveIdempotencyKey(key, requestHash string) (*IdempotencyRecord, error) {
+	var existing *IdempotencyRecord
+	err := js.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(idempotencyKeysBucket)
+		now := time.Now().UTC()
+
+		if data := bucket.Get([]byte(key)); data != nil {
+			record := &IdempotencyRecord{}
+			if err := json.Unmarshal(data, record); err != nil {
+				return err
+			}
+			if !record.expired(now) {
+				existing = record
+				return nil
+			}
+		}
+
+		data, err := json.Marshal(IdempotencyRecord{Key: key, RequestHash: requestHash, CreatedAt: now})
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key), data)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	return existing, nil
+}
+
+// CompleteIdempotencyKey records the job a reserved key's request resolved to
+func (js *JobStore) CompleteIdempotencyKey(key, jobID string) error {
+	return js.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(idempotencyKeysBucket)
+		data := bucket.Get([]byte(key))
+		if data == nil {
+			return fmt.Errorf("idempotency key not found: %s", key)
+		}
+		record := &IdempotencyRecord{}
+		if err := json.Unmarshal(data, record); err != nil {
+			return err
+		}
+		record.JobID = jobID
+		record.CreatedAt = time.Now().UTC()
+
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key), data)
+	})
+}
+
+// ReleaseIdempotencyKey frees a reserved key whose request failed, so the client can retry with it
+func (js *JobStore) ReleaseIdempotencyKey(key string) error {
+	return js.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(idempotencyKeysBucket).Delete([]byte(key))
+	})
+}
+
+// PurgeExpiredIdempotencyKeys deletes the records that expired before now and returns how many it removed
+func (js *JobStore) PurgeExpiredIdempotencyKeys(now time.Time) (int, error) {
+	purged := 0
+	err := js.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(idempotencyKeysBucket)
+
+		// Deleting while iterating skips keys in bolt, so the keys are collected first
+		var expired [][]byte
+		err := bucket.ForEach(func(key, data []byte) error {
+			var record IdempotencyRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				return err
+			}
+			if record.expired(now) {
+				expired = append(expired, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		purged = len(expired)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+
+	return purged, nil
+}
+
+// scanFingerprint identifies scans that are equivalent for duplicate detection: same project, commit,
+// mode and set of scan types
+func scanFingerprint(projectName, commitID string, isFastScan bool, scanTypes []string) string {
+	types := make([]string, 0, len(scanTypes))
+	for _, scanType := range scanTypes {
+		types = append(types, strings.ToLower(strings.TrimSpace(scanType)))
+	}
+	sort.Strings(types)
+	return fmt.Sprintf("%s|%s|%t|%s", projectName, commitID, isFastScan, strings.Join(types, ","))
+}
+
+// requestHash identifies the request an idempotency key was first sent with
+func requestHash(req StaticScanRequestWithFile) string {
+	fingerprint := scanFingerprint(req.ProjectName, req.CommitID, req.IsFastScan, req.ScanTypes)
+	parts := []string{fingerprint, req.AppName, req.Branch, req.Preset, req.TargetBranch, strings.Join(req.Excludes, ",")}
+	if req.Source != nil {
+		parts = append(parts, req.Source.RepoURL)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// validateIdempotencyKey checks the Idempotency-Key header value
+func validateIdempotencyKey(key string) error {
+	if len(key) > maxIdempotencyKeyLength {
+		return fmt.Errorf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength)
+	}
+	for _, r := range key {
+		if r < 0x21 || r > 0x7e {
+			return fmt.Errorf("Idempotency-Key must contain only visible ASCII characters")
+		}
+	}
+	return nil
+}
+
+// replayIdempotencyKey returns the submission a key already resolved to
+func (ss *ScanService) replayIdempotencyKey(record *IdempotencyRecord, hash string) (*ScanSubmission, error) {
+	if record.RequestHash != hash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if record.JobID == "" {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	job, err := ss.jobs.Get(record.JobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scan job for idempotency key: %v", err)
+	}
+
+	ss.logger.Infof("🔁 Idempotency-Key %s replayed, returning scan job %s", record.Key, job.ID)
+	submission := ss.submissionFor(job)
+	submission.Replayed = true
+	return submission, nil
+}
+
+// findActiveDuplicate returns the job of a queued or running scan equivalent to the request, if any
+func (ss *ScanService) findActiveDuplicate(req StaticScanRequestWithFile) (*ScanJob, error) {
+	jobs, err := ss.jobs.List(JobStateQueued, JobStatePolling)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint := scanFingerprint(req.ProjectName, req.CommitID, req.IsFastScan, req.ScanTypes)
+	var latest *ScanJob
+	for i := range jobs {
+		job := &jobs[i]
+		if isTerminalStatus(job.LastStatus) {
+			continue
+		}
+		if scanFingerprint(job.ProjectName, job.CommitID, job.IsFastScan, job.ScanTypes) != fingerprint {
+			continue
+		}
+		if latest == nil || job.CreatedAt.After(latest.CreatedAt) {
+			latest = job
+		}
+	}
+
+	return latest, nil
+}
+
+// beginSubmission serializes submissions of equivalent scans so that concurrent retries see each
+// other's jobs. The returned function ends the submission.
+func (ss *ScanService) beginSubmission(fingerprint string) func() {
+	for {
+		ss.submitMu.Lock()
+		inFlight, ok := ss.submitting[fingerprint]
+		if !ok {
+			done := make(chan struct{})
+			ss.submitting[fingerprint] = done
+			ss.submitMu.Unlock()
+
+			return func() {
+				ss.submitMu.Lock()
+				delete(ss.submitting, fingerprint)
+				ss.submitMu.Unlock()
+				close(done)
+			}
+		}
+		ss.submitMu.Unlock()
+		<-inFlight
+	}
+}
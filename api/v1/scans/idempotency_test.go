@@ -0,0 +1,307 @@
+package scans
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/madhatkul/CxWrapper-v2/api/gitsource"
+	bolt "go.etcd.io/bbolt"
+)
+
+// openTestJobStore opens a job store in the bolt file at path, closed when the test ends
+func openTestJobStore(t *testing.T, path string) (*JobStore, *bolt.DB) {
+	t.Helper()
+
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store, err := NewJobStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, db
+}
+
+// putIdempotencyRecord stores a record as is, e.g. to backdate it
+func putIdempotencyRecord(t *testing.T, db *bolt.DB, record IdempotencyRecord) {
+	t.Helper()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(idempotencyKeysBucket).Put([]byte(record.Key), data)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReserveIdempotencyKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	store, db := openTestJobStore(t, path)
+
+	existing, err := store.ReserveIdempotencyKey("key-1", "hash-1")
+	if err != nil || existing != nil {
+		t.Fatalf("first ReserveIdempotencyKey() = %+v, %v; want the key reserved", existing, err)
+	}
+
+	existing, err = store.ReserveIdempotencyKey("key-1", "hash-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existing == nil || existing.RequestHash != "hash-1" || existing.JobID != "" {
+		t.Fatalf("second ReserveIdempotencyKey() = %+v, want the pending reservation of hash-1", existing)
+	}
+
+	if err := store.CompleteIdempotencyKey("key-1", "job-1"); err != nil {
+		t.Fatalf("CompleteIdempotencyKey() = %v", err)
+	}
+	if err := store.CompleteIdempotencyKey("missing", "job-1"); err == nil {
+		t.Fatal("CompleteIdempotencyKey() of an unknown key = nil, want an error")
+	}
+
+	// The completed key survives reopening the database
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	store, db = openTestJobStore(t, path)
+
+	existing, err = store.ReserveIdempotencyKey("key-1", "hash-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existing == nil || existing.JobID != "job-1" {
+		t.Fatalf("ReserveIdempotencyKey() after reopening = %+v, want the record of job-1", existing)
+	}
+
+	// A released key can be reserved again
+	if err := store.ReleaseIdempotencyKey("key-1"); err != nil {
+		t.Fatalf("ReleaseIdempotencyKey() = %v", err)
+	}
+	existing, err = store.ReserveIdempotencyKey("key-1", "hash-2")
+	if err != nil || existing != nil {
+		t.Fatalf("ReserveIdempotencyKey() after release = %+v, %v; want the key reserved", existing, err)
+	}
+
+	// Expired records are replaced
+	now := time.Now().UTC()
+	putIdempotencyRecord(t, db, IdempotencyRecord{Key: "stale", RequestHash: "old", CreatedAt: now.Add(-idempotencyReservationTimeout - time.Minute)})
+	putIdempotencyRecord(t, db, IdempotencyRecord{Key: "done", RequestHash: "old", JobID: "job-2", CreatedAt: now.Add(-idempotencyKeyTTL - time.Minute)})
+	for _, key := range []string{"stale", "done"} {
+		existing, err = store.ReserveIdempotencyKey(key, "new")
+		if err != nil || existing != nil {
+			t.Fatalf("ReserveIdempotencyKey(%s) = %+v, %v; want the expired record replaced", key, existing, err)
+		}
+	}
+}
+
+func TestIdempotencyRecordExpired(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		record IdempotencyRecord
+		want   bool
+	}{
+		{name: "fresh reservation", record: IdempotencyRecord{CreatedAt: now.Add(-time.Minute)}},
+		{name: "abandoned reservation", record: IdempotencyRecord{CreatedAt: now.Add(-idempotencyReservationTimeout - time.Second)}, want: true},
+		{name: "completed within the TTL", record: IdempotencyRecord{JobID: "job", CreatedAt: now.Add(-idempotencyReservationTimeout - time.Second)}},
+		{name: "completed past the TTL", record: IdempotencyRecord{JobID: "job", CreatedAt: now.Add(-idempotencyKeyTTL - time.Second)}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.record.expired(now); got != tt.want {
+				t.Fatalf("expired() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPurgeExpiredIdempotencyKeys(t *testing.T) {
+	store, db := openTestJobStore(t, filepath.Join(t.TempDir(), "jobs.db"))
+	now := time.Now().UTC()
+
+	records := []struct {
+		record     IdempotencyRecord
+		wantPurged bool
+	}{
+		{record: IdempotencyRecord{Key: "pending", CreatedAt: now.Add(-time.Minute)}},
+		{record: IdempotencyRecord{Key: "abandoned", CreatedAt: now.Add(-idempotencyReservationTimeout - time.Minute)}, wantPurged: true},
+		{record: IdempotencyRecord{Key: "recent", JobID: "job-1", CreatedAt: now.Add(-time.Hour)}},
+		{record: IdempotencyRecord{Key: "old", JobID: "job-2", CreatedAt: now.Add(-idempotencyKeyTTL - time.Minute)}, wantPurged: true},
+	}
+	for _, r := range records {
+		putIdempotencyRecord(t, db, r.record)
+	}
+
+	purged, err := store.PurgeExpiredIdempotencyKeys(now)
+	if err != nil || purged != 2 {
+		t.Fatalf("PurgeExpiredIdempotencyKeys() = %d, %v; want 2", purged, err)
+	}
+
+	for _, r := range records {
+		var stored bool
+		db.View(func(tx *bolt.Tx) error {
+			stored = tx.Bucket(idempotencyKeysBucket).Get([]byte(r.record.Key)) != nil
+			return nil
+		})
+		if stored == r.wantPurged {
+			t.Errorf("record %s stored = %t, want purged %t", r.record.Key, stored, r.wantPurged)
+		}
+	}
+}
+
+func TestIdempotentSubmission(t *testing.T) {
+	ss, fake := newTestService(t)
+
+	keyed := func(key string) StaticScanRequestWithFile {
+		req := testScanRequest(t, "commit-1")
+		req.IdempotencyKey = key
+		return req
+	}
+
+	first, err := ss.StartStaticScanWithFile(keyed("key-1"))
+	if err != nil {
+		t.Fatalf("StartStaticScanWithFile() = %v", err)
+	}
+	if first.Replayed {
+		t.Fatal("first submission is marked replayed")
+	}
+
+	// A retry with the same key returns the same job, even with force
+	retry := keyed("key-1")
+	retry.Force = true
+	replayed, err := ss.StartStaticScanWithFile(retry)
+	if err != nil {
+		t.Fatalf("replay = %v", err)
+	}
+	if !replayed.Replayed || replayed.JobID != first.JobID || replayed.ScanID != first.ScanID {
+		t.Fatalf("replay = %+v, want job %s replayed", replayed, first.JobID)
+	}
+
+	// The same key with a different request is rejected
+	other := keyed("key-1")
+	other.Branch = "release"
+	if _, err := ss.StartStaticScanWithFile(other); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Fatalf("reused key = %v, want ErrIdempotencyKeyReused", err)
+	}
+
+	// A key whose first request is still being processed is not replayed yet
+	if _, err := ss.jobs.ReserveIdempotencyKey("key-2", requestHash(keyed("key-2"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ss.StartStaticScanWithFile(keyed("key-2")); !errors.Is(err, ErrIdempotencyKeyInProgress) {
+		t.Fatalf("key in progress = %v, want ErrIdempotencyKeyInProgress", err)
+	}
+
+	// A failed request frees its key for the retry
+	fake.FailNext("ScanProjectZipByID", errors.New("injected failure"))
+	failing := keyed("key-3")
+	failing.Force = true
+	if _, err := ss.StartStaticScanWithFile(failing); err == nil {
+		t.Fatal("StartStaticScanWithFile() = nil, want the injected failure")
+	}
+	failing = keyed("key-3")
+	failing.Force = true
+	recovered, err := ss.StartStaticScanWithFile(failing)
+	if err != nil {
+		t.Fatalf("retry after failure = %v", err)
+	}
+	if recovered.Replayed || recovered.ScanID == "" || recovered.JobID == first.JobID {
+		t.Fatalf("retry after failure = %+v, want a new scan", recovered)
+	}
+}
+
+func TestDuplicateSubmission(t *testing.T) {
+	ss, _ := newTestService(t)
+
+	first, err := ss.StartStaticScanWithFile(testScanRequest(t, "commit-1"))
+	if err != nil {
+		t.Fatalf("StartStaticScanWithFile() = %v", err)
+	}
+
+	// The scan types are compared as a set
+	same := testScanRequest(t, "commit-1")
+	same.ScanTypes = []string{"SCA", "sast"}
+	duplicate, err := ss.StartStaticScanWithFile(same)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !duplicate.Duplicate || duplicate.JobID != first.JobID {
+		t.Fatalf("duplicate = %+v, want job %s", duplicate, first.JobID)
+	}
+
+	fast := testScanRequest(t, "commit-1")
+	fast.IsFastScan = true
+	forced := testScanRequest(t, "commit-1")
+	forced.Force = true
+	for name, req := range map[string]StaticScanRequestWithFile{"fast scan": fast, "forced": forced} {
+		submission, err := ss.StartStaticScanWithFile(req)
+		if err != nil {
+			t.Fatalf("%s = %v", name, err)
+		}
+		if submission.Duplicate || submission.JobID == first.JobID {
+			t.Fatalf("%s = %+v, want a new scan", name, submission)
+		}
+	}
+}
+
+func TestRequestHash(t *testing.T) {
+	base := StaticScanRequestWithFile{AppName: "payments", ProjectName: "payments-api", Branch: "main", CommitID: "c1", ScanTypes: []string{"sast", "sca"}, Preset: "ASA Premium"}
+
+	reordered := base
+	reordered.ScanTypes = []string{"SCA", " sast"}
+	if requestHash(reordered) != requestHash(base) {
+		t.Fatal("requestHash() depends on the order and case of the scan types")
+	}
+
+	changes := map[string]func(req *StaticScanRequestWithFile){
+		"app":           func(req *StaticScanRequestWithFile) { req.AppName = "billing" },
+		"branch":        func(req *StaticScanRequestWithFile) { req.Branch = "release" },
+		"commit":        func(req *StaticScanRequestWithFile) { req.CommitID = "c2" },
+		"fast scan":     func(req *StaticScanRequestWithFile) { req.IsFastScan = true },
+		"preset":        func(req *StaticScanRequestWithFile) { req.Preset = "OWASP TOP 10" },
+		"target branch": func(req *StaticScanRequestWithFile) { req.TargetBranch = "main" },
+		"excludes":      func(req *StaticScanRequestWithFile) { req.Excludes = []string{"docs/**"} },
+		"repository": func(req *StaticScanRequestWithFile) {
+			req.Source = &gitsource.Source{RepoURL: "https://git.example.com/payments.git", Commit: "c1"}
+		},
+	}
+	for name, change := range changes {
+		req := base
+		change(&req)
+		if requestHash(req) == requestHash(base) {
+			t.Errorf("requestHash() ignores the %s", name)
+		}
+	}
+}
+
+func TestValidateIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{key: ""},
+		{key: "3f2b6c1e-8d4a-4c7b-9e0f-1a2b3c4d5e6f"},
+		{key: strings.Repeat("k", maxIdempotencyKeyLength)},
+		{key: strings.Repeat("k", maxIdempotencyKeyLength+1), wantErr: true},
+		{key: "with space", wantErr: true},
+		{key: "tab\tkey", wantErr: true},
+		{key: "ключ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := validateIdempotencyKey(tt.key); (err != nil) != tt.wantErr {
+			t.Errorf("validateIdempotencyKey(%q) = %v, want error %t", tt.key, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+// api/v1/scans/jobs.go
+package scans
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	cx1 "github.com/madhatkul/CxWrapper-v2/Cx1ClientGo"
+	bolt "go.etcd.io/bbolt"
+)
+
+// JobState tracks how far the wrapper got with a triggered scan
+type JobState string
+
+const (
+	// JobStateQueued jobs wait in the wrapper's scheduler for a slot to trigger their scan
+	JobStateQueued           JobState = "queued"
+	JobStatePolling          JobState = "polling"
+	JobStateResultsFetched   JobState = "results_fetched"
+	JobStateWebhookQueued    JobState = "webhook_queued"
+	JobStateWebhookDelivered JobState = "webhook_delivered"
+	JobStateFailed           JobState = "failed"
+	// JobStateSuperseded jobs were canceled because a newer scan of the same branch was submitted
+	JobStateSuperseded JobState = "superseded"
+)
+
+const (
+	// defaultJobRetention is how long a job is kept once it reached a final state
+	defaultJobRetention = 7 * 24 * time.Hour
+	jobCleanupInterval  = 10 * time.Minute
+)
+
+var (
+	scanJobsBucket = []byte("scan_jobs")
+	// scanIDIndexBucket maps Cx1 scan IDs to the ID of the job tracking them
+	scanIDIndexBucket = []byte("scan_jobs_by_scan_id")
+)
+
+// ScanJob is the persisted record of a scan triggered through the wrapper
+type ScanJob struct {
+	ID          string   `json:"id"`
+	ScanID      string   `json:"scan_id"`
+	ProjectID   string   `json:"project_id"`
+	ProjectName string   `json:"project_name"`
+	AppName     string   `json:"app_name"`
+	Branch      string   `json:"branch"`
+	CommitID    string   `json:"commit_id"`
+	IsFastScan  bool     `json:"is_fast_scan"`
+	ScanTypes   []string `json:"scan_types"`
+	State       JobState `json:"state"`
+	Error       string   `json:"error,omitempty"`
+	// Last Cx1 status and per-engine statuses seen while polling, so transitions are not re-sent after a restart
+	LastStatus     string            `json:"last_status,omitempty"`
+	EngineStatuses map[string]string `json:"engine_statuses,omitempty"`
+	// SupersededBy is the ID of the job that replaced this one
+	SupersededBy string `json:"superseded_by,omitempty"`
+	// Dispatch holds what is needed to trigger the scan while the job is queued
+	Dispatch  *ScanDispatch `json:"dispatch,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// ScanDispatch is the scan a queued job triggers; the archive is uploaded before the job is queued
+type ScanDispatch struct {
+	UploadURL      string                  `json:"upload_url"`
+	Configurations []cx1.ScanConfiguration `json:"configurations"`
+	Tags           map[string]string       `json:"tags"`
+}
+
+// JobStore keeps scan jobs in a bolt database so polling can be resumed after a restart
+type JobStore struct {
+	db *bolt.DB
+}
+
+func NewJobStore(db *bolt.DB) (*JobStore, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{scanJobsBucket, idempotencyKeysBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		if tx.Bucket(scanIDIndexBucket) == nil {
+			return buildScanIDIndex(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize scan job store: %w", err)
+	}
+
+	return &JobStore{db: db}, nil
+}
+
+// buildScanIDIndex creates the scan ID index for the jobs stored before it existed
+func buildScanIDIndex(tx *bolt.Tx) error {
+	index, err := tx.CreateBucket(scanIDIndexBucket)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(scanJobsBucket).ForEach(func(id, data []byte) error {
+		var job ScanJob
+		if err := json.Unmarshal(data, &job); err != nil {
+			return err
+		}
+		if job.ScanID == "" {
+			return nil
+		}
+		return index.Put([]byte(job.ScanID), id)
+	})
+}
+
+// jobRetentionFromEnv reads SCAN_JOB_RETENTION (e.g. "72h"), falling back to the default. It is never
+// shorter than an Idempotency-Key lives, so a replayed request always finds its job.
+func jobRetentionFromEnv() time.Duration {
+	retention := defaultJobRetention
+	if v, err := time.ParseDuration(os.Getenv("SCAN_JOB_RETENTION")); err == nil && v > 0 {
+		retention = v
+	}
+	if retention < idempotencyKeyTTL {
+		retention = idempotencyKeyTTL
+	}
+	return retention
+}
+
+// isFinal reports whether the wrapper is done with the job, so it may be pruned
+func (job *ScanJob) isFinal() bool {
+	switch job.State {
+	case JobStateWebhookDelivered, JobStateFailed, JobStateSuperseded:
+		return true
+	}
+	return false
+}
+
+// Create assigns an ID to the job and persists it
+func (js *JobStore) Create(job *ScanJob) error {
+	if job.ID == "" {
+		job.ID = newJobID()
+	}
+	now := time.Now().UTC()
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	return js.db.Update(func(tx *bolt.Tx) error {
+		return putJob(tx, job)
+	})
+}
+
+// Get returns the job with the given ID
+func (js *JobStore) Get(id string) (*ScanJob, error) {
+	var job *ScanJob
+	err := js.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(scanJobsBucket).Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("scan job not found: %s", id)
+		}
+		job = &ScanJob{}
+		return json.Unmarshal(data, job)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// Update applies fn to the stored job inside a single transaction and returns the result
+func (js *JobStore) Update(id string, fn func(job *ScanJob)) (*ScanJob, error) {
+	var job *ScanJob
+	err := js.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(scanJobsBucket)
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("scan job not found: %s", id)
+		}
+		job = &ScanJob{}
+		if err := json.Unmarshal(data, job); err != nil {
+			return err
+		}
+		fn(job)
+		job.UpdatedAt = time.Now().UTC()
+		return putJob(tx, job)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// SetState moves a job to the given state, recording errMsg when it is not empty
+func (js *JobStore) SetState(id string, state JobState, errMsg string) (*ScanJob, error) {
+	return js.Update(id, func(job *ScanJob) {
+		job.State = state
+		job.Error = errMsg
+	})
+}
+
+// List returns all jobs matching one of the given states, or every job if no state is given
+func (js *JobStore) List(states ...JobState) ([]ScanJob, error) {
+	wanted := make(map[JobState]bool)
+	for _, state := range states {
+		wanted[state] = true
+	}
+
+	var jobs []ScanJob
+	err := js.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(scanJobsBucket).ForEach(func(_, data []byte) error {
+			var job ScanJob
+			if err := json.Unmarshal(data, &job); err != nil {
+				return err
+			}
+			if len(wanted) == 0 || wanted[job.State] {
+				jobs = append(jobs, job)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scan jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// ForCommit returns the jobs of a commit, optionally within a project
+func (js *JobStore) ForCommit(commitID, projectName string) ([]ScanJob, error) {
+	all, err := js.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []ScanJob
+	for _, job := range all {
+		if job.CommitID == commitID && (projectName == "" || job.ProjectName == projectName) {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// FindByScanID returns the job tracking the given Cx1 scan ID
+func (js *JobStore) FindByScanID(scanID string) (*ScanJob, error) {
+	var job *ScanJob
+	err := js.db.View(func(tx *bolt.Tx) error {
+		id := tx.Bucket(scanIDIndexBucket).Get([]byte(scanID))
+		if id == nil {
+			return nil
+		}
+		data := tx.Bucket(scanJobsBucket).Get(id)
+		if data == nil {
+			return nil
+		}
+		found := &ScanJob{}
+		if err := json.Unmarshal(data, found); err != nil {
+			return err
+		}
+		// The job may have moved on to another scan since, e.g. when a superseded scan was restored
+		if found.ScanID == scanID {
+			job = found
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, fmt.Errorf("no scan job found for scan ID: %s", scanID)
+	}
+
+	return job, nil
+}
+
+// PruneFinished deletes the jobs that reached a final state before cutoff and returns how many it removed
+func (js *JobStore) PruneFinished(cutoff time.Time) (int, error) {
+	pruned := 0
+	err := js.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(scanJobsBucket)
+		index := tx.Bucket(scanIDIndexBucket)
+
+		// Deleting while iterating skips keys in bolt, so the jobs are collected first
+		var finished []ScanJob
+		err := bucket.ForEach(func(_, data []byte) error {
+			var job ScanJob
+			if err := json.Unmarshal(data, &job); err != nil {
+				return err
+			}
+			if job.isFinal() && job.UpdatedAt.Before(cutoff) {
+				finished = append(finished, job)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, job := range finished {
+			if err := bucket.Delete([]byte(job.ID)); err != nil {
+				return err
+			}
+			if job.ScanID != "" && string(index.Get([]byte(job.ScanID))) == job.ID {
+				if err := index.Delete([]byte(job.ScanID)); err != nil {
+					return err
+				}
+			}
+		}
+		pruned = len(finished)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune scan jobs: %w", err)
+	}
+
+	return pruned, nil
+}
+
+// putJob stores the job and indexes it by its scan ID, if it has one yet
+func putJob(tx *bolt.Tx, job *ScanJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scan job: %w", err)
+	}
+	if err := tx.Bucket(scanJobsBucket).Put([]byte(job.ID), data); err != nil {
+		return err
+	}
+	if job.ScanID == "" {
+		return nil
+	}
+	return tx.Bucket(scanIDIndexBucket).Put([]byte(job.ScanID), []byte(job.ID))
+}
+
+func newJobID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
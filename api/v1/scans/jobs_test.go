@@ -0,0 +1,157 @@
+package scans
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestFindByScanID(t *testing.T) {
+	store, _ := openTestJobStore(t, filepath.Join(t.TempDir(), "jobs.db"))
+
+	queued := &ScanJob{CommitID: "commit-1", State: JobStateQueued}
+	if err := store.Create(queued); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Update(queued.ID, func(job *ScanJob) { job.ScanID = "scan-1" }); err != nil {
+		t.Fatal(err)
+	}
+	polling := &ScanJob{ScanID: "scan-2", CommitID: "commit-2", State: JobStatePolling}
+	if err := store.Create(polling); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		scanID string
+		want   string
+	}{
+		{scanID: "scan-1", want: queued.ID},
+		{scanID: "scan-2", want: polling.ID},
+		{scanID: "scan-3"},
+		{scanID: ""},
+	}
+	for _, tt := range tests {
+		job, err := store.FindByScanID(tt.scanID)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("FindByScanID(%q) = %s, want an error", tt.scanID, job.ID)
+			}
+			continue
+		}
+		if err != nil || job.ID != tt.want {
+			t.Errorf("FindByScanID(%q) = %v, %v; want %s", tt.scanID, job, err, tt.want)
+		}
+	}
+
+	// A job that moved on to another scan is no longer found by the old one
+	if _, err := store.Update(polling.ID, func(job *ScanJob) { job.ScanID = "scan-4" }); err != nil {
+		t.Fatal(err)
+	}
+	if job, err := store.FindByScanID("scan-2"); err == nil {
+		t.Fatalf("FindByScanID(scan-2) = %s after the job moved on", job.ID)
+	}
+	if job, err := store.FindByScanID("scan-4"); err != nil || job.ID != polling.ID {
+		t.Fatalf("FindByScanID(scan-4) = %v, %v; want %s", job, err, polling.ID)
+	}
+}
+
+func TestScanIDIndexIsBuiltForExistingJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+
+	// A database written before the index existed
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(ScanJob{ID: "job-1", ScanID: "scan-1", State: JobStatePolling})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket(scanJobsBucket)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte("job-1"), data)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, _ := openTestJobStore(t, path)
+	if job, err := store.FindByScanID("scan-1"); err != nil || job.ID != "job-1" {
+		t.Fatalf("FindByScanID(scan-1) = %v, %v; want job-1", job, err)
+	}
+}
+
+func TestPruneFinished(t *testing.T) {
+	store, db := openTestJobStore(t, filepath.Join(t.TempDir(), "jobs.db"))
+	now := time.Now().UTC()
+
+	tests := []struct {
+		state      JobState
+		age        time.Duration
+		wantPruned bool
+	}{
+		{state: JobStateWebhookDelivered, age: 8 * 24 * time.Hour, wantPruned: true},
+		{state: JobStateFailed, age: 8 * 24 * time.Hour, wantPruned: true},
+		{state: JobStateSuperseded, age: 8 * 24 * time.Hour, wantPruned: true},
+		{state: JobStateWebhookDelivered, age: time.Hour},
+		{state: JobStateQueued, age: 8 * 24 * time.Hour},
+		{state: JobStatePolling, age: 8 * 24 * time.Hour},
+		{state: JobStateResultsFetched, age: 8 * 24 * time.Hour},
+		{state: JobStateWebhookQueued, age: 8 * 24 * time.Hour},
+	}
+
+	// Jobs are stored as is so they can be backdated
+	jobs := make([]ScanJob, len(tests))
+	err := db.Update(func(tx *bolt.Tx) error {
+		for i, tt := range tests {
+			jobs[i] = ScanJob{ID: newJobID(), ScanID: newJobID(), State: tt.state, UpdatedAt: now.Add(-tt.age)}
+			if err := putJob(tx, &jobs[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pruned, err := store.PruneFinished(now.Add(-defaultJobRetention))
+	if err != nil || pruned != 3 {
+		t.Fatalf("PruneFinished() = %d, %v; want 3", pruned, err)
+	}
+
+	for i, tt := range tests {
+		_, getErr := store.Get(jobs[i].ID)
+		_, findErr := store.FindByScanID(jobs[i].ScanID)
+		if gone := getErr != nil && findErr != nil; gone != tt.wantPruned {
+			t.Errorf("%s job %s old: Get() = %v, FindByScanID() = %v; want pruned %t", tt.state, tt.age, getErr, findErr, tt.wantPruned)
+		}
+	}
+}
+
+func TestJobRetentionFromEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: defaultJobRetention},
+		{value: "72h", want: 72 * time.Hour},
+		{value: "1h", want: idempotencyKeyTTL},
+		{value: "a week", want: defaultJobRetention},
+	}
+	for _, tt := range tests {
+		t.Setenv("SCAN_JOB_RETENTION", tt.value)
+		if got := jobRetentionFromEnv(); got != tt.want {
+			t.Errorf("jobRetentionFromEnv() with %q = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// api/v1/scans/lifecycle.go
+package scans
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	cx1 "github.com/madhatkul/CxWrapper-v2/Cx1ClientGo"
+	"github.com/madhatkul/CxWrapper-v2/api/v1/webhooks"
+)
+
+const (
+	defaultPollInterval = 10 * time.Second
+	maxPollFailures     = 10
+)
+
+// statusEvents maps Cx1 scan statuses to the lifecycle event published when a scan enters them
+var statusEvents = map[string]string{
+	"Queued":    webhooks.EventScanQueued,
+	"Running":   webhooks.EventScanRunning,
+	"Completed": webhooks.EventScanCompleted,
+	"Partial":   webhooks.EventScanPartial,
+	"Failed":    webhooks.EventScanFailed,
+	"Canceled":  webhooks.EventScanCanceled,
+}
+
+func isTerminalStatus(status string) bool {
+	switch status {
+	case "Completed", "Partial", "Failed", "Canceled":
+		return true
+	}
+	return false
+}
+
+// pollIntervalFromEnv reads SCAN_POLL_INTERVAL (e.g. "15s"), falling back to the default
+func pollIntervalFromEnv() time.Duration {
+	if v, err := time.ParseDuration(os.Getenv("SCAN_POLL_INTERVAL")); err == nil && v > 0 {
+		return v
+	}
+	return defaultPollInterval
+}
+
+// pollUntilTerminal polls Cx1 until the scan reaches a terminal status, publishing an event for every
+// status and engine transition along the way
+func (ss *ScanService) pollUntilTerminal(job *ScanJob, scan *cx1.Scan) (cx1.Scan, error) {
+	current := *scan
+	failures := 0
+
+	for {
+		ss.recordTransitions(job, &current)
+		if isTerminalStatus(current.Status) {
+			return current, nil
+		}
+
+		time.Sleep(ss.pollInterval)
+
+		next, err := ss.cx1Client.GetScanByID(current.ScanID)
+		if err != nil {
+			failures++
+			ss.logger.Warnf("⚠️ Failed to poll scan ID %s (%d/%d): %v", current.ScanID, failures, maxPollFailures, err)
+			if failures >= maxPollFailures {
+				return current, fmt.Errorf("giving up polling scan %s after %d consecutive errors: %v", current.ScanID, failures, err)
+			}
+			continue
+		}
+
+		failures = 0
+		current = next
+	}
+}
+
+// recordTransitions publishes lifecycle events for changes since the last poll and persists what was seen
+func (ss *ScanService) recordTransitions(job *ScanJob, scan *cx1.Scan) {
+	previousStatus := job.LastStatus
+	changed := false
+
+	for _, engine := range scan.StatusDetails {
+		if job.EngineStatuses[engine.Name] == engine.Status {
+			continue
+		}
+
+		ss.logger.Infof("🔧 Scan ID %s engine %s: %s", scan.ScanID, engine.Name, engine.Status)
+		ss.publishLifecycle(job, scan, webhooks.EventScanEngineProgress, job.EngineStatuses[engine.Name], &engine)
+
+		if job.EngineStatuses == nil {
+			job.EngineStatuses = make(map[string]string)
+		}
+		job.EngineStatuses[engine.Name] = engine.Status
+		changed = true
+	}
+
+	if scan.Status != previousStatus {
+		ss.logger.Infof("🔁 Scan ID %s status: %s -> %s", scan.ScanID, previousStatus, scan.Status)
+
+		// Completion carries the full results and is sent once they have been fetched. A superseded scan's
+		// cancellation was already announced as scan.superseded.
+		eventType, ok := statusEvents[scan.Status]
+		if ok && eventType == webhooks.EventScanCanceled && ss.isSuperseded(job.ID) {
+			ok = false
+		}
+		if ok && eventType != webhooks.EventScanCompleted {
+			ss.publishLifecycle(job, scan, eventType, previousStatus, nil)
+		}
+
+		job.LastStatus = scan.Status
+		changed = true
+	}
+
+	if !changed {
+		return
+	}
+
+	ss.broker.Publish(newStatusUpdate(job.CommitID, job.ProjectName, scan))
+
+	engineStatuses := job.EngineStatuses
+	lastStatus := job.LastStatus
+	if _, err := ss.jobs.Update(job.ID, func(stored *ScanJob) {
+		stored.LastStatus = lastStatus
+		stored.EngineStatuses = engineStatuses
+	}); err != nil {
+		ss.logger.Errorf("❌ Failed to record status for scan job %s: %v", job.ID, err)
+	}
+}
+
+// publishLifecycle sends a lifecycle event to matching subscriptions. previousStatus is the scan status for
+// status transitions, or the engine's previous status when engine is set.
+func (ss *ScanService) publishLifecycle(job *ScanJob, scan *cx1.Scan, eventType, previousStatus string, engine *cx1.ScanStatusDetails) {
+	now := time.Now().UTC()
+
+	// v1: WebhookPayload envelope
+	v1 := WebhookPayload{
+		Event:       eventType,
+		ScanID:      scan.ScanID,
+		CommitID:    job.CommitID,
+		ProjectName: job.ProjectName,
+		Branch:      scan.Branch,
+		Status:      scan.Status,
+		Timestamp:   now,
+	}
+
+	// v2: ScanEventV2
+	v2 := newScanEventV2(job, scan, eventType, now)
+
+	if engine != nil {
+		v1.Details = map[string]interface{}{
+			"engine":          engine.Name,
+			"engine_status":   engine.Status,
+			"previous_status": previousStatus,
+			"engine_details":  engine.Details,
+		}
+		v2.Engine = &webhooks.EngineStatusV2{
+			Name:           engine.Name,
+			Status:         engine.Status,
+			PreviousStatus: previousStatus,
+			Details:        engine.Details,
+		}
+	} else {
+		v1.Details = map[string]interface{}{
+			"previous_status": previousStatus,
+			"engines":         scan.StatusDetails,
+		}
+		v2.PreviousStatus = previousStatus
+	}
+
+	ss.publishEvent(job, scan, eventType, now, v1, v2)
+}
+
+// newScanEventV2 fills the fields of a v2 payload shared by every event type
+func newScanEventV2(job *ScanJob, scan *cx1.Scan, eventType string, occurredAt time.Time) *webhooks.ScanEventV2 {
+	event := &webhooks.ScanEventV2{
+		SchemaVersion: "2",
+		Event:         eventType,
+		ScanID:        scan.ScanID,
+		CommitID:      job.CommitID,
+		AppName:       job.AppName,
+		ProjectID:     scan.ProjectID,
+		ProjectName:   job.ProjectName,
+		Branch:        scan.Branch,
+		Status:        scan.Status,
+		OccurredAt:    occurredAt,
+	}
+	for _, engine := range scan.StatusDetails {
+		event.Engines = append(event.Engines, webhooks.EngineStatusV2{
+			Name:    engine.Name,
+			Status:  engine.Status,
+			Details: engine.Details,
+		})
+	}
+	return event
+}
+
+// publishEvent marshals every payload version of an event and publishes it
+func (ss *ScanService) publishEvent(job *ScanJob, scan *cx1.Scan, eventType string, occurredAt time.Time, v1, v2 interface{}) (int, error) {
+	payloads := make(map[string][]byte)
+	for version, payload := range map[string]interface{}{webhooks.PayloadVersionV1: v1, webhooks.PayloadVersionV2: v2} {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			ss.logger.Errorf("❌ Failed to marshal %s %s payload for scan ID %s: %v", eventType, version, scan.ScanID, err)
+			return 0, fmt.Errorf("failed to marshal webhook payload: %w", err)
+		}
+		payloads[version] = data
+	}
+
+	deliveries, err := ss.webhookService.Publish(webhooks.Event{
+		Type:        eventType,
+		ScanID:      scan.ScanID,
+		CommitID:    job.CommitID,
+		AppName:     job.AppName,
+		ProjectName: job.ProjectName,
+		Branch:      scan.Branch,
+		Time:        occurredAt,
+		Payloads:    payloads,
+	})
+	if err != nil {
+		ss.logger.Errorf("❌ Failed to publish %s for scan ID %s: %v", eventType, scan.ScanID, err)
+	}
+
+	return len(deliveries), err
+}
@@ -0,0 +1,51 @@
+// api/v1/scans/reports/compare.go
+package reports
+
+// Diff splits the findings of a scan into those introduced since a baseline scan, those the baseline had
+// that are gone, and those present in both
+type Diff struct {
+	New       []Finding `json:"new"`
+	Fixed     []Finding `json:"fixed"`
+	Unchanged []Finding `json:"unchanged"`
+}
+
+// Compare matches findings by Key, so a finding keeps its identity when the code around it moves
+func Compare(current, baseline []Finding) Diff {
+	diff := Diff{
+		New:       []Finding{},
+		Fixed:     []Finding{},
+		Unchanged: []Finding{},
+	}
+
+	inBaseline := make(map[string]bool, len(baseline))
+	for _, finding := range baseline {
+		inBaseline[finding.Key()] = true
+	}
+
+	inCurrent := make(map[string]bool, len(current))
+	for _, finding := range current {
+		key := finding.Key()
+		if inCurrent[key] {
+			continue
+		}
+		inCurrent[key] = true
+
+		if inBaseline[key] {
+			diff.Unchanged = append(diff.Unchanged, finding)
+		} else {
+			diff.New = append(diff.New, finding)
+		}
+	}
+
+	fixed := make(map[string]bool)
+	for _, finding := range baseline {
+		key := finding.Key()
+		if inCurrent[key] || fixed[key] {
+			continue
+		}
+		fixed[key] = true
+		diff.Fixed = append(diff.Fixed, finding)
+	}
+
+	return diff
+}
@@ -0,0 +1,78 @@
+package reports
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/madhatkul/CxWrapper-v2/api/cxclient"
+)
+
+func TestCompare(t *testing.T) {
+	sqli := Finding{Engine: EngineSAST, SimilarityID: "s1", RuleID: "SQL_Injection", File: "a.java", Line: 10}
+	sqliMoved := Finding{Engine: EngineSAST, SimilarityID: "s1", RuleID: "SQL_Injection", File: "a.java", Line: 42}
+	xss := Finding{Engine: EngineSAST, SimilarityID: "s2", RuleID: "XSS", File: "b.java"}
+	log4j := Finding{Engine: EngineSCA, SimilarityID: "s1", RuleID: "CVE-2021-44228", Package: "log4j-core"}
+	secret := Finding{Engine: EngineSecrets, RuleID: "aws-access-token", File: "app.properties"}
+	secretElsewhere := Finding{Engine: EngineSecrets, RuleID: "aws-access-token", File: "other.properties"}
+
+	tests := []struct {
+		name      string
+		current   []Finding
+		baseline  []Finding
+		new       []Finding
+		fixed     []Finding
+		unchanged []Finding
+	}{
+		{name: "both empty"},
+		{name: "no baseline", current: []Finding{sqli, xss}, new: []Finding{sqli, xss}},
+		{name: "everything fixed", baseline: []Finding{sqli, xss}, fixed: []Finding{sqli, xss}},
+		{name: "identical", current: []Finding{sqli, xss}, baseline: []Finding{xss, sqli}, unchanged: []Finding{sqli, xss}},
+		{name: "new, fixed and unchanged", current: []Finding{sqli, log4j}, baseline: []Finding{sqli, xss}, new: []Finding{log4j}, fixed: []Finding{xss}, unchanged: []Finding{sqli}},
+		{name: "moved code keeps its identity", current: []Finding{sqliMoved}, baseline: []Finding{sqli}, unchanged: []Finding{sqliMoved}},
+		{name: "same similarity ID on another engine is a different finding", current: []Finding{log4j}, baseline: []Finding{sqli}, new: []Finding{log4j}, fixed: []Finding{sqli}},
+		{name: "without a similarity ID the location identifies the finding", current: []Finding{secretElsewhere}, baseline: []Finding{secret}, new: []Finding{secretElsewhere}, fixed: []Finding{secret}},
+		{name: "duplicates reported once", current: []Finding{sqli, sqliMoved, xss}, baseline: []Finding{xss, xss}, new: []Finding{sqli}, unchanged: []Finding{xss}},
+		{name: "duplicate baseline findings fixed once", baseline: []Finding{xss, xss}, fixed: []Finding{xss}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := Compare(tt.current, tt.baseline)
+			check := func(kind string, got, want []Finding) {
+				if want == nil {
+					want = []Finding{}
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s = %+v, want %+v", kind, got, want)
+				}
+			}
+			check("new", diff.New, tt.new)
+			check("fixed", diff.Fixed, tt.fixed)
+			check("unchanged", diff.Unchanged, tt.unchanged)
+		})
+	}
+}
+
+func TestCompareSyntheticScans(t *testing.T) {
+	// Repeated scans of a project report the same similarity IDs, so nothing changes between them
+	baseline := Flatten(cxclient.SyntheticResults("project-1", Engines))
+	current := Flatten(cxclient.SyntheticResults("project-1", Engines))
+
+	diff := Compare(current, baseline)
+	if len(diff.New) != 0 || len(diff.Fixed) != 0 || len(diff.Unchanged) != len(current) {
+		t.Fatalf("Compare() = %d new, %d fixed, %d unchanged; want all %d unchanged", len(diff.New), len(diff.Fixed), len(diff.Unchanged), len(current))
+	}
+
+	// Adding an engine makes its findings new; dropping one makes them fixed
+	sastOnly := Flatten(cxclient.SyntheticResults("project-1", []string{EngineSAST}))
+	withSCA := Flatten(cxclient.SyntheticResults("project-1", []string{EngineSAST, EngineSCA}))
+
+	diff = Compare(withSCA, sastOnly)
+	if len(diff.New) != 1 || diff.New[0].Engine != EngineSCA || len(diff.Unchanged) != len(sastOnly) {
+		t.Fatalf("Compare() after adding SCA = %+v", diff)
+	}
+	diff = Compare(sastOnly, withSCA)
+	if len(diff.Fixed) != 1 || diff.Fixed[0].Engine != EngineSCA || len(diff.New) != 0 {
+		t.Fatalf("Compare() after dropping SCA = %+v", diff)
+	}
+}
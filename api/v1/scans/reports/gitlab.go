@@ -0,0 +1,299 @@
+// api/v1/scans/reports/gitlab.go
+package reports
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GitLab security report types
+const (
+	GitLabSAST               = "sast"
+	GitLabDependencyScanning = "dependency_scanning"
+	GitLabSecretDetection    = "secret_detection"
+)
+
+const (
+	gitLabSchemaVersion = "15.0.7"
+	gitLabTimeLayout    = "2006-01-02T15:04:05"
+	gitLabVendorName    = "Checkmarx"
+)
+
+// gitLabEngines lists the engines whose findings go into each report type. KICS is reported as SAST,
+// which is how GitLab's own IaC scanning reports its findings.
+var gitLabEngines = map[string][]string{
+	GitLabSAST:               {EngineSAST, EngineKICS},
+	GitLabDependencyScanning: {EngineSCA},
+	GitLabSecretDetection:    {EngineSecrets},
+}
+
+var gitLabSeverities = map[string]string{
+	SeverityCritical: "Critical",
+	SeverityHigh:     "High",
+	SeverityMedium:   "Medium",
+	SeverityLow:      "Low",
+	SeverityInfo:     "Info",
+}
+
+// manifestFiles guesses the manifest of an SCA package from its package manager, since Cx1 does not
+// report which file declared the dependency
+var manifestFiles = map[string]string{
+	"npm":      "package.json",
+	"maven":    "pom.xml",
+	"gradle":   "build.gradle",
+	"python":   "requirements.txt",
+	"pip":      "requirements.txt",
+	"pypi":     "requirements.txt",
+	"nuget":    "packages.config",
+	"go":       "go.mod",
+	"golang":   "go.mod",
+	"rubygems": "Gemfile.lock",
+	"composer": "composer.json",
+	"php":      "composer.json",
+	"cargo":    "Cargo.toml",
+}
+
+type gitLabReport struct {
+	Version         string                `json:"version"`
+	Scan            gitLabScan            `json:"scan"`
+	Vulnerabilities []gitLabVulnerability `json:"vulnerabilities"`
+}
+
+type gitLabScan struct {
+	Analyzer  gitLabTool `json:"analyzer"`
+	Scanner   gitLabTool `json:"scanner"`
+	Type      string     `json:"type"`
+	StartTime string     `json:"start_time"`
+	EndTime   string     `json:"end_time"`
+	Status    string     `json:"status"`
+}
+
+type gitLabTool struct {
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Version string       `json:"version"`
+	Vendor  gitLabVendor `json:"vendor"`
+}
+
+type gitLabVendor struct {
+	Name string `json:"name"`
+}
+
+type gitLabVulnerability struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Severity    string             `json:"severity"`
+	Solution    string             `json:"solution,omitempty"`
+	Identifiers []gitLabIdentifier `json:"identifiers"`
+	Links       []gitLabLink       `json:"links,omitempty"`
+	Location    gitLabLocation     `json:"location"`
+}
+
+type gitLabIdentifier struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	URL   string `json:"url,omitempty"`
+}
+
+type gitLabLink struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url"`
+}
+
+type gitLabLocation struct {
+	File       string            `json:"file,omitempty"`
+	StartLine  uint64            `json:"start_line,omitempty"`
+	Dependency *gitLabDependency `json:"dependency,omitempty"`
+	Commit     *gitLabCommit     `json:"commit,omitempty"`
+}
+
+type gitLabDependency struct {
+	Package gitLabPackage `json:"package"`
+	Version string        `json:"version"`
+}
+
+type gitLabPackage struct {
+	Name string `json:"name"`
+}
+
+type gitLabCommit struct {
+	SHA string `json:"sha"`
+}
+
+// GitLab renders the scans as a GitLab security report of the given type. When a commit has both a
+// fast and a full scan, findings present in both are reported once, from the first scan given. Findings
+// triaged as not exploitable are left out, since GitLab has no way to show them as dismissed.
+func GitLab(reportType string, scans []Scan) ([]byte, error) {
+	engines, ok := gitLabEngines[reportType]
+	if !ok {
+		return nil, fmt.Errorf("unknown GitLab report type: %s", reportType)
+	}
+	included := make(map[string]bool)
+	for _, engine := range engines {
+		included[engine] = true
+	}
+
+	now := time.Now().UTC()
+	report := gitLabReport{
+		Version: gitLabSchemaVersion,
+		Scan: gitLabScan{
+			Analyzer:  gitLabTool{ID: "checkmarx-one", Name: "Checkmarx One", Version: "1.0", Vendor: gitLabVendor{Name: gitLabVendorName}},
+			Scanner:   gitLabTool{ID: "checkmarx-one-" + strings.ReplaceAll(reportType, "_", "-"), Name: "Checkmarx One", Version: "1.0", Vendor: gitLabVendor{Name: gitLabVendorName}},
+			Type:      reportType,
+			StartTime: now.Format(gitLabTimeLayout),
+			EndTime:   now.Format(gitLabTimeLayout),
+			Status:    "success",
+		},
+		Vulnerabilities: []gitLabVulnerability{},
+	}
+
+	seen := make(map[string]bool)
+	for i, scan := range scans {
+		if i == 0 {
+			report.Scan.StartTime = gitLabTime(scan.Info.CreatedAt, now)
+			report.Scan.EndTime = gitLabTime(scan.Info.UpdatedAt, now)
+		}
+
+		for _, finding := range Flatten(scan.Results) {
+			if !included[finding.Engine] || finding.Dismissed() || seen[finding.Key()] {
+				continue
+			}
+			seen[finding.Key()] = true
+			report.Vulnerabilities = append(report.Vulnerabilities, gitLabVulnerabilityFor(finding, scan.Info))
+		}
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal GitLab report: %v", err)
+	}
+	return data, nil
+}
+
+func gitLabVulnerabilityFor(finding Finding, info ScanInfo) gitLabVulnerability {
+	name := finding.RuleName
+	if name == "" {
+		name = finding.RuleID
+	}
+
+	vulnerability := gitLabVulnerability{
+		ID:          gitLabID(finding),
+		Name:        strings.ReplaceAll(name, "_", " "),
+		Description: finding.Description,
+		Severity:    gitLabSeverities[finding.Severity],
+		Identifiers: gitLabIdentifiers(finding),
+	}
+	if finding.HelpURI != "" {
+		vulnerability.Links = []gitLabLink{{URL: finding.HelpURI}}
+	}
+
+	switch finding.Engine {
+	case EngineSCA:
+		manager, pkg, version := parsePackageIdentifier(finding.Package)
+		file, ok := manifestFiles[strings.ToLower(manager)]
+		if !ok {
+			file = finding.Package
+		}
+		vulnerability.Location = gitLabLocation{
+			File: file,
+			Dependency: &gitLabDependency{
+				Package: gitLabPackage{Name: pkg},
+				Version: version,
+			},
+		}
+		if finding.Recommendation != "" {
+			vulnerability.Solution = fmt.Sprintf("Upgrade %s to version %s", pkg, finding.Recommendation)
+		}
+	case EngineSecrets:
+		vulnerability.Location = gitLabLocation{
+			File:      finding.File,
+			StartLine: finding.Line,
+			Commit:    &gitLabCommit{SHA: info.CommitID},
+		}
+		vulnerability.Solution = "Revoke the secret, rotate it and remove it from the repository history"
+	default:
+		vulnerability.Location = gitLabLocation{File: finding.File, StartLine: finding.Line}
+		if finding.Engine == EngineKICS && finding.Recommendation != "" {
+			vulnerability.Solution = "Expected: " + finding.Recommendation
+		}
+	}
+
+	return vulnerability
+}
+
+func gitLabIdentifiers(finding Finding) []gitLabIdentifier {
+	var identifiers []gitLabIdentifier
+
+	switch finding.Engine {
+	case EngineSCA:
+		if strings.HasPrefix(finding.CVE, "CVE-") {
+			identifiers = append(identifiers, gitLabIdentifier{Type: "cve", Name: finding.CVE, Value: finding.CVE, URL: finding.HelpURI})
+		}
+	default:
+		name := finding.RuleName
+		if name == "" {
+			name = finding.RuleID
+		}
+		identifiers = append(identifiers, gitLabIdentifier{
+			Type:  "checkmarx_" + finding.Engine + "_rule",
+			Name:  name,
+			Value: finding.RuleID,
+		})
+	}
+
+	if finding.CWE != "" {
+		number := strings.TrimPrefix(finding.CWE, "CWE-")
+		identifiers = append(identifiers, gitLabIdentifier{
+			Type:  "cwe",
+			Name:  "CWE-" + number,
+			Value: number,
+			URL:   fmt.Sprintf("https://cwe.mitre.org/data/definitions/%s.html", number),
+		})
+	}
+
+	// GitLab requires at least one identifier
+	if len(identifiers) == 0 {
+		identifiers = append(identifiers, gitLabIdentifier{
+			Type:  "checkmarx_" + finding.Engine + "_rule",
+			Name:  finding.RuleID,
+			Value: finding.RuleID,
+		})
+	}
+
+	return identifiers
+}
+
+// gitLabID derives a stable UUID-formatted ID from the finding's similarity ID so that GitLab tracks
+// the same vulnerability across pipelines
+func gitLabID(finding Finding) string {
+	key := finding.Key()
+	if finding.SimilarityID == "" {
+		key += ":" + finding.ResultID
+	}
+	sum := sha256.Sum256([]byte(key))
+	h := hex.EncodeToString(sum[:16])
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:32])
+}
+
+// parsePackageIdentifier splits an SCA identifier such as "Npm-left-pad-1.3.0" into manager, name and version
+func parsePackageIdentifier(identifier string) (manager, name, version string) {
+	first := strings.Index(identifier, "-")
+	last := strings.LastIndex(identifier, "-")
+	if first < 0 || last <= first {
+		return "", identifier, ""
+	}
+	return identifier[:first], identifier[first+1 : last], identifier[last+1:]
+}
+
+func gitLabTime(value string, fallback time.Time) string {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC().Format(gitLabTimeLayout)
+	}
+	return fallback.Format(gitLabTimeLayout)
+}
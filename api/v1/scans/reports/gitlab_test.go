@@ -0,0 +1,116 @@
+package reports
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGitLabGolden(t *testing.T) {
+	for _, reportType := range []string{GitLabSAST, GitLabDependencyScanning, GitLabSecretDetection} {
+		t.Run(reportType, func(t *testing.T) {
+			data, err := GitLab(reportType, syntheticScans())
+			if err != nil {
+				t.Fatalf("GitLab(%s) = %v", reportType, err)
+			}
+			assertGolden(t, "synthetic.gitlab-"+reportType+".json", append(data, '\n'))
+		})
+	}
+}
+
+func TestGitLabFindings(t *testing.T) {
+	tests := []struct {
+		reportType string
+		// wantNames are the vulnerabilities in report order. The full scan's dismissed Log_Forging finding is
+		// left out, so the fast scan's untriaged copy is reported; its SQL_Injection is not repeated.
+		wantNames []string
+	}{
+		{reportType: GitLabSAST, wantNames: []string{"SQL Injection", "Missing User Instruction", "Log Forging"}},
+		{reportType: GitLabDependencyScanning, wantNames: []string{"CVE-2021-44228"}},
+		{reportType: GitLabSecretDetection, wantNames: []string{"AWS Access Token"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reportType, func(t *testing.T) {
+			data, err := GitLab(tt.reportType, syntheticScans())
+			if err != nil {
+				t.Fatalf("GitLab() = %v", err)
+			}
+
+			var report gitLabReport
+			if err := json.Unmarshal(data, &report); err != nil {
+				t.Fatalf("GitLab() is not valid JSON: %v", err)
+			}
+			if report.Scan.Type != tt.reportType {
+				t.Fatalf("scan.type = %q, want %q", report.Scan.Type, tt.reportType)
+			}
+			if report.Scan.StartTime != "2024-05-01T10:00:00" || report.Scan.EndTime != "2024-05-01T10:12:30" {
+				t.Fatalf("scan times = %s - %s, want the first scan's", report.Scan.StartTime, report.Scan.EndTime)
+			}
+
+			if len(report.Vulnerabilities) != len(tt.wantNames) {
+				t.Fatalf("got %d vulnerabilities, want %d", len(report.Vulnerabilities), len(tt.wantNames))
+			}
+			ids := make(map[string]bool)
+			for i, vulnerability := range report.Vulnerabilities {
+				if vulnerability.Name != tt.wantNames[i] {
+					t.Errorf("vulnerability %d name = %q, want %q", i, vulnerability.Name, tt.wantNames[i])
+				}
+				if len(vulnerability.Identifiers) == 0 {
+					t.Errorf("vulnerability %q has no identifiers", vulnerability.Name)
+				}
+				if ids[vulnerability.ID] {
+					t.Errorf("duplicate vulnerability ID %s", vulnerability.ID)
+				}
+				ids[vulnerability.ID] = true
+			}
+		})
+	}
+}
+
+func TestGitLabStableIDs(t *testing.T) {
+	first, err := GitLab(GitLabSAST, syntheticScans())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := GitLab(GitLabSAST, syntheticScans())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first) != string(second) {
+		t.Fatal("rendering the same scans twice gave different reports")
+	}
+}
+
+func TestGitLabUnknownReportType(t *testing.T) {
+	if _, err := GitLab("container_scanning", syntheticScans()); err == nil {
+		t.Fatal("expected an error for an unknown report type")
+	}
+}
+
+func TestParsePackageIdentifier(t *testing.T) {
+	tests := []struct {
+		identifier  string
+		wantManager string
+		wantName    string
+		wantVersion string
+	}{
+		{identifier: "Npm-left-pad-1.3.0", wantManager: "Npm", wantName: "left-pad", wantVersion: "1.3.0"},
+		{identifier: "Maven-org.apache.logging.log4j:log4j-core-2.14.1", wantManager: "Maven", wantName: "org.apache.logging.log4j:log4j-core", wantVersion: "2.14.1"},
+		{identifier: "Npm-@babel/core-7.22.5", wantManager: "Npm", wantName: "@babel/core", wantVersion: "7.22.5"},
+		{identifier: "Python-requests-2.31.0", wantManager: "Python", wantName: "requests", wantVersion: "2.31.0"},
+		{identifier: "Go-github.com/gin-gonic/gin-v1.9.0", wantManager: "Go", wantName: "github.com/gin-gonic/gin", wantVersion: "v1.9.0"},
+		{identifier: "Npm-lodash", wantName: "Npm-lodash"},
+		{identifier: "lodash", wantName: "lodash"},
+		{identifier: "", wantName: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.identifier, func(t *testing.T) {
+			manager, name, version := parsePackageIdentifier(tt.identifier)
+			if manager != tt.wantManager || name != tt.wantName || version != tt.wantVersion {
+				t.Fatalf("parsePackageIdentifier(%q) = (%q, %q, %q), want (%q, %q, %q)",
+					tt.identifier, manager, name, version, tt.wantManager, tt.wantName, tt.wantVersion)
+			}
+		})
+	}
+}
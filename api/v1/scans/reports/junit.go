@@ -0,0 +1,205 @@
+// api/v1/scans/reports/junit.go
+package reports
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// ContentTypeJUnit is the media type of a JUnit XML report
+const ContentTypeJUnit = "application/xml"
+
+// DefaultJUnitThreshold is the lowest severity that fails a test case when none is given
+const DefaultJUnitThreshold = SeverityHigh
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit renders the scans as JUnit XML. Every engine of every scan is a test suite and every query
+// (SAST, KICS), package (SCA) or rule (secrets) a test case, which fails when it has a finding at or
+// above threshold that was not triaged as not exploitable. A policy-blocked scan adds a failing
+// "policy" suite so the pipeline shows why the build broke.
+func JUnit(scans []Scan, threshold string) ([]byte, error) {
+	threshold = NormalizeSeverity(threshold)
+
+	report := junitTestSuites{Name: "Checkmarx One"}
+	for _, scan := range scans {
+		byEngine := make(map[string][]Finding)
+		for _, finding := range Flatten(scan.Results) {
+			byEngine[finding.Engine] = append(byEngine[finding.Engine], finding)
+		}
+
+		for _, engine := range Engines {
+			findings, ok := byEngine[engine]
+			if !ok {
+				continue
+			}
+			report.Suites = append(report.Suites, junitSuiteFor(scan.Info, engine, findings, threshold))
+		}
+
+		if scan.Info.IsPolicyBlocked {
+			report.Suites = append(report.Suites, junitPolicySuite(scan.Info))
+		}
+	}
+
+	for _, suite := range report.Suites {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JUnit report: %v", err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func junitSuiteFor(info ScanInfo, engine string, findings []Finding, threshold string) junitTestSuite {
+	classname := fmt.Sprintf("checkmarx.%s.%s", info.Mode(), engine)
+
+	// Group findings into test cases, keeping the order in which each case was first seen
+	var names []string
+	cases := make(map[string][]Finding)
+	for _, finding := range findings {
+		name := junitCaseName(finding)
+		if _, ok := cases[name]; !ok {
+			names = append(names, name)
+		}
+		cases[name] = append(cases[name], finding)
+	}
+
+	suite := junitTestSuite{
+		Name:       classname,
+		Timestamp:  info.UpdatedAt,
+		Properties: junitProperties(info, threshold),
+	}
+
+	for _, name := range names {
+		testCase := junitTestCase{Name: name, Classname: classname}
+
+		var failing, passing []string
+		for _, finding := range cases[name] {
+			line := junitFindingLine(finding)
+			if !finding.Dismissed() && AtLeast(finding.Severity, threshold) {
+				failing = append(failing, line)
+			} else {
+				passing = append(passing, line)
+			}
+		}
+
+		if len(failing) > 0 {
+			testCase.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d finding(s) at or above %s", len(failing), threshold),
+				Type:    engine,
+				Text:    strings.Join(failing, "\n"),
+			}
+			suite.Failures++
+		}
+		if len(passing) > 0 {
+			testCase.SystemOut = strings.Join(passing, "\n")
+		}
+
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	suite.Tests = len(suite.TestCases)
+
+	return suite
+}
+
+func junitPolicySuite(info ScanInfo) junitTestSuite {
+	classname := fmt.Sprintf("checkmarx.%s.policy", info.Mode())
+	return junitTestSuite{
+		Name:      classname,
+		Tests:     1,
+		Failures:  1,
+		Timestamp: info.UpdatedAt,
+		TestCases: []junitTestCase{{
+			Name:      "Checkmarx One policy",
+			Classname: classname,
+			Failure: &junitFailure{
+				Message: "Scan violates a Checkmarx One policy that breaks the build",
+				Type:    "policy",
+				Text:    fmt.Sprintf("Scan %s is blocked by policy. See %s", info.ScanID, info.Link),
+			},
+		}},
+	}
+}
+
+func junitProperties(info ScanInfo, threshold string) []junitProperty {
+	return []junitProperty{
+		{Name: "scan_id", Value: info.ScanID},
+		{Name: "project_id", Value: info.ProjectID},
+		{Name: "commit_id", Value: info.CommitID},
+		{Name: "branch", Value: info.Branch},
+		{Name: "link", Value: info.Link},
+		{Name: "severity_threshold", Value: threshold},
+	}
+}
+
+// junitCaseName names a test case after what the finding is about: the package for SCA, the query or rule otherwise
+func junitCaseName(finding Finding) string {
+	if finding.Engine == EngineSCA && finding.Package != "" {
+		return finding.Package
+	}
+	if finding.RuleName != "" {
+		return finding.RuleName
+	}
+	return finding.RuleID
+}
+
+func junitFindingLine(finding Finding) string {
+	var b strings.Builder
+	b.WriteString(finding.Severity)
+
+	switch {
+	case finding.Engine == EngineSCA:
+		fmt.Fprintf(&b, " %s", finding.RuleID)
+		if finding.Recommendation != "" {
+			fmt.Fprintf(&b, " (recommended version: %s)", finding.Recommendation)
+		}
+	case finding.File != "":
+		fmt.Fprintf(&b, " %s:%d", finding.File, finding.Line)
+	}
+
+	if finding.State != "" {
+		fmt.Fprintf(&b, " [%s]", finding.State)
+	}
+	if finding.SimilarityID != "" {
+		fmt.Fprintf(&b, " similarity_id=%s", finding.SimilarityID)
+	}
+	return b.String()
+}
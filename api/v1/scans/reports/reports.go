@@ -0,0 +1,215 @@
+// Package reports converts Cx1 scan results into formats understood by CI and code-scanning tools.
+//
+// Every renderer works on the same flattened view of a result set: one Finding per SAST, SCA, KICS
+// or secrets result, with the engine specific fields mapped onto common ones.
+package reports
+
+import (
+	"fmt"
+	"strings"
+
+	cx1 "github.com/madhatkul/CxWrapper-v2/Cx1ClientGo"
+)
+
+// Engines as reported on a Finding
+const (
+	EngineSAST    = "sast"
+	EngineSCA     = "sca"
+	EngineKICS    = "kics"
+	EngineSecrets = "secrets"
+)
+
+// Engines lists the engines in the order reports present them
+var Engines = []string{EngineSAST, EngineSCA, EngineKICS, EngineSecrets}
+
+// Severities in increasing order
+const (
+	SeverityInfo     = "INFO"
+	SeverityLow      = "LOW"
+	SeverityMedium   = "MEDIUM"
+	SeverityHigh     = "HIGH"
+	SeverityCritical = "CRITICAL"
+)
+
+var severityRank = map[string]int{
+	SeverityInfo:     0,
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityCritical: 4,
+}
+
+// notExploitable is the triage state of findings that were reviewed and dismissed
+const notExploitable = "NOT_EXPLOITABLE"
+
+// ScanInfo identifies the scan a result set came from
+type ScanInfo struct {
+	ScanID          string
+	ProjectID       string
+	ProjectName     string
+	Branch          string
+	CommitID        string
+	Link            string
+	IsFastScan      bool
+	IsPolicyBlocked bool
+	CreatedAt       string
+	UpdatedAt       string
+}
+
+// Mode returns "fast" or "full"
+func (s ScanInfo) Mode() string {
+	if s.IsFastScan {
+		return "fast"
+	}
+	return "full"
+}
+
+// Scan is a completed scan and its results
+type Scan struct {
+	Info    ScanInfo
+	Results cx1.ScanResultSet
+}
+
+// Finding is a single result from any engine
+type Finding struct {
+	Engine       string `json:"engine"`
+	ResultID     string `json:"result_id"`
+	SimilarityID string `json:"similarity_id"`
+	// RuleID and RuleName identify what was found: the query for SAST and KICS, the vulnerability for SCA
+	// and the rule for secrets
+	RuleID      string  `json:"rule_id"`
+	RuleName    string  `json:"rule_name,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Severity    string  `json:"severity"`
+	State       string  `json:"state,omitempty"`
+	Status      string  `json:"status,omitempty"`
+	File        string  `json:"file,omitempty"`
+	Line        uint64  `json:"line,omitempty"`
+	Column      uint64  `json:"column,omitempty"`
+	CWE         string  `json:"cwe,omitempty"`
+	CVE         string  `json:"cve,omitempty"`
+	CVSS        float64 `json:"cvss,omitempty"`
+	Package     string  `json:"package,omitempty"`
+	// Recommendation is the fix suggested for SCA findings and the expected value for KICS findings
+	Recommendation string `json:"recommendation,omitempty"`
+	HelpURI        string `json:"help_uri,omitempty"`
+	// Fingerprint is an engine specific hash that stays stable when unrelated code moves
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// Dismissed reports whether the finding was triaged as not exploitable
+func (f Finding) Dismissed() bool {
+	return strings.EqualFold(f.State, notExploitable)
+}
+
+// Key identifies a finding across scans of the same project: its similarity ID, which Cx1 keeps stable
+// across scans, scoped to the engine that reported it
+func (f Finding) Key() string {
+	if f.SimilarityID != "" {
+		return f.Engine + ":" + f.SimilarityID
+	}
+	return f.Engine + ":" + f.RuleID + ":" + f.File + ":" + f.Package
+}
+
+// Flatten maps every result in the set onto a Finding, in SAST, SCA, KICS, secrets order
+func Flatten(results cx1.ScanResultSet) []Finding {
+	findings := make([]Finding, 0, len(results.SAST)+len(results.SCA)+len(results.KICS)+len(results.Secrets))
+
+	for _, r := range results.SAST {
+		f := newFinding(EngineSAST, r.ScanResultBase)
+		f.RuleID = fmt.Sprintf("%d", r.Data.QueryID)
+		f.RuleName = r.Data.QueryName
+		f.Fingerprint = r.Data.ResultHash
+		if r.VulnerabilityDetails.CweId > 0 {
+			f.CWE = fmt.Sprintf("CWE-%d", r.VulnerabilityDetails.CweId)
+		}
+		// The first node is where the tainted data enters, which is where the finding is reported
+		if len(r.Data.Nodes) > 0 {
+			node := r.Data.Nodes[0]
+			f.File = normalizePath(node.FileName)
+			f.Line = node.Line
+			f.Column = node.Column
+		}
+		findings = append(findings, f)
+	}
+
+	for _, r := range results.SCA {
+		f := newFinding(EngineSCA, r.ScanResultBase)
+		f.RuleID = r.VulnerabilityDetails.CveName
+		if f.RuleID == "" {
+			f.RuleID = r.ResultID
+		}
+		f.RuleName = r.VulnerabilityDetails.CveName
+		f.CVE = r.VulnerabilityDetails.CveName
+		f.CVSS = r.VulnerabilityDetails.CVSSScore
+		f.CWE = r.VulnerabilityDetails.CweId
+		f.Package = r.Data.PackageIdentifier
+		f.Recommendation = r.Data.Recommendation
+		if strings.HasPrefix(f.CVE, "CVE-") {
+			f.HelpURI = "https://nvd.nist.gov/vuln/detail/" + f.CVE
+		}
+		findings = append(findings, f)
+	}
+
+	for _, r := range results.KICS {
+		f := newFinding(EngineKICS, r.ScanResultBase)
+		f.RuleID = r.Data.QueryID
+		f.RuleName = r.Data.QueryName
+		f.File = normalizePath(r.Data.FileName)
+		f.Line = r.Data.Line
+		f.Recommendation = r.Data.ExpectedValue
+		f.HelpURI = r.Data.QueryURL
+		findings = append(findings, f)
+	}
+
+	for _, r := range results.Secrets {
+		f := newFinding(EngineSecrets, r.ScanResultBase)
+		f.RuleID = r.Data.RuleID
+		f.RuleName = r.Data.RuleName
+		if r.Data.RuleDescription != "" {
+			f.Description = r.Data.RuleDescription
+		}
+		f.File = normalizePath(r.Data.FileName)
+		f.Line = r.Data.Line
+		findings = append(findings, f)
+	}
+
+	return findings
+}
+
+func newFinding(engine string, base cx1.ScanResultBase) Finding {
+	return Finding{
+		Engine:       engine,
+		ResultID:     base.ResultID,
+		SimilarityID: base.SimilarityID,
+		Description:  base.Description,
+		Severity:     NormalizeSeverity(base.Severity),
+		State:        base.State,
+		Status:       base.Status,
+	}
+}
+
+// NormalizeSeverity upper-cases a severity and maps unknown values to INFO
+func NormalizeSeverity(severity string) string {
+	severity = strings.ToUpper(strings.TrimSpace(severity))
+	if _, ok := severityRank[severity]; !ok {
+		return SeverityInfo
+	}
+	return severity
+}
+
+// AtLeast reports whether severity is at or above threshold
+func AtLeast(severity, threshold string) bool {
+	return severityRank[NormalizeSeverity(severity)] >= severityRank[NormalizeSeverity(threshold)]
+}
+
+// ValidSeverity reports whether severity is one of the known severities, in any case
+func ValidSeverity(severity string) bool {
+	_, ok := severityRank[strings.ToUpper(strings.TrimSpace(severity))]
+	return ok
+}
+
+// normalizePath turns the absolute paths Cx1 reports inside the uploaded archive into repository relative paths
+func normalizePath(file string) string {
+	return strings.TrimLeft(strings.ReplaceAll(file, "\\", "/"), "/")
+}
@@ -0,0 +1,73 @@
+package reports
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/madhatkul/CxWrapper-v2/api/cxclient"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// syntheticScans is a full scan of every engine, with one SAST finding triaged as not exploitable,
+// followed by a fast SAST scan of the same commit
+func syntheticScans() []Scan {
+	full := cxclient.SyntheticResults("project-1", Engines)
+	full.SAST[1].State = notExploitable
+
+	return []Scan{
+		{
+			Info: ScanInfo{
+				ScanID:      "scan-full",
+				ProjectID:   "project-1",
+				ProjectName: "payments-api",
+				Branch:      "main",
+				CommitID:    "0123456789abcdef0123456789abcdef01234567",
+				Link:        "https://cx1.example.com/projects/project-1/scans?id=scan-full",
+				CreatedAt:   "2024-05-01T10:00:00Z",
+				UpdatedAt:   "2024-05-01T10:12:30Z",
+			},
+			Results: full,
+		},
+		{
+			Info: ScanInfo{
+				ScanID:      "scan-fast",
+				ProjectID:   "project-1",
+				ProjectName: "payments-api",
+				Branch:      "main",
+				CommitID:    "0123456789abcdef0123456789abcdef01234567",
+				Link:        "https://cx1.example.com/projects/project-1/scans?id=scan-fast",
+				IsFastScan:  true,
+				CreatedAt:   "2024-05-01T09:58:00Z",
+				UpdatedAt:   "2024-05-01T10:01:10Z",
+			},
+			Results: cxclient.SyntheticResults("project-1", []string{EngineSAST}),
+		},
+	}
+}
+
+// assertGolden compares got with testdata/name, or rewrites the file when the tests run with -update
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match the golden file; run with -update and review the diff\ngot:\n%s", name, got)
+	}
+}
@@ -0,0 +1,329 @@
+// api/v1/scans/reports/sarif.go
+package reports
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	// ContentTypeSARIF is the media type of a SARIF log
+	ContentTypeSARIF = "application/sarif+json"
+
+	toolInformationURI = "https://checkmarx.com/product/application-security-platform/"
+)
+
+var engineToolNames = map[string]string{
+	EngineSAST:    "Checkmarx One SAST",
+	EngineSCA:     "Checkmarx One SCA",
+	EngineKICS:    "Checkmarx One KICS",
+	EngineSecrets: "Checkmarx One Secret Detection",
+}
+
+// securitySeverity is the CVSS-like score code-scanning UIs use to rank findings
+var securitySeverity = map[string]string{
+	SeverityCritical: "9.5",
+	SeverityHigh:     "8.0",
+	SeverityMedium:   "5.5",
+	SeverityLow:      "3.0",
+	SeverityInfo:     "0.0",
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool              sarifTool              `json:"tool"`
+	AutomationDetails sarifAutomationDetails `json:"automationDetails"`
+	Results           []sarifResult          `json:"results"`
+	Properties        map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifAutomationDetails struct {
+	ID string `json:"id"`
+}
+
+type sarifRule struct {
+	ID                   string                 `json:"id"`
+	Name                 string                 `json:"name,omitempty"`
+	ShortDescription     sarifMessage           `json:"shortDescription"`
+	FullDescription      *sarifMessage          `json:"fullDescription,omitempty"`
+	HelpURI              string                 `json:"helpUri,omitempty"`
+	DefaultConfiguration sarifConfiguration     `json:"defaultConfiguration"`
+	Properties           map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string                 `json:"ruleId"`
+	RuleIndex           int                    `json:"ruleIndex"`
+	Level               string                 `json:"level"`
+	Message             sarifMessage           `json:"message"`
+	Locations           []sarifLocation        `json:"locations,omitempty"`
+	PartialFingerprints map[string]string      `json:"partialFingerprints,omitempty"`
+	Suppressions        []sarifSuppression     `json:"suppressions,omitempty"`
+	Properties          map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   uint64 `json:"startLine"`
+	StartColumn uint64 `json:"startColumn,omitempty"`
+}
+
+type sarifLogicalLocation struct {
+	Name string `json:"name"`
+	Kind string `json:"kind,omitempty"`
+}
+
+type sarifSuppression struct {
+	Kind   string `json:"kind"`
+	Status string `json:"status"`
+}
+
+// SARIF renders the scans as a SARIF 2.1.0 log with one run per scan and engine. Runs are told apart by
+// their automationDetails id, "checkmarx/<engine>/<fast|full>/", so code-scanning UIs keep them as separate
+// analyses.
+func SARIF(scans []Scan) ([]byte, error) {
+	log := sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{},
+	}
+
+	for _, scan := range scans {
+		byEngine := make(map[string][]Finding)
+		for _, finding := range Flatten(scan.Results) {
+			byEngine[finding.Engine] = append(byEngine[finding.Engine], finding)
+		}
+
+		for _, engine := range Engines {
+			findings, ok := byEngine[engine]
+			if !ok {
+				continue
+			}
+			log.Runs = append(log.Runs, sarifRunFor(scan.Info, engine, findings))
+		}
+	}
+
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal SARIF log: %v", err)
+	}
+	return data, nil
+}
+
+func sarifRunFor(info ScanInfo, engine string, findings []Finding) sarifRun {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           engineToolNames[engine],
+			InformationURI: toolInformationURI,
+			Rules:          []sarifRule{},
+		}},
+		AutomationDetails: sarifAutomationDetails{
+			ID: fmt.Sprintf("checkmarx/%s/%s/", engine, info.Mode()),
+		},
+		Results: make([]sarifResult, 0, len(findings)),
+		Properties: map[string]interface{}{
+			"scanId":          info.ScanID,
+			"projectId":       info.ProjectID,
+			"commitId":        info.CommitID,
+			"branch":          info.Branch,
+			"link":            info.Link,
+			"isFastScan":      info.IsFastScan,
+			"isPolicyBlocked": info.IsPolicyBlocked,
+		},
+	}
+
+	ruleIndex := make(map[string]int)
+	for _, finding := range findings {
+		index, ok := ruleIndex[finding.RuleID]
+		if !ok {
+			index = len(run.Tool.Driver.Rules)
+			ruleIndex[finding.RuleID] = index
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRuleFor(finding))
+		} else if moreSevere(finding, run.Tool.Driver.Rules[index]) {
+			// A rule's level is that of its most severe finding
+			run.Tool.Driver.Rules[index] = sarifRuleFor(finding)
+		}
+
+		run.Results = append(run.Results, sarifResultFor(finding, index))
+	}
+
+	return run
+}
+
+// moreSevere reports whether finding is more severe than the finding rule was built from
+func moreSevere(finding Finding, rule sarifRule) bool {
+	current, _ := rule.Properties["problem.severity"].(string)
+	return severityRank[finding.Severity] > severityRank[current]
+}
+
+func sarifRuleFor(finding Finding) sarifRule {
+	name := finding.RuleName
+	if name == "" {
+		name = finding.RuleID
+	}
+
+	rule := sarifRule{
+		ID:                   finding.RuleID,
+		Name:                 toRuleName(name),
+		ShortDescription:     sarifMessage{Text: name},
+		HelpURI:              finding.HelpURI,
+		DefaultConfiguration: sarifConfiguration{Level: sarifLevel(finding.Severity)},
+		Properties: map[string]interface{}{
+			"security-severity": securitySeverityFor(finding),
+			"problem.severity":  finding.Severity,
+			"tags":              sarifTags(finding),
+		},
+	}
+	if finding.Description != "" {
+		rule.FullDescription = &sarifMessage{Text: finding.Description}
+	}
+	return rule
+}
+
+func sarifResultFor(finding Finding, ruleIndex int) sarifResult {
+	result := sarifResult{
+		RuleID:    finding.RuleID,
+		RuleIndex: ruleIndex,
+		Level:     sarifLevel(finding.Severity),
+		Message:   sarifMessage{Text: sarifMessageText(finding)},
+		Properties: map[string]interface{}{
+			"severity": finding.Severity,
+			"state":    finding.State,
+			"status":   finding.Status,
+			"resultId": finding.ResultID,
+		},
+	}
+
+	if finding.File != "" {
+		location := sarifLocation{PhysicalLocation: &sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: finding.File},
+		}}
+		if finding.Line > 0 {
+			location.PhysicalLocation.Region = &sarifRegion{StartLine: finding.Line, StartColumn: finding.Column}
+		}
+		result.Locations = []sarifLocation{location}
+	} else if finding.Package != "" {
+		result.Locations = []sarifLocation{{
+			LogicalLocations: []sarifLogicalLocation{{Name: finding.Package, Kind: "package"}},
+		}}
+	}
+
+	result.PartialFingerprints = map[string]string{}
+	if finding.SimilarityID != "" {
+		result.PartialFingerprints["similarityId/v1"] = finding.SimilarityID
+	}
+	if finding.Fingerprint != "" {
+		result.PartialFingerprints["resultHash/v1"] = finding.Fingerprint
+	}
+
+	if finding.Dismissed() {
+		result.Suppressions = []sarifSuppression{{Kind: "external", Status: "accepted"}}
+	}
+	if finding.Package != "" {
+		result.Properties["package"] = finding.Package
+	}
+	if finding.Recommendation != "" {
+		result.Properties["recommendation"] = finding.Recommendation
+	}
+
+	return result
+}
+
+func sarifLevel(severity string) string {
+	switch severity {
+	case SeverityCritical, SeverityHigh:
+		return "error"
+	case SeverityMedium:
+		return "warning"
+	}
+	return "note"
+}
+
+// securitySeverityFor prefers the CVSS score of SCA findings over the fixed score for the severity
+func securitySeverityFor(finding Finding) string {
+	if finding.CVSS > 0 {
+		return fmt.Sprintf("%.1f", finding.CVSS)
+	}
+	return securitySeverity[finding.Severity]
+}
+
+func sarifTags(finding Finding) []string {
+	tags := []string{"security", finding.Engine}
+	if finding.CWE != "" {
+		tags = append(tags, "external/cwe/"+strings.ToLower(finding.CWE))
+	}
+	return tags
+}
+
+func sarifMessageText(finding Finding) string {
+	name := finding.RuleName
+	if name == "" {
+		name = finding.RuleID
+	}
+
+	switch {
+	case finding.Package != "":
+		text := fmt.Sprintf("%s in %s", name, finding.Package)
+		if finding.Recommendation != "" {
+			text += fmt.Sprintf(" (recommended version: %s)", finding.Recommendation)
+		}
+		return text
+	case finding.File != "" && finding.Line > 0:
+		return fmt.Sprintf("%s at %s:%d", name, finding.File, finding.Line)
+	case finding.File != "":
+		return fmt.Sprintf("%s in %s", name, finding.File)
+	}
+	return name
+}
+
+// toRuleName turns a query name such as "SQL_Injection" into the PascalCase identifier SARIF expects
+func toRuleName(name string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == ' ' || r == '-' || r == '.'
+	}) {
+		b.WriteString(strings.ToUpper(word[:1]))
+		b.WriteString(word[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package reports
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSARIFGolden(t *testing.T) {
+	data, err := SARIF(syntheticScans())
+	if err != nil {
+		t.Fatalf("SARIF() = %v", err)
+	}
+	assertGolden(t, "synthetic.sarif", append(data, '\n'))
+}
+
+func TestSARIFStructure(t *testing.T) {
+	data, err := SARIF(syntheticScans())
+	if err != nil {
+		t.Fatalf("SARIF() = %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("SARIF() is not valid JSON: %v", err)
+	}
+	if log.Version != "2.1.0" {
+		t.Fatalf("version = %q", log.Version)
+	}
+
+	// One run per scan and engine: four engines in the full scan, SAST in the fast one
+	wantRuns := []string{
+		"checkmarx/sast/full/",
+		"checkmarx/sca/full/",
+		"checkmarx/kics/full/",
+		"checkmarx/secrets/full/",
+		"checkmarx/sast/fast/",
+	}
+	if len(log.Runs) != len(wantRuns) {
+		t.Fatalf("got %d runs, want %d", len(log.Runs), len(wantRuns))
+	}
+	for i, run := range log.Runs {
+		if run.AutomationDetails.ID != wantRuns[i] {
+			t.Errorf("run %d automationDetails.id = %q, want %q", i, run.AutomationDetails.ID, wantRuns[i])
+		}
+		for _, result := range run.Results {
+			if result.RuleIndex < 0 || result.RuleIndex >= len(run.Tool.Driver.Rules) {
+				t.Errorf("run %d result %s has rule index %d out of range", i, result.RuleID, result.RuleIndex)
+				continue
+			}
+			if rule := run.Tool.Driver.Rules[result.RuleIndex]; rule.ID != result.RuleID {
+				t.Errorf("run %d result rule %q points at rule %q", i, result.RuleID, rule.ID)
+			}
+		}
+	}
+
+	// The finding triaged as not exploitable is kept, suppressed
+	suppressed := 0
+	for _, result := range log.Runs[0].Results {
+		suppressed += len(result.Suppressions)
+	}
+	if suppressed != 1 {
+		t.Fatalf("full SAST run has %d suppressions, want 1", suppressed)
+	}
+}
+
+func TestSARIFNoScans(t *testing.T) {
+	data, err := SARIF(nil)
+	if err != nil {
+		t.Fatalf("SARIF() = %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("SARIF() is not valid JSON: %v", err)
+	}
+	if log.Runs == nil || len(log.Runs) != 0 {
+		t.Fatalf("runs = %v, want an empty array", log.Runs)
+	}
+}
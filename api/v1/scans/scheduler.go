@@ -0,0 +1,310 @@
+// api/v1/scans/scheduler.go
+package scans
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PriorityClass orders queued scans: fast scans first, then full scans of a default branch, then the rest
+type PriorityClass string
+
+const (
+	PriorityFast          PriorityClass = "fast"
+	PriorityDefaultBranch PriorityClass = "default_branch"
+	PriorityNormal        PriorityClass = "normal"
+)
+
+func (p PriorityClass) rank() int {
+	switch p {
+	case PriorityFast:
+		return 0
+	case PriorityDefaultBranch:
+		return 1
+	}
+	return 2
+}
+
+// QueueStatus is where a queued scan stands
+type QueueStatus struct {
+	// Position is 1 for the scan that starts next. It is an estimate: scans of applications at their
+	// concurrency cap wait longer, and a scan of a higher priority class may still overtake.
+	Position int           `json:"position"`
+	Length   int           `json:"length"`
+	Priority PriorityClass `json:"priority"`
+	// Running is the number of scans the wrapper has started that are not finished yet
+	Running int `json:"running"`
+	// Limit is the global concurrency cap, 0 when there is none
+	Limit int `json:"limit,omitempty"`
+}
+
+type queuedJob struct {
+	job *ScanJob
+	seq uint64
+}
+
+// Scheduler decides when scans are triggered in Cx1 so the tenant's concurrent scan license is not
+// exceeded. A global and a per-application cap bound the scans running at once; when a slot frees, the
+// queued scan of the best priority class starts, and within a class the application with the fewest
+// running scans, then the one served least recently, goes first.
+type Scheduler struct {
+	globalLimit     int
+	appLimit        int
+	appLimits       map[string]int
+	defaultBranches map[string]bool
+
+	mu      sync.Mutex
+	queue   []queuedJob
+	seq     uint64
+	running map[string]string // job ID -> application
+	perApp  map[string]int
+	served  map[string]uint64 // application -> number of its last dispatch
+	// dispatches counts the jobs started, numbering them for served
+	dispatches uint64
+	// dispatch triggers a job the scheduler started outside of Enqueue
+	dispatch func(job *ScanJob)
+}
+
+// NewScheduler reads SCAN_MAX_CONCURRENT, the global cap; SCAN_MAX_CONCURRENT_PER_APP, the cap of every
+// application; SCAN_APP_CONCURRENCY, a JSON object of per-application caps overriding it, e.g.
+// {"payments": 4}; and SCAN_DEFAULT_BRANCHES, the comma-separated branches whose scans are prioritized
+// (default "main,master"). Caps of 0 mean unlimited. On a malformed setting it returns the scheduler
+// without it along with the error.
+func NewScheduler(dispatch func(job *ScanJob)) (*Scheduler, error) {
+	s := &Scheduler{
+		appLimits:       make(map[string]int),
+		defaultBranches: map[string]bool{"main": true, "master": true},
+		running:         make(map[string]string),
+		perApp:          make(map[string]int),
+		served:          make(map[string]uint64),
+		dispatch:        dispatch,
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("SCAN_MAX_CONCURRENT")); err == nil && v > 0 {
+		s.globalLimit = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("SCAN_MAX_CONCURRENT_PER_APP")); err == nil && v > 0 {
+		s.appLimit = v
+	}
+	if v := os.Getenv("SCAN_DEFAULT_BRANCHES"); v != "" {
+		s.defaultBranches = make(map[string]bool)
+		for _, branch := range strings.Split(v, ",") {
+			if branch = strings.TrimSpace(branch); branch != "" {
+				s.defaultBranches[branch] = true
+			}
+		}
+	}
+	if v := os.Getenv("SCAN_APP_CONCURRENCY"); v != "" {
+		if err := json.Unmarshal([]byte(v), &s.appLimits); err != nil {
+			return s, fmt.Errorf("invalid SCAN_APP_CONCURRENCY: %v", err)
+		}
+	}
+
+	return s, nil
+}
+
+// Classify returns the priority class of a scan
+func (s *Scheduler) Classify(isFastScan bool, branch string) PriorityClass {
+	switch {
+	case isFastScan:
+		return PriorityFast
+	case s.defaultBranches[branch]:
+		return PriorityDefaultBranch
+	}
+	return PriorityNormal
+}
+
+// Enqueue queues a job and reports whether it was given a slot right away, in which case the caller
+// triggers it. Otherwise the job is passed to the dispatch function once its turn comes.
+func (s *Scheduler) Enqueue(job *ScanJob) bool {
+	s.mu.Lock()
+	s.seq++
+	s.queue = append(s.queue, queuedJob{job: job, seq: s.seq})
+	started := s.schedule()
+	s.mu.Unlock()
+
+	now := false
+	for _, next := range started {
+		if next == job {
+			now = true
+			continue
+		}
+		go s.dispatch(next)
+	}
+	return now
+}
+
+// Track counts a job that is already running, such as one resumed after a restart, against the caps
+func (s *Scheduler) Track(job *ScanJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.running[job.ID]; !ok {
+		s.running[job.ID] = job.AppName
+		s.perApp[job.AppName]++
+	}
+}
+
+// Release frees the slot of a job whose scan finished or could not be started, starting the next ones
+func (s *Scheduler) Release(jobID string) {
+	s.mu.Lock()
+	if app, ok := s.running[jobID]; ok {
+		delete(s.running, jobID)
+		if s.perApp[app]--; s.perApp[app] <= 0 {
+			delete(s.perApp, app)
+		}
+	}
+	started := s.schedule()
+	s.mu.Unlock()
+
+	for _, next := range started {
+		go s.dispatch(next)
+	}
+}
+
+// Remove takes a job out of the queue before it starts, reporting whether it was queued
+func (s *Scheduler) Remove(jobID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, queued := range s.queue {
+		if queued.job.ID == jobID {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Queued returns the queued jobs in the order they are expected to start, with their queue status
+func (s *Scheduler) Queued() ([]*ScanJob, []QueueStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order := s.expectedOrder()
+	jobs := make([]*ScanJob, len(order))
+	statuses := make([]QueueStatus, len(order))
+	for i, queued := range order {
+		jobs[i] = queued.job
+		statuses[i] = QueueStatus{
+			Position: i + 1,
+			Length:   len(order),
+			Priority: s.Classify(queued.job.IsFastScan, queued.job.Branch),
+			Running:  len(s.running),
+			Limit:    s.globalLimit,
+		}
+	}
+	return jobs, statuses
+}
+
+// Position returns the queue status of a job, or false if it is not queued
+func (s *Scheduler) Position(jobID string) (QueueStatus, bool) {
+	jobs, statuses := s.Queued()
+	for i, job := range jobs {
+		if job.ID == jobID {
+			return statuses[i], true
+		}
+	}
+	return QueueStatus{}, false
+}
+
+// schedule moves every job that may start now from the queue to running and returns them. s.mu must be held.
+func (s *Scheduler) schedule() []*ScanJob {
+	var started []*ScanJob
+	for {
+		if s.globalLimit > 0 && len(s.running) >= s.globalLimit {
+			return started
+		}
+
+		best := -1
+		for i, queued := range s.queue {
+			if limit := s.limitFor(queued.job.AppName); limit > 0 && s.perApp[queued.job.AppName] >= limit {
+				continue
+			}
+			if best < 0 || s.before(queued, s.queue[best], s.perApp, s.served) {
+				best = i
+			}
+		}
+		if best < 0 {
+			return started
+		}
+
+		next := s.queue[best]
+		s.queue = append(s.queue[:best], s.queue[best+1:]...)
+		s.running[next.job.ID] = next.job.AppName
+		s.perApp[next.job.AppName]++
+		s.dispatches++
+		s.served[next.job.AppName] = s.dispatches
+		started = append(started, next.job)
+	}
+}
+
+// expectedOrder simulates the order queued jobs would start in if no running scan finished, ignoring
+// the caps, which only delay a job. s.mu must be held.
+func (s *Scheduler) expectedOrder() []queuedJob {
+	remaining := append([]queuedJob{}, s.queue...)
+	perApp := make(map[string]int, len(s.perApp))
+	for app, n := range s.perApp {
+		perApp[app] = n
+	}
+
+	served := make(map[string]uint64, len(s.served))
+	for app, seq := range s.served {
+		served[app] = seq
+	}
+
+	dispatches := s.dispatches
+	order := make([]queuedJob, 0, len(remaining))
+	for len(remaining) > 0 {
+		best := 0
+		for i := 1; i < len(remaining); i++ {
+			if s.before(remaining[i], remaining[best], perApp, served) {
+				best = i
+			}
+		}
+		next := remaining[best]
+		remaining = append(remaining[:best], remaining[best+1:]...)
+		perApp[next.job.AppName]++
+		dispatches++
+		served[next.job.AppName] = dispatches
+		order = append(order, next)
+	}
+	return order
+}
+
+// before reports whether a should start before b, given the running scans and last dispatch of every application
+func (s *Scheduler) before(a, b queuedJob, perApp map[string]int, served map[string]uint64) bool {
+	aRank := s.Classify(a.job.IsFastScan, a.job.Branch).rank()
+	bRank := s.Classify(b.job.IsFastScan, b.job.Branch).rank()
+	if aRank != bRank {
+		return aRank < bRank
+	}
+	if a.job.AppName != b.job.AppName {
+		if perApp[a.job.AppName] != perApp[b.job.AppName] {
+			return perApp[a.job.AppName] < perApp[b.job.AppName]
+		}
+		if served[a.job.AppName] != served[b.job.AppName] {
+			return served[a.job.AppName] < served[b.job.AppName]
+		}
+	}
+	return a.seq < b.seq
+}
+
+func (s *Scheduler) limitFor(app string) int {
+	if limit, ok := s.appLimits[app]; ok {
+		return limit
+	}
+	return s.appLimit
+}
+
+// sortByCreation orders jobs oldest first, the order they are re-queued in after a restart
+func sortByCreation(jobs []ScanJob) {
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+}
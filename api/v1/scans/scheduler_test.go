@@ -0,0 +1,296 @@
+package scans
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	cx1 "github.com/madhatkul/CxWrapper-v2/Cx1ClientGo"
+)
+
+// newTestScheduler returns a scheduler configured by env, which replaces every scheduler setting, and
+// the channel the jobs it starts outside of Enqueue are sent on
+func newTestScheduler(t *testing.T, env map[string]string) (*Scheduler, chan *ScanJob) {
+	t.Helper()
+
+	for _, key := range []string{"SCAN_MAX_CONCURRENT", "SCAN_MAX_CONCURRENT_PER_APP", "SCAN_APP_CONCURRENCY", "SCAN_DEFAULT_BRANCHES"} {
+		t.Setenv(key, env[key])
+	}
+
+	dispatched := make(chan *ScanJob, 16)
+	s, err := NewScheduler(func(job *ScanJob) { dispatched <- job })
+	if err != nil {
+		t.Fatalf("NewScheduler() = %v", err)
+	}
+	return s, dispatched
+}
+
+func schedulerJob(id, app, branch string, fast bool) *ScanJob {
+	return &ScanJob{ID: id, AppName: app, ProjectName: app + "-api", Branch: branch, CommitID: "commit-" + id, IsFastScan: fast}
+}
+
+// enqueue queues jobs, failing the test unless each one starts, or waits, as wantStarted says
+func enqueue(t *testing.T, s *Scheduler, wantStarted bool, jobs ...*ScanJob) {
+	t.Helper()
+
+	for _, job := range jobs {
+		if started := s.Enqueue(job); started != wantStarted {
+			t.Fatalf("Enqueue(%s) = %t, want %t", job.ID, started, wantStarted)
+		}
+	}
+}
+
+// expectDispatched waits for the scheduler to start the given jobs, in any order
+func expectDispatched(t *testing.T, dispatched chan *ScanJob, ids ...string) {
+	t.Helper()
+
+	var got []string
+	timeout := time.After(5 * time.Second)
+	for len(got) < len(ids) {
+		select {
+		case job := <-dispatched:
+			got = append(got, job.ID)
+		case <-timeout:
+			t.Fatalf("dispatched %v, want %v", got, ids)
+		}
+	}
+	want := append([]string{}, ids...)
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("dispatched %v, want %v", got, want)
+	}
+
+	select {
+	case job := <-dispatched:
+		t.Fatalf("dispatched %s as well, want only %v", job.ID, ids)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func queuedIDs(s *Scheduler) []string {
+	jobs, _ := s.Queued()
+	ids := make([]string, len(jobs))
+	for i, job := range jobs {
+		ids[i] = job.ID
+	}
+	return ids
+}
+
+func TestSchedulerPriority(t *testing.T) {
+	s, dispatched := newTestScheduler(t, map[string]string{"SCAN_MAX_CONCURRENT": "1"})
+
+	enqueue(t, s, true, schedulerJob("running", "payments", "feature/a", false))
+	enqueue(t, s, false,
+		schedulerJob("normal", "payments", "feature/b", false),
+		schedulerJob("default", "payments", "main", false),
+		schedulerJob("fast", "payments", "feature/c", true),
+	)
+
+	// Later submissions of a better class overtake the earlier ones
+	if got, want := queuedIDs(s), []string{"fast", "default", "normal"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("queue = %v, want %v", got, want)
+	}
+	status, ok := s.Position("normal")
+	want := QueueStatus{Position: 3, Length: 3, Priority: PriorityNormal, Running: 1, Limit: 1}
+	if !ok || status != want {
+		t.Fatalf("Position(normal) = %+v, %t; want %+v", status, ok, want)
+	}
+	if _, ok := s.Position("running"); ok {
+		t.Fatalf("Position(running) found a job that already started")
+	}
+
+	for _, step := range []struct{ release, next string }{
+		{release: "running", next: "fast"},
+		{release: "fast", next: "default"},
+		{release: "default", next: "normal"},
+	} {
+		s.Release(step.release)
+		expectDispatched(t, dispatched, step.next)
+	}
+}
+
+func TestSchedulerClassify(t *testing.T) {
+	s, _ := newTestScheduler(t, map[string]string{"SCAN_DEFAULT_BRANCHES": "develop, release"})
+
+	tests := []struct {
+		fast   bool
+		branch string
+		want   PriorityClass
+	}{
+		{fast: true, branch: "feature/a", want: PriorityFast},
+		{fast: true, branch: "develop", want: PriorityFast},
+		{branch: "develop", want: PriorityDefaultBranch},
+		{branch: "release", want: PriorityDefaultBranch},
+		{branch: "main", want: PriorityNormal},
+	}
+	for _, tt := range tests {
+		if got := s.Classify(tt.fast, tt.branch); got != tt.want {
+			t.Errorf("Classify(%t, %q) = %s, want %s", tt.fast, tt.branch, got, tt.want)
+		}
+	}
+}
+
+func TestSchedulerFairness(t *testing.T) {
+	t.Run("fewest running first", func(t *testing.T) {
+		s, dispatched := newTestScheduler(t, map[string]string{"SCAN_MAX_CONCURRENT": "3"})
+
+		enqueue(t, s, true,
+			schedulerJob("pay-1", "payments", "feature", false),
+			schedulerJob("pay-2", "payments", "feature", false),
+			schedulerJob("pay-3", "payments", "feature", false),
+		)
+		enqueue(t, s, false,
+			schedulerJob("pay-4", "payments", "feature", false),
+			schedulerJob("bill-1", "billing", "feature", false),
+		)
+		if got, want := queuedIDs(s), []string{"bill-1", "pay-4"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("queue = %v, want %v", got, want)
+		}
+
+		s.Release("pay-1")
+		expectDispatched(t, dispatched, "bill-1")
+	})
+
+	t.Run("least recently served first", func(t *testing.T) {
+		s, dispatched := newTestScheduler(t, map[string]string{"SCAN_MAX_CONCURRENT": "1"})
+
+		enqueue(t, s, true, schedulerJob("pay-1", "payments", "feature", false))
+		enqueue(t, s, false,
+			schedulerJob("pay-2", "payments", "feature", false),
+			schedulerJob("pay-3", "payments", "feature", false),
+			schedulerJob("bill-1", "billing", "feature", false),
+		)
+
+		// Once pay-1 finishes neither application has a scan running, and billing waited longest
+		for _, step := range []struct{ release, next string }{
+			{release: "pay-1", next: "bill-1"},
+			{release: "bill-1", next: "pay-2"},
+			{release: "pay-2", next: "pay-3"},
+		} {
+			s.Release(step.release)
+			expectDispatched(t, dispatched, step.next)
+		}
+	})
+}
+
+func TestSchedulerCaps(t *testing.T) {
+	s, dispatched := newTestScheduler(t, map[string]string{
+		"SCAN_MAX_CONCURRENT":         "3",
+		"SCAN_MAX_CONCURRENT_PER_APP": "1",
+		"SCAN_APP_CONCURRENCY":        `{"payments": 2}`,
+	})
+
+	enqueue(t, s, true, schedulerJob("pay-1", "payments", "feature", false), schedulerJob("pay-2", "payments", "feature", false))
+	// payments is at its own cap
+	enqueue(t, s, false, schedulerJob("pay-3", "payments", "feature", false))
+	enqueue(t, s, true, schedulerJob("bill-1", "billing", "feature", false))
+	// billing is at the default cap
+	enqueue(t, s, false, schedulerJob("bill-2", "billing", "feature", false))
+	// Every slot is taken
+	enqueue(t, s, false, schedulerJob("rep-1", "reports", "feature", false))
+
+	// The expected order ignores the caps, which only delay a job
+	if got, want := queuedIDs(s), []string{"rep-1", "bill-2", "pay-3"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("queue = %v, want %v", got, want)
+	}
+
+	for _, step := range []struct{ release, next string }{
+		// pay-3 is skipped while payments has two scans running
+		{release: "bill-1", next: "rep-1"},
+		{release: "pay-1", next: "bill-2"},
+		{release: "rep-1", next: "pay-3"},
+	} {
+		s.Release(step.release)
+		expectDispatched(t, dispatched, step.next)
+	}
+
+	// A scan resumed after a restart counts against the caps like one the scheduler started
+	s.Track(schedulerJob("rep-0", "reports", "feature", false))
+	for _, id := range []string{"pay-2", "bill-2", "pay-3"} {
+		s.Release(id)
+	}
+	enqueue(t, s, false, schedulerJob("rep-2", "reports", "feature", false))
+	s.Release("rep-0")
+	expectDispatched(t, dispatched, "rep-2")
+}
+
+func TestSchedulerMalformedSettings(t *testing.T) {
+	t.Setenv("SCAN_MAX_CONCURRENT", "1")
+	t.Setenv("SCAN_MAX_CONCURRENT_PER_APP", "")
+	t.Setenv("SCAN_APP_CONCURRENCY", `{"payments": "two"}`)
+	t.Setenv("SCAN_DEFAULT_BRANCHES", "")
+
+	s, err := NewScheduler(func(*ScanJob) {})
+	if err == nil {
+		t.Fatalf("NewScheduler() accepted a malformed SCAN_APP_CONCURRENCY")
+	}
+	// The other settings still apply
+	enqueue(t, s, true, schedulerJob("pay-1", "payments", "feature", false))
+	enqueue(t, s, false, schedulerJob("bill-1", "billing", "feature", false))
+}
+
+func TestResumeQueuedJobs(t *testing.T) {
+	t.Setenv("SCAN_MAX_CONCURRENT", "1")
+	t.Setenv("SCAN_MAX_CONCURRENT_PER_APP", "")
+	t.Setenv("SCAN_APP_CONCURRENCY", "")
+	t.Setenv("SCAN_DEFAULT_BRANCHES", "")
+	ss, fake := newTestService(t)
+
+	project, err := fake.CreateProject("payments-api", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := testZip(t)
+	uploadURL, err := fake.UploadStreamForProjectByID(project.ProjectID, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Jobs a previous run left queued in the database, oldest first
+	var ids []string
+	for _, job := range []*ScanJob{
+		schedulerJob("", "payments", "feature/a", false),
+		schedulerJob("", "payments", "main", false),
+		schedulerJob("", "payments", "feature/b", true),
+	} {
+		job.ProjectID = project.ProjectID
+		job.ProjectName = project.Name
+		job.CommitID = "commit-" + job.Branch
+		job.ScanTypes = []string{"sast"}
+		job.State = JobStateQueued
+		job.Dispatch = &ScanDispatch{
+			UploadURL:      uploadURL,
+			Configurations: []cx1.ScanConfiguration{{ScanType: "sast"}},
+			Tags:           map[string]string{"commit_id": job.CommitID},
+		}
+		if err := ss.jobs.Create(job); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, job.ID)
+		// CreatedAt orders the jobs on resume
+		time.Sleep(time.Millisecond)
+	}
+	oldest, defaultBranch, fast := ids[0], ids[1], ids[2]
+
+	if err := ss.ResumePendingJobs(); err != nil {
+		t.Fatalf("ResumePendingJobs() = %v", err)
+	}
+
+	// The oldest job takes the only slot, the others go back in line by priority
+	running := waitForJob(t, ss, oldest, JobStatePolling)
+	if got, want := queuedIDs(ss.scheduler), []string{fast, defaultBranch}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("queue = %v, want %v", got, want)
+	}
+
+	for _, next := range []string{fast, defaultBranch} {
+		if job, _ := ss.jobs.Get(next); job.State != JobStateQueued || job.Dispatch == nil {
+			t.Fatalf("job %s = %s, want it still queued with its dispatch", next, job.State)
+		}
+		fake.Complete(running.ScanID)
+		waitForJob(t, ss, running.ID, JobStateWebhookDelivered, JobStateFailed)
+		running = waitForJob(t, ss, next, JobStatePolling)
+	}
+}
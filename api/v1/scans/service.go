@@ -1,788 +1,1173 @@
-// api/v1/scans/service.go
-package scans
-
-import (
-	"bytes"
-	"context"
-	"crypto/tls"
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"os"
-	"time"
-
-	cx1 "github.com/madhatkul/CxWrapper-v2/Cx1ClientGo"
-	"github.com/madhatkul/CxWrapper-v2/util"
-)
-
-type ScanService struct {
-	cx1Client *cx1.Cx1Client
-	logger    util.Logger
-}
-
-func NewScanService(client *cx1.Cx1Client, logger util.Logger) *ScanService {
-	return &ScanService{
-		cx1Client: client,
-		logger:    logger,
-	}
-}
-
-// Updated StartStaticScanWithFile to use client-provided configurations
-func (ss *ScanService) StartStaticScanWithFile(req StaticScanRequestWithFile) (*cx1.Scan, error) {
-	ss.logger.Infof("Starting static scan for project: %s on branch: %s", req.ProjectName, req.Branch)
-
-	// Validate input
-	if req.AppName == "" {
-		return nil, fmt.Errorf("application name is required")
-	}
-	if req.ProjectName == "" {
-		return nil, fmt.Errorf("project name is required")
-	}
-	if req.Branch == "" {
-		return nil, fmt.Errorf("branch is required")
-	}
-	if req.CommitID == "" {
-		return nil, fmt.Errorf("commit ID is required")
-	}
-	if req.FileSize == 0 {
-		return nil, fmt.Errorf("file contents are empty")
-	}
-
-	if req.Preset == "" {
-		return nil, fmt.Errorf("preset is required")
-	}
-
-	var project cx1.Project
-
-	// Get project by name
-	projects, err := ss.cx1Client.GetProjectsByName(req.ProjectName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get project '%s': %v", req.ProjectName, err)
-	}
-	if len(projects) == 0 {
-		ss.logger.Infof("Project '%s' not found, creating a new one.", req.ProjectName)
-
-		newProject, err := ss.cx1Client.CreateProject(req.ProjectName, []string{}, make(map[string]string))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create new project '%s': %v", req.ProjectName, err)
-		}
-		project = newProject
-		ss.logger.Infof("✅ New project created with ID: %s", project.ProjectID)
-	} else {
-		project = projects[0] // ใช้โปรเจกต์แรกที่เจอ
-		ss.logger.Infof("✅ Project found with ID: %s", project.ProjectID)
-	}
-
-	projectID := project.ProjectID
-
-	err = ss.AssignProjectToApp(req.AppName, project.Name)
-	if err != nil {
-		ss.logger.Errorf("Failed to assign project to application: %v", err)
-		return nil, fmt.Errorf("failed to assign project to application: %v", err)
-	}
-
-	// Upload file contents
-	uploadURL, err := ss.cx1Client.UploadStreamForProjectByID(projectID, req.File, req.FileSize)
-	if err != nil {
-		return nil, fmt.Errorf("failed to upload file to project %s: %v", projectID, err)
-	}
-
-	ss.logger.Infof("✅ File uploaded successfully, URL: %s File Size: %s", uploadURL, (req.FileSize))
-
-	// Convert client configurations to cx1.ScanConfigurationSet
-	defaultSettings, err := ss.cx1Client.GetScanConfigurationByProjectID(projectID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get default scan configuration for project %s: %v", projectID, err)
-	}
-	ss.logger.Infof("✅ Retrieved %d default configuration settings for project", len(defaultSettings))
-
-	// 2. Filter configurations based on the requested scan types
-	configMap := make(map[string]map[string]string)
-	requiredCategories := make(map[string]bool)
-	for _, scanType := range req.ScanTypes {
-		requiredCategories[scanType] = true
-	}
-
-	for _, setting := range defaultSettings {
-		if requiredCategories[setting.Category] {
-			if _, ok := configMap[setting.Category]; !ok {
-				configMap[setting.Category] = make(map[string]string)
-			}
-
-			if setting.Value != "" {
-				configMap[setting.Category][setting.Name] = setting.Value
-			}
-		}
-	}
-
-	ss.logger.Infof("configMap: %v", configMap)
-
-	for _, scanType := range req.ScanTypes {
-		if scanType == "microengines" {
-			configMap["microengines"] = map[string]string{
-				"scorecard": "true",
-				"2ms":       "true",
-			}
-		}
-	}
-
-	// 3. If is_fast_scan is true, override the SAST configuration
-	if req.IsFastScan {
-		ss.logger.Infof("⚡ Fast scan requested. Overriding SAST configuration.")
-		if _, ok := configMap["sast"]; !ok {
-			configMap["sast"] = make(map[string]string)
-		}
-		// The key "fast scan mode" corresponds to the 'name' field from the get configuration response
-		configMap["sast"]["fastScanMode"] = "true"
-	}
-
-	if req.Preset != "" {
-		ss.logger.Infof("🎯 Applying preset: %s", req.Preset)
-
-		if _, ok := configMap["sast"]; !ok {
-			configMap["sast"] = make(map[string]string)
-		}
-		configMap["sast"]["presetName"] = req.Preset
-
-		err := ss.cx1Client.UpdateProjectConfigurationByID(projectID, []cx1.ConfigurationSetting{
-			{ // Added the struct type here
-				Key:             "scan.config.sast.presetName",
-				Name:            "presetName",
-				Category:        "sast",
-				OriginLevel:     "Project",
-				Value:           req.Preset,
-				ValueType:       "RESTList",
-				ValueTypeParams: "{\"path\":\"/queries/presets\",\"fieldMap\":{\"id\":\"id\",\"value\":\"name\",\"label\":\"name\"}}",
-				AllowOverride:   true,
-			},
-		})
-		if err != nil {
-			ss.logger.Errorf("Failed to update project configuration: %v", err)
-		}
-	}
-	var finalScanConfigurations []cx1.ScanConfiguration
-	for category, values := range configMap {
-		finalScanConfigurations = append(finalScanConfigurations, cx1.ScanConfiguration{
-			ScanType: category,
-			Values:   values,
-		})
-	}
-
-	configJSON, _ := json.Marshal(finalScanConfigurations)
-	ss.logger.Infof("Prepared %d scan configurations. Details: %s", len(finalScanConfigurations), string(configJSON))
-
-	// Prepare tags
-	tags := req.Tags
-	if tags == nil {
-		tags = make(map[string]string)
-	}
-
-	tags["commit_id"] = req.CommitID
-
-	// Trigger scan
-	scan, err := ss.cx1Client.ScanProjectZipByID(projectID, uploadURL, req.Branch, finalScanConfigurations, tags)
-	if err != nil {
-		return nil, fmt.Errorf("failed to trigger scan for project %s: %v", projectID, err)
-	}
-
-	// Polling
-	go ss.PollingStatus(&scan)
-
-	ss.logger.Infof("✅ Scan triggered successfully with ID: %s for project ID: %s", scan.ScanID, projectID)
-
-	return &scan, nil
-}
-
-func (ss *ScanService) PollingStatus(scan *cx1.Scan) {
-
-	ss.logger.Infof("🔄 Polling status for scan ID: %s", scan.ScanID)
-
-	ss.logger.Infof("🔄 Starting scan polling process")
-
-	updatedScan, err := ss.cx1Client.ScanPolling(scan)
-	if err != nil {
-		ss.logger.Errorf("❌ Error during scan polling: %v", err)
-		return
-	}
-
-	ss.logger.Infof("✅ Scan polling completed successfully for scan ID: %s with status: %s", updatedScan.ScanID, updatedScan.Status)
-
-	response, err := ss.GetScanResultsByScanID(updatedScan.ScanID)
-	if err != nil {
-		ss.logger.Errorf("❌ Error getting scan results for scan ID %s: %v", updatedScan.ScanID, err)
-		return
-	}
-
-	ss.logger.Infof("✅ Scan results retrieved successfully for scan ID: %s", updatedScan.ScanID)
-
-	// Log the actual response content (be careful with size)
-	if response != nil {
-		ss.logger.Debugf("📋 Full scan response details: %+v", response)
-	}
-
-	// Webhook section (currently commented out)
-	webhookURL := os.Getenv("STATIC_WEBHOOK_URL")
-	if err := ss.sendWebhook(webhookURL, &updatedScan); err != nil {
-		ss.logger.Errorf("❌ Failed to send webhook: %v", err)
-	} else {
-		ss.logger.Infof("✅ Webhook sent successfully")
-	}
-}
-
-func (ss *ScanService) sendWebhook(webhookURL string, scan *cx1.Scan) (err error) {
-	// Create payload
-	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("a panic occurred while sending webhook: %v", r)
-		}
-	}()
-
-	// Construct the ScanResultResponse, similar to GetAllScanResultsByCommitID but for a single scan.
-	resultsLink := fmt.Sprintf("https://sng.ast.checkmarx.net/projects/%s/scans?branch=%s&id=%s",
-		scan.ProjectID, scan.Branch, scan.ScanID)
-
-	scanResponse := &ScanResultResponse{
-		Link:      resultsLink,
-		ScanID:    scan.ScanID,
-		CommitID:  scan.Tags["commit_id"], // Assumes commit_id is in tags
-		ProjectID: scan.ProjectID,
-		Branch:    scan.Branch,
-		Status:    scan.Status,
-		CreatedAt: scan.CreatedAt,
-		UpdatedAt: scan.UpdatedAt,
-		Tags:      scan.Tags,
-	}
-
-	if scan.Status == "Completed" {
-		results, err := ss.cx1Client.GetAllScanResultsByID(scan.ScanID)
-		if err != nil {
-			ss.logger.Errorf("Failed to get results for scan ID %s: %v", scan.ScanID, err)
-			errorMsg := fmt.Sprintf("Failed to get results: %v", err)
-			scanResponse.Error = &errorMsg
-		} else {
-			scanResponse.Results = results
-			scanResponse.Summary = Summary{TotalResults: int(results.Count())}
-			ss.logger.Debugf("Retrieved %d results for scan ID %s", results.Count(), scan.ScanID)
-		}
-
-		config, err := ss.cx1Client.GetScanConfigurationByID(scan.ProjectID, scan.ScanID)
-		if err != nil {
-			ss.logger.Warnf("Failed to get scan configuration for scan ID %s: %v. Assuming full scan.", scan.ScanID, err)
-			scanResponse.IsFastScan = false
-		} else {
-			scanResponse.IsFastScan = ss.isFastScanMode(config)
-		}
-
-		breakbuild, policyErr := ss.cx1Client.RetrievePolicyViolationInfo(scan.ProjectID, scan.ScanID)
-		if policyErr != nil {
-			ss.logger.Warnf("Failed to retrieve policy violation info for scan ID %s: %v. Continuing without breakbuild status.", scan.ScanID, policyErr)
-			warning := fmt.Sprintf("Policy violation info unavailable: %v", policyErr)
-			scanResponse.PolicyWarning = &warning
-			scanResponse.BreakBuild = false
-		} else {
-			scanResponse.BreakBuild = breakbuild
-		}
-	} else {
-		scanResponse.IsFastScan = false
-		scanResponse.BreakBuild = false
-		statusMsg := fmt.Sprintf("Scan not completed (status: %s)", scan.Status)
-		scanResponse.StatusMessage = &statusMsg
-	}
-
-	// Marshal payload to JSON
-	jsonData, err := json.Marshal(scanResponse)
-	if err != nil {
-		return fmt.Errorf("failed to marshal webhook payload: %w", err)
-	}
-
-	// Create HTTP request
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create webhook request: %w", err)
-	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "CX1-ScanService/1.0")
-
-	// Send request
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
-			// This single line tells the client to use HTTP_PROXY, HTTPS_PROXY,
-			// and NO_PROXY from your environment variables.
-			Proxy: http.ProxyFromEnvironment,
-
-			// A default TLSClientConfig is secure and enforces certificate validation.
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-		},
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send webhook request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check response status
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("webhook returned non-success status: %d", resp.StatusCode)
-	}
-
-	return nil
-}
-
-func (ss *ScanService) GetScanResultsByScanID(scanID string) (interface{}, error) {
-
-	// Get filtered scans
-	scan, err := ss.cx1Client.GetScanByID(scanID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get scans: %v", err)
-	}
-
-	ss.logger.Debugf("Found scan ID: %s with status: %s", scan.ScanID, scan.Status)
-
-	// Check if scan is completed
-	if scan.Status != "Completed" {
-		return nil, fmt.Errorf("scan is not completed yet (current status: %s). Scan ID: %s", scan.Status, scan.ScanID)
-	}
-
-	// Get detailed results using the actual scan ID
-	results, err := ss.cx1Client.GetAllScanResultsByID(scan.ScanID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get results for scan %s: %v", scan.ScanID, err)
-	}
-
-	ss.logger.Infof("Successfully retrieved scan results for scan ID: %s with %d results", scan.ScanID, results.Count())
-
-	// Create a structured response with scan metadata and results
-	resultsLink := fmt.Sprintf("https://sng.ast.checkmarx.net/projects/%s/scans?branch=%s&id=%s",
-		scan.ProjectID, scan.Branch, scan.ScanID)
-
-	var breakbuild bool
-	var policyErr error
-
-	breakbuild, policyErr = ss.cx1Client.RetrievePolicyViolationInfo(scan.ProjectID, scan.ScanID)
-	if policyErr != nil {
-
-		ss.logger.Warnf("Failed to retrieve policy violation info for scan ID %s: %v. Continuing without breakbuild status.", scan.ScanID, policyErr)
-
-		// Set breakbuild to false as default when policy info is unavailable
-		breakbuild = true
-	}
-
-	response := &ScanResultResponse{
-		Link:       resultsLink,
-		BreakBuild: breakbuild,
-		ScanID:     scan.ScanID,
-		ProjectID:  scan.ProjectID,
-		Branch:     scan.Branch,
-		Status:     scan.Status,
-		CreatedAt:  scan.CreatedAt,
-		UpdatedAt:  scan.UpdatedAt,
-		Tags:       scan.Tags,
-		Results:    results,
-		Summary: Summary{
-			TotalResults: int(results.Count()),
-		},
-	}
-
-	if policyErr != nil {
-		warning := fmt.Sprintf("Policy violation info unavailable: %v", policyErr)
-		response.PolicyWarning = &warning
-	}
-
-	return response, nil
-}
-
-func (ss *ScanService) GetAllScanResultsByCommitID(commitID string, projectName string) (interface{}, error) {
-	// Create filter to find scans by commit_id
-	filter := cx1.ScanFilter{}
-
-	// Add commit_id filter
-	filter.TagKeys = append(filter.TagKeys, "commit_id")
-	filter.TagValues = append(filter.TagValues, commitID)
-
-	// Optionally filter by project if provided
-	if projectName != "" {
-		projects, err := ss.cx1Client.GetProjectsByName(projectName)
-		if err != nil {
-			return nil, fmt.Errorf("failed to find project '%s': %v", projectName, err)
-		}
-		if len(projects) == 0 {
-			return nil, fmt.Errorf("project '%s' not found", projectName)
-		}
-		filter.ProjectID = projects[0].ProjectID
-		ss.logger.Debugf("Filtering by project '%s' (ID: %s) and commit_id '%s' for results", projectName, filter.ProjectID, commitID)
-	} else {
-		ss.logger.Debugf("Filtering by commit_id '%s' only for results", commitID)
-	}
-
-	// Get filtered scans (assuming they are sorted newest to oldest)
-	scans, err := ss.cx1Client.GetLastScansFiltered(filter)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get scans: %v", err)
-	}
-
-	if len(scans) == 0 {
-		return nil, fmt.Errorf("no scans found for commit_id: %s", commitID)
-	}
-
-	// Pointers to hold the latest fast and full scans found.
-	var latestFastScan *ScanResultResponse
-	var latestFullScan *ScanResultResponse
-
-	// Process scans to find the latest of each type
-	for _, scan := range scans {
-		// Optimization: if we've found both, we can stop processing.
-		if latestFastScan != nil && latestFullScan != nil {
-			break
-		}
-
-		ss.logger.Debugf("Processing scan ID: %s with status: %s for commit_id: %s", scan.ScanID, scan.Status, commitID)
-
-		// Initialize scan response
-		resultsLink := fmt.Sprintf("https://sng.ast.checkmarx.net/projects/%s/scans?branch=%s&id=%s",
-			scan.ProjectID, scan.Branch, scan.ScanID)
-
-		scanResponse := ScanResultResponse{
-			Link:      resultsLink,
-			ScanID:    scan.ScanID,
-			CommitID:  commitID,
-			ProjectID: scan.ProjectID,
-			Branch:    scan.Branch,
-			Status:    scan.Status,
-			CreatedAt: scan.CreatedAt,
-			UpdatedAt: scan.UpdatedAt,
-			Tags:      scan.Tags,
-		}
-
-		// Only get detailed results for completed scans
-		if scan.Status == "Completed" {
-			results, err := ss.cx1Client.GetAllScanResultsByID(scan.ScanID)
-			if err != nil {
-				ss.logger.Errorf("Failed to get results for scan ID %s: %v", scan.ScanID, err)
-				errorMsg := fmt.Sprintf("Failed to get results: %v", err)
-				scanResponse.Error = &errorMsg
-			} else {
-				scanResponse.Results = results
-				scanResponse.Summary = Summary{TotalResults: int(results.Count())}
-				ss.logger.Debugf("Retrieved %d results for scan ID %s", results.Count(), scan.ScanID)
-			}
-
-			// Determine if the scan is a fast scan
-			config, err := ss.cx1Client.GetScanConfigurationByID(scan.ProjectID, scan.ScanID)
-			if err != nil {
-				ss.logger.Warnf("Failed to get scan configuration for scan ID %s: %v. Assuming full scan.", scan.ScanID, err)
-				scanResponse.IsFastScan = false
-			} else {
-				scanResponse.IsFastScan = ss.isFastScanMode(config)
-			}
-
-			ss.logger.Infof("Is fast scan: ", config)
-
-			// Get policy violation info
-			breakbuild, policyErr := ss.cx1Client.RetrievePolicyViolationInfo(scan.ProjectID, scan.ScanID)
-			if policyErr != nil {
-				ss.logger.Warnf("Failed to retrieve policy violation info for scan ID %s: %v. Continuing without breakbuild status.", scan.ScanID, policyErr)
-				warning := fmt.Sprintf("Policy violation info unavailable: %v", policyErr)
-				scanResponse.PolicyWarning = &warning
-				scanResponse.BreakBuild = false
-			} else {
-				scanResponse.BreakBuild = breakbuild
-			}
-		} else {
-			// For non-completed scans, set defaults
-			scanResponse.IsFastScan = false
-			scanResponse.BreakBuild = false
-			statusMsg := fmt.Sprintf("Scan not completed (status: %s)", scan.Status)
-			scanResponse.StatusMessage = &statusMsg
-		}
-
-		// Assign the processed scan to the correct category if it's the first one we've found
-		if scanResponse.IsFastScan {
-			if latestFastScan == nil {
-				// Create a new variable to hold the response and assign its address
-				s := scanResponse
-				latestFastScan = &s
-			}
-		} else { // It's a full scan
-			if latestFullScan == nil {
-				s := scanResponse
-				latestFullScan = &s
-			}
-		}
-	}
-
-	// Create the final response object with the categorized scans
-	response := &AllScansResponse{
-		CommitID:    commitID,
-		ProjectName: projectName,
-		Scans: CategorizedScans{
-			Fast: latestFastScan,
-			Full: latestFullScan,
-		},
-	}
-
-	// Add summary counts based on the found scans
-	completedScans := 0
-	breakBuildCount := 0
-	totalScansInResponse := 0
-
-	if latestFastScan != nil {
-		totalScansInResponse++
-		if latestFastScan.Status == "Completed" {
-			completedScans++
-			if latestFastScan.BreakBuild {
-				breakBuildCount++
-			}
-		}
-	}
-	if latestFullScan != nil {
-		totalScansInResponse++
-		if latestFullScan.Status == "Completed" {
-			completedScans++
-			if latestFullScan.BreakBuild {
-				breakBuildCount++
-			}
-		}
-	}
-
-	response.TotalScans = totalScansInResponse
-	response.Summary = AllScansSummary{
-		CompletedScans:  completedScans,
-		BreakBuildCount: breakBuildCount,
-	}
-
-	ss.logger.Infof("✅ Successfully processed and categorized latest scans for commit_id: %s. Total scans found: %d, Completed: %d, BreakBuild: %d", commitID, totalScansInResponse, completedScans, breakBuildCount)
-
-	return response, nil
-}
-
-func (ss *ScanService) isFastScanMode(config interface{}) bool {
-	// The config is a slice of a specific struct type, not a generic interface.
-	// We perform a type assertion to the correct type from the cx1 library.
-	if configSlice, ok := config.([]cx1.ConfigurationSetting); ok {
-		for _, setting := range configSlice {
-			// Access the struct's 'Key' field directly.
-			if setting.Key == "scan.config.sast.fastScanMode" {
-
-				ss.logger.Infof("Found fastScanMode setting, checking value: %s (type: %T)", setting.Value, setting.Value)
-
-				// Access the 'Value' field and check its type (string or bool).
-				// setting.Value is of type string, so compare directly
-				return setting.Value == "true"
-			}
-		}
-	} else {
-		// Add a log to show if the initial type assertion fails.
-		ss.logger.Warnf("isFastScanMode received an unexpected config type: %T", config)
-	}
-
-	// Default to false if not found or if the type assertion fails.
-	return false
-}
-
-func (ss *ScanService) ListScansFiltered(req ListScansRequest) (*ListScansResponse, error) {
-	// Create ScanFilter based on the request
-	filter := cx1.ScanFilter{}
-
-	// Handle project_name - need to resolve to project_id
-	if req.ProjectName != "" {
-		projects, err := ss.cx1Client.GetProjectsByName(req.ProjectName)
-		if err != nil {
-			return nil, fmt.Errorf("failed to find project '%s': %v", req.ProjectName, err)
-		}
-		if len(projects) == 0 {
-			return nil, fmt.Errorf("project '%s' not found", req.ProjectName)
-		}
-		// Use the first matching project
-		filter.ProjectID = projects[0].ProjectID
-
-		ss.logger.Debugf("Resolved project name '%s' to ID '%s'", req.ProjectName, filter.ProjectID)
-	}
-
-	// Handle commit_id - add it to tag values for filtering
-	if req.CommitID != "" {
-		filter.TagKeys = append(filter.TagKeys, "commit_id")
-		filter.TagValues = append(filter.TagValues, req.CommitID)
-		ss.logger.Debugf("Added commit_id filter: %s", req.CommitID)
-	}
-
-	// Get filtered scans using the cx1Client method
-	scans, err := ss.cx1Client.GetLastScansFiltered(filter)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get filtered scans: %v", err)
-	}
-
-	ss.logger.Debugf("Retrieved filtered scans, total count: %d", len(scans))
-
-	// Apply client-side pagination since the API might not support it directly
-	total := len(scans)
-	start := req.Offset
-	end := req.Offset + req.Limit
-
-	// Validate pagination bounds
-	if start > total {
-		start = total
-	}
-	if end > total {
-		end = total
-	}
-
-	var paginatedScans []cx1.Scan
-	if start < total {
-		paginatedScans = scans[start:end]
-	}
-
-	return &ListScansResponse{
-		Scans:  paginatedScans,
-		Total:  total,
-		Limit:  req.Limit,
-		Offset: req.Offset,
-	}, nil
-}
-
-// GetScanStatusByCommitID gets scan status by commit_id, optionally filtered by project_name
-func (ss *ScanService) GetScanStatusByCommitID(commitID string, projectName string) (*SimpleScanStatus, error) {
-	// Create filter to find scans by commit_id
-	filter := cx1.ScanFilter{}
-
-	// Add commit_id filter
-	filter.TagKeys = append(filter.TagKeys, "commit_id")
-	filter.TagValues = append(filter.TagValues, commitID)
-
-	// Optionally filter by project if provided
-	if projectName != "" {
-		projects, err := ss.cx1Client.GetProjectsByName(projectName)
-		if err != nil {
-			return nil, fmt.Errorf("failed to find project '%s': %v", projectName, err)
-		}
-		if len(projects) == 0 {
-			return nil, fmt.Errorf("project '%s' not found", projectName)
-		}
-		filter.ProjectID = projects[0].ProjectID
-
-		ss.logger.Debugf("Filtering by project '%s' (ID: %s) and commit_id '%s'", projectName, filter.ProjectID, commitID)
-	} else {
-		ss.logger.Debugf("Filtering by commit_id '%s' only", commitID)
-	}
-
-	// Get filtered scans
-	scans, err := ss.cx1Client.GetLastScansFiltered(filter)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get scans: %v", err)
-	}
-
-	if len(scans) == 0 {
-		return nil, fmt.Errorf("no scans found for commit_id: %s", commitID)
-	}
-
-	// Get the most recent scan (first one since GetLastScansFiltered sorts by created_at desc)
-	scan := scans[0]
-
-	return &SimpleScanStatus{
-		ScanID: scan.ScanID,
-		Status: scan.Status,
-	}, nil
-}
-
-func (ss *ScanService) CancelScan(commitID string, projectName string) error {
-	filter := cx1.ScanFilter{}
-
-	// Add commit_id filter
-	filter.TagKeys = append(filter.TagKeys, "commit_id")
-	filter.TagValues = append(filter.TagValues, commitID)
-
-	// Add project_name filter if provided
-	if projectName != "" {
-		filter.TagKeys = append(filter.TagKeys, "project_name")
-		filter.TagValues = append(filter.TagValues, projectName)
-	}
-
-	scans, err := ss.cx1Client.GetLastScansFiltered(filter)
-	if err != nil {
-		return fmt.Errorf("failed to get scans: %v", err)
-	}
-
-	if len(scans) == 0 {
-		if projectName != "" {
-			return fmt.Errorf("no scans found for commit_id: %s and project_name: %s", commitID, projectName)
-		}
-		return fmt.Errorf("no scans found for commit_id: %s", commitID)
-	}
-
-	// Get the most recent scan (first one since GetLastScansFiltered sorts by created_at desc)
-	scan := scans[0]
-
-	err = ss.cx1Client.CancelScanByID(scan.ScanID)
-	if err != nil {
-		return fmt.Errorf("failed to cancel scan: %v", err)
-	}
-
-	return nil
-}
-
-// func (ss *ScanService) TempGetConfig(project_Id string) ([]cx1.ConfigurationSetting, error) {
-//  config, err := ss.cx1Client.GetScanConfigurationByProjectID(project_Id)
-//  if err != nil {
-//      return nil, fmt.Errorf("failed to get config: %v", err)
-//  }
-
-//  return config, nil
-// }
-
-func (s *ScanService) AssignProjectToApp(appName string, projectName string) error {
-	// Get application by name.
-	application, err := s.cx1Client.GetApplicationByName(appName)
-	if err != nil {
-		s.logger.Infof("Application '%s' not found, creating a new one.", appName)
-		// Create the application if it doesn't exist.
-		newApplication, createErr := s.cx1Client.CreateApplication(appName)
-		if createErr != nil {
-			s.logger.Errorf("Error creating application '%s': %v", appName, createErr)
-			return createErr
-		}
-		application = newApplication
-	} else {
-		s.logger.Infof("Application fetched: %v", application.Name)
-	}
-
-	// Get project by name.
-	projects, err := s.cx1Client.GetProjectsByName(projectName)
-	if err != nil {
-		return fmt.Errorf("failed to get project '%s': %v", projectName, err)
-	}
-
-	var project cx1.Project
-	if len(projects) == 0 {
-		s.logger.Infof("Project '%s' not found, creating a new one.", projectName)
-		// Create the project if it doesn't exist.
-		newProject, createErr := s.cx1Client.CreateProject(projectName, []string{}, make(map[string]string))
-		if createErr != nil {
-			return fmt.Errorf("failed to create new project '%s': %v", projectName, createErr)
-		}
-		project = newProject
-		s.logger.Infof("✅ New project created with ID: %s", project.ProjectID)
-	} else {
-		project = projects[0] // Use the first project found.
-		s.logger.Infof("✅ Project found with ID: %s", project.ProjectID)
-	}
-
-	// Assign the project to the application.
-	application.AssignProject(&project)
-
-	// Update the application to save the changes.
-	if err := s.cx1Client.UpdateApplication(&application); err != nil {
-		s.logger.Errorf("Error updating application '%s': %v", appName, err)
-		return err
-	}
-
-	s.logger.Infof("✅ Successfully assigned project '%s' to application '%s'", projectName, appName)
-	return nil
-}
+// api/v1/scans/service.go
+package scans
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	cx1 "github.com/madhatkul/CxWrapper-v2/Cx1ClientGo"
+	"github.com/madhatkul/CxWrapper-v2/api/archive"
+	"github.com/madhatkul/CxWrapper-v2/api/cxclient"
+	"github.com/madhatkul/CxWrapper-v2/api/gitsource"
+	"github.com/madhatkul/CxWrapper-v2/api/v1/uploads"
+	"github.com/madhatkul/CxWrapper-v2/api/v1/webhooks"
+	"github.com/madhatkul/CxWrapper-v2/util"
+)
+
+type ScanService struct {
+	cx1Client      cxclient.Client
+	jobs           *JobStore
+	webhookService *webhooks.WebhookService
+	uploads        *uploads.UploadService
+	archiveRules   *archive.Rules
+	gitSource      *gitsource.Fetcher
+	scheduler      *Scheduler
+	// supersedePolicy decides which projects cancel older scans of a branch when a newer one is submitted
+	supersedePolicy *SupersedePolicy
+	pollInterval    time.Duration
+	// jobRetention is how long jobs are kept once they reached a final state
+	jobRetention time.Duration
+	broker       *StatusBroker
+	logger       util.Logger
+
+	// watchMu makes starting a commit watcher atomic, so concurrent subscribers start one between them
+	watchMu sync.Mutex
+
+	// resultCalls shares the results fetch of a commit between waiters that finish together
+	resultsMu   sync.Mutex
+	resultCalls map[string]*resultCall
+	// submitting holds the fingerprints of scan submissions in progress, see beginSubmission
+	submitMu   sync.Mutex
+	submitting map[string]chan struct{}
+}
+
+func NewScanService(client cxclient.Client, jobs *JobStore, webhookService *webhooks.WebhookService, uploadService *uploads.UploadService, logger util.Logger) *ScanService {
+	archiveRules, err := archive.RulesFromEnv()
+	if err != nil {
+		logger.Errorf("❌ Ignoring malformed archive rules: %v", err)
+	}
+	// Git remotes have their own allowlists, so reaching an internal git server does not open webhooks up to it
+	gitDestinations, err := webhooks.DestinationPolicyFromEnv("GIT_SOURCE")
+	if err != nil {
+		logger.Errorf("❌ Ignoring malformed git source destination settings: %v", err)
+		gitDestinations = &webhooks.DestinationPolicy{}
+	}
+
+	ss := &ScanService{
+		cx1Client:      client,
+		jobs:           jobs,
+		webhookService: webhookService,
+		uploads:        uploadService,
+		archiveRules:   archiveRules,
+		gitSource:      gitsource.NewFetcher(maxUploadSizeFromEnv(), gitDestinations),
+		pollInterval:   pollIntervalFromEnv(),
+		jobRetention:   jobRetentionFromEnv(),
+		broker:         NewStatusBroker(),
+		logger:         logger,
+		resultCalls:    make(map[string]*resultCall),
+		submitting:     make(map[string]chan struct{}),
+	}
+	webhookService.OnFinished(ss.handleDeliveryFinished)
+
+	scheduler, err := NewScheduler(ss.dispatchQueued)
+	if err != nil {
+		logger.Errorf("❌ Ignoring malformed scan concurrency settings: %v", err)
+	}
+	ss.scheduler = scheduler
+	ss.supersedePolicy = supersedePolicyFromEnv()
+
+	return ss
+}
+
+// Updated StartStaticScanWithFile to use client-provided configurations
+func (ss *ScanService) StartStaticScanWithFile(req StaticScanRequestWithFile) (submission *ScanSubmission, err error) {
+	ss.logger.Infof("Starting static scan for project: %s on branch: %s", req.ProjectName, req.Branch)
+
+	// Validate input
+	if req.AppName == "" {
+		return nil, fmt.Errorf("application name is required")
+	}
+	if req.ProjectName == "" {
+		return nil, fmt.Errorf("project name is required")
+	}
+	if req.Branch == "" {
+		return nil, fmt.Errorf("branch is required")
+	}
+	if req.CommitID == "" {
+		return nil, fmt.Errorf("commit ID is required")
+	}
+	if req.Source != nil {
+		if err := ss.gitSource.Validate(*req.Source); err != nil {
+			return nil, err
+		}
+	} else if req.FileSize == 0 {
+		return nil, fmt.Errorf("file contents are empty")
+	}
+
+	if req.Preset == "" {
+		return nil, fmt.Errorf("preset is required")
+	}
+
+	if req.IdempotencyKey != "" {
+		hash := requestHash(req)
+		// Assigned to the named err rather than declared, since the deferred release below checks it
+		var record *IdempotencyRecord
+		record, err = ss.jobs.ReserveIdempotencyKey(req.IdempotencyKey, hash)
+		if err != nil {
+			return nil, err
+		}
+		if record != nil {
+			return ss.replayIdempotencyKey(record, hash)
+		}
+
+		// Keep the key only if the request went through, so a failed request can be retried with it
+		defer func() {
+			if err != nil {
+				if releaseErr := ss.jobs.ReleaseIdempotencyKey(req.IdempotencyKey); releaseErr != nil {
+					ss.logger.Errorf("❌ Failed to release Idempotency-Key %s: %v", req.IdempotencyKey, releaseErr)
+				}
+				return
+			}
+			if completeErr := ss.jobs.CompleteIdempotencyKey(req.IdempotencyKey, submission.JobID); completeErr != nil {
+				ss.logger.Errorf("❌ Failed to record Idempotency-Key %s: %v", req.IdempotencyKey, completeErr)
+			}
+		}()
+	}
+
+	endSubmission := ss.beginSubmission(scanFingerprint(req.ProjectName, req.CommitID, req.IsFastScan, req.ScanTypes))
+	defer endSubmission()
+
+	if !req.Force {
+		existing, err := ss.findActiveDuplicate(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check for duplicate scans: %v", err)
+		}
+		if existing != nil {
+			ss.logger.Infof("♻️ Scan job %s is already %s for commit_id %s, not starting another", existing.ID, existing.State, req.CommitID)
+			submission := ss.submissionFor(existing)
+			submission.Duplicate = true
+			return submission, nil
+		}
+	}
+
+	// Fetch the commit only now that the request is known to start a scan
+	if req.Source != nil {
+		ss.logger.Infof("📥 Fetching commit %s from %s", req.Source.Commit, req.Source.RepoURL)
+		fetched, size, err := ss.gitSource.Archive(context.Background(), *req.Source)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(fetched.Name())
+		defer fetched.Close()
+		req.File, req.FileSize, req.FileName = fetched, size, req.Source.FileName()
+		ss.logger.Infof("✅ Commit %s archived: %d bytes", req.Source.Commit, size)
+	}
+
+	// Inspect the archive and strip what the scanners do not need before it leaves the wrapper
+	sanitized, report, err := ss.sanitizeArchive(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare archive: %w", err)
+	}
+	defer os.Remove(sanitized.Name())
+	defer sanitized.Close()
+	if report.StrippedFiles > 0 {
+		ss.logger.Infof("🧹 Stripped %d of %d files (%d bytes) from the archive, %d -> %d bytes", report.StrippedFiles, report.StrippedFiles+report.KeptFiles, report.StrippedBytes, report.OriginalSize, report.Size)
+	}
+
+	var project cx1.Project
+
+	// Get project by name
+	projects, err := ss.cx1Client.GetProjectsByName(req.ProjectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project '%s': %v", req.ProjectName, err)
+	}
+	if len(projects) == 0 {
+		ss.logger.Infof("Project '%s' not found, creating a new one.", req.ProjectName)
+
+		newProject, err := ss.cx1Client.CreateProject(req.ProjectName, []string{}, make(map[string]string))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create new project '%s': %v", req.ProjectName, err)
+		}
+		project = newProject
+		ss.logger.Infof("✅ New project created with ID: %s", project.ProjectID)
+	} else {
+		project = projects[0] // ใช้โปรเจกต์แรกที่เจอ
+		ss.logger.Infof("✅ Project found with ID: %s", project.ProjectID)
+	}
+
+	projectID := project.ProjectID
+
+	err = ss.AssignProjectToApp(req.AppName, project.Name)
+	if err != nil {
+		ss.logger.Errorf("Failed to assign project to application: %v", err)
+		return nil, fmt.Errorf("failed to assign project to application: %v", err)
+	}
+
+	// Upload file contents
+	uploadURL, err := ss.cx1Client.UploadStreamForProjectByID(projectID, sanitized, report.Size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload file to project %s: %w", projectID, err)
+	}
+
+	ss.logger.Infof("✅ File uploaded successfully, URL: %s File Size: %s", uploadURL, (req.FileSize))
+
+	// Convert client configurations to cx1.ScanConfigurationSet
+	defaultSettings, err := ss.cx1Client.GetScanConfigurationByProjectID(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default scan configuration for project %s: %v", projectID, err)
+	}
+	ss.logger.Infof("✅ Retrieved %d default configuration settings for project", len(defaultSettings))
+
+	// 2. Filter configurations based on the requested scan types
+	configMap := make(map[string]map[string]string)
+	requiredCategories := make(map[string]bool)
+	for _, scanType := range req.ScanTypes {
+		requiredCategories[scanType] = true
+	}
+
+	for _, setting := range defaultSettings {
+		if requiredCategories[setting.Category] {
+			if _, ok := configMap[setting.Category]; !ok {
+				configMap[setting.Category] = make(map[string]string)
+			}
+
+			if setting.Value != "" {
+				configMap[setting.Category][setting.Name] = setting.Value
+			}
+		}
+	}
+
+	ss.logger.Infof("configMap: %v", configMap)
+
+	for _, scanType := range req.ScanTypes {
+		if scanType == "microengines" {
+			configMap["microengines"] = map[string]string{
+				"scorecard": "true",
+				"2ms":       "true",
+			}
+		}
+	}
+
+	// 3. If is_fast_scan is true, override the SAST configuration
+	if req.IsFastScan {
+		ss.logger.Infof("⚡ Fast scan requested. Overriding SAST configuration.")
+		if _, ok := configMap["sast"]; !ok {
+			configMap["sast"] = make(map[string]string)
+		}
+		// The key "fast scan mode" corresponds to the 'name' field from the get configuration response
+		configMap["sast"]["fastScanMode"] = "true"
+	}
+
+	if req.Preset != "" {
+		ss.logger.Infof("🎯 Applying preset: %s", req.Preset)
+
+		if _, ok := configMap["sast"]; !ok {
+			configMap["sast"] = make(map[string]string)
+		}
+		configMap["sast"]["presetName"] = req.Preset
+
+		err := ss.cx1Client.UpdateProjectConfigurationByID(projectID, []cx1.ConfigurationSetting{
+			{ // Added the struct type here
+				Key:             "scan.config.sast.presetName",
+				Name:            "presetName",
+				Category:        "sast",
+				OriginLevel:     "Project",
+				Value:           req.Preset,
+				ValueType:       "RESTList",
+				ValueTypeParams: "{\"path\":\"/queries/presets\",\"fieldMap\":{\"id\":\"id\",\"value\":\"name\",\"label\":\"name\"}}",
+				AllowOverride:   true,
+			},
+		})
+		if err != nil {
+			ss.logger.Errorf("Failed to update project configuration: %v", err)
+		}
+	}
+	var finalScanConfigurations []cx1.ScanConfiguration
+	for category, values := range configMap {
+		finalScanConfigurations = append(finalScanConfigurations, cx1.ScanConfiguration{
+			ScanType: category,
+			Values:   values,
+		})
+	}
+
+	configJSON, _ := json.Marshal(finalScanConfigurations)
+	ss.logger.Infof("Prepared %d scan configurations. Details: %s", len(finalScanConfigurations), string(configJSON))
+
+	// Prepare tags
+	tags := req.Tags
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+
+	tags["commit_id"] = req.CommitID
+	if req.TargetBranch != "" {
+		tags["target_branch"] = req.TargetBranch
+	}
+
+	// Record the job before triggering it so it can be queued, and resumed after a restart
+	job := &ScanJob{
+		ProjectID:   projectID,
+		ProjectName: req.ProjectName,
+		AppName:     req.AppName,
+		Branch:      req.Branch,
+		CommitID:    req.CommitID,
+		IsFastScan:  req.IsFastScan,
+		ScanTypes:   req.ScanTypes,
+		State:       JobStateQueued,
+		Dispatch: &ScanDispatch{
+			UploadURL:      uploadURL,
+			Configurations: finalScanConfigurations,
+			Tags:           tags,
+		},
+	}
+	// A job that is not persisted could be neither tracked nor dispatched, so the submission fails here
+	if err := ss.jobs.Create(job); err != nil {
+		return nil, fmt.Errorf("failed to persist scan job for project %s: %w", req.ProjectName, err)
+	}
+
+	// Older scans are canceled before queueing so a queued one does not hold the place of its replacement
+	var superseded []SupersededScan
+	if ss.supersedePolicy.Enabled(req.ProjectName, req.CancelSuperseded) {
+		superseded = ss.supersedeOlder(job)
+	}
+
+	if !ss.scheduler.Enqueue(job) {
+		submission := ss.submissionFor(job)
+		submission.Archive = report
+		submission.Superseded = superseded
+		ss.logger.Infof("⏳ Scan for project %s queued at position %d of %d", req.ProjectName, submission.Queue.Position, submission.Queue.Length)
+		return submission, nil
+	}
+
+	scan, err := ss.dispatch(job)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScanSubmission{ScanID: scan.ScanID, JobID: job.ID, Archive: report, Superseded: superseded}, nil
+}
+
+// dispatch triggers the scan of a job the scheduler gave a slot to and starts polling it
+func (ss *ScanService) dispatch(job *ScanJob) (*cx1.Scan, error) {
+	d := job.Dispatch
+	scan, err := ss.cx1Client.ScanProjectZipByID(job.ProjectID, d.UploadURL, job.Branch, d.Configurations, d.Tags)
+	if err != nil {
+		ss.scheduler.Release(job.ID)
+		ss.failJob(job, fmt.Sprintf("failed to trigger scan: %v", err))
+		return nil, fmt.Errorf("failed to trigger scan for project %s: %v", job.ProjectID, err)
+	}
+
+	job.ScanID = scan.ScanID
+	job.State = JobStatePolling
+	job.Dispatch = nil
+	if _, err := ss.jobs.Update(job.ID, func(stored *ScanJob) {
+		stored.ScanID = scan.ScanID
+		stored.State = JobStatePolling
+		stored.Dispatch = nil
+	}); err != nil {
+		ss.logger.Errorf("❌ Failed to record scan ID %s on job %s: %v", scan.ScanID, job.ID, err)
+	}
+
+	// Polling
+	go ss.PollingStatus(job, &scan)
+
+	ss.logger.Infof("✅ Scan triggered successfully with ID: %s for project ID: %s", scan.ScanID, job.ProjectID)
+	return &scan, nil
+}
+
+// dispatchQueued triggers a job that waited in the queue
+func (ss *ScanService) dispatchQueued(job *ScanJob) {
+	ss.logger.Infof("🚦 Starting queued scan for project %s, commit_id %s", job.ProjectName, job.CommitID)
+	if _, err := ss.dispatch(job); err != nil {
+		ss.logger.Errorf("❌ Failed to start queued scan job %s: %v", job.ID, err)
+	}
+}
+
+// submissionFor describes an existing job as a submission, with its queue position while it is queued
+func (ss *ScanService) submissionFor(job *ScanJob) *ScanSubmission {
+	submission := &ScanSubmission{ScanID: job.ScanID, JobID: job.ID}
+	if job.State == JobStateQueued {
+		if status, ok := ss.scheduler.Position(job.ID); ok {
+			submission.Queue = &status
+		}
+	}
+	return submission
+}
+
+// queuedJobForCommit returns the most recently queued job for a commit, optionally within a project
+func (ss *ScanService) queuedJobForCommit(commitID, projectName string) (*ScanJob, *QueueStatus) {
+	jobs, statuses := ss.scheduler.Queued()
+
+	var latest *ScanJob
+	var status *QueueStatus
+	for i, job := range jobs {
+		if job.CommitID != commitID || (projectName != "" && job.ProjectName != projectName) {
+			continue
+		}
+		if latest == nil || job.CreatedAt.After(latest.CreatedAt) {
+			latest, status = job, &statuses[i]
+		}
+	}
+	return latest, status
+}
+
+// Start removes jobs that finished longer than SCAN_JOB_RETENTION ago and expired Idempotency-Key
+// records until ctx is cancelled
+func (ss *ScanService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(jobCleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			ss.pruneJobs()
+			ss.purgeIdempotencyKeys()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (ss *ScanService) pruneJobs() {
+	pruned, err := ss.jobs.PruneFinished(time.Now().Add(-ss.jobRetention))
+	if err != nil {
+		ss.logger.Errorf("❌ %v", err)
+		return
+	}
+	if pruned > 0 {
+		ss.logger.Infof("🧹 Removed %d finished scan jobs", pruned)
+	}
+}
+
+func (ss *ScanService) purgeIdempotencyKeys() {
+	purged, err := ss.jobs.PurgeExpiredIdempotencyKeys(time.Now().UTC())
+	if err != nil {
+		ss.logger.Errorf("❌ %v", err)
+		return
+	}
+	if purged > 0 {
+		ss.logger.Infof("🧹 Removed %d expired Idempotency-Key records", purged)
+	}
+}
+
+// ResumePendingJobs restarts polling and webhook delivery for jobs left unfinished by a previous run
+func (ss *ScanService) ResumePendingJobs() error {
+	jobs, err := ss.jobs.List(JobStatePolling, JobStateResultsFetched)
+	if err != nil {
+		return err
+	}
+	queued, err := ss.jobs.List(JobStateQueued)
+	if err != nil {
+		return err
+	}
+
+	ss.logger.Infof("🔁 Resuming %d pending and %d queued scan jobs", len(jobs), len(queued))
+
+	for i := range jobs {
+		job := &jobs[i]
+
+		scan, err := ss.cx1Client.GetScanByID(job.ScanID)
+		if err != nil {
+			ss.logger.Errorf("❌ Failed to get scan %s for job %s: %v", job.ScanID, job.ID, err)
+			ss.failJob(job, fmt.Sprintf("failed to get scan on resume: %v", err))
+			continue
+		}
+
+		switch job.State {
+		case JobStatePolling:
+			ss.scheduler.Track(job)
+			go ss.PollingStatus(job, &scan)
+		case JobStateResultsFetched:
+			go ss.notifyJob(job, &scan)
+		}
+	}
+
+	// Queued jobs go back in line behind the running ones, in the order they were submitted
+	sortByCreation(queued)
+	for i := range queued {
+		job := &queued[i]
+		if ss.scheduler.Enqueue(job) {
+			go ss.dispatchQueued(job)
+		}
+	}
+
+	return nil
+}
+
+func (ss *ScanService) PollingStatus(job *ScanJob, scan *cx1.Scan) {
+
+	ss.logger.Infof("🔄 Polling status for scan ID: %s", scan.ScanID)
+
+	ss.logger.Infof("🔄 Starting scan polling process")
+
+	stopPolling := ss.broker.StartPolling(job.CommitID)
+	updatedScan, err := ss.pollUntilTerminal(job, scan)
+	stopPolling()
+	ss.scheduler.Release(job.ID)
+	if err != nil {
+		ss.logger.Errorf("❌ Error during scan polling: %v", err)
+		ss.broker.Fail(job.CommitID, scan.ScanID, err)
+		ss.failJob(job, fmt.Sprintf("scan polling failed: %v", err))
+		return
+	}
+
+	ss.logger.Infof("✅ Scan polling completed successfully for scan ID: %s with status: %s", updatedScan.ScanID, updatedScan.Status)
+
+	if updatedScan.Status != "Completed" {
+		if ss.isSuperseded(job.ID) {
+			ss.logger.Infof("⏭️ Scan ID %s was superseded and finished with status: %s", updatedScan.ScanID, updatedScan.Status)
+			return
+		}
+		ss.failJob(job, fmt.Sprintf("scan finished with status: %s", updatedScan.Status))
+		return
+	}
+
+	response, err := ss.GetScanResultsByScanID(updatedScan.ScanID)
+	if err != nil {
+		ss.logger.Errorf("❌ Error getting scan results for scan ID %s: %v", updatedScan.ScanID, err)
+		ss.failJob(job, fmt.Sprintf("failed to get scan results: %v", err))
+		return
+	}
+
+	ss.logger.Infof("✅ Scan results retrieved successfully for scan ID: %s", updatedScan.ScanID)
+
+	// Log the actual response content (be careful with size)
+	if response != nil {
+		ss.logger.Debugf("📋 Full scan response details: %+v", response)
+	}
+
+	if _, err := ss.jobs.SetState(job.ID, JobStateResultsFetched, ""); err != nil {
+		ss.logger.Errorf("❌ Failed to update scan job %s: %v", job.ID, err)
+	}
+
+	ss.notifyJob(job, &updatedScan)
+}
+
+// notifyJob queues the completion webhook for a job whose results are available
+func (ss *ScanService) notifyJob(job *ScanJob, scan *cx1.Scan) {
+	queued, err := ss.sendWebhook(job, scan)
+	if err != nil {
+		ss.logger.Errorf("❌ Failed to send webhook: %v", err)
+		ss.failJob(job, fmt.Sprintf("failed to send webhook: %v", err))
+		return
+	}
+
+	if queued == 0 {
+		ss.logger.Infof("No webhook subscriptions matched scan ID %s", scan.ScanID)
+		if _, err := ss.jobs.SetState(job.ID, JobStateWebhookDelivered, ""); err != nil {
+			ss.logger.Errorf("❌ Failed to update scan job %s: %v", job.ID, err)
+		}
+		return
+	}
+
+	ss.logger.Infof("✅ %d webhook deliveries queued successfully", queued)
+	if _, err := ss.jobs.SetState(job.ID, JobStateWebhookQueued, ""); err != nil {
+		ss.logger.Errorf("❌ Failed to update scan job %s: %v", job.ID, err)
+	}
+}
+
+// handleDeliveryFinished records the webhook outcome on the job that produced it once all of its deliveries have finished
+func (ss *ScanService) handleDeliveryFinished(delivery webhooks.Delivery) {
+	job, err := ss.jobs.FindByScanID(delivery.ScanID)
+	if err != nil {
+		ss.logger.Warnf("Webhook delivery %s finished but no job was found: %v", delivery.ID, err)
+		return
+	}
+
+	// Lifecycle events are best-effort; the job tracks delivery of the completion payload. Manual
+	// replays happen after the job has settled and leave its state alone.
+	if delivery.EventType != webhooks.EventScanCompleted || delivery.ReplayOf != "" {
+		return
+	}
+
+	if delivery.Status != webhooks.DeliveryStatusDelivered {
+		ss.failJob(job, fmt.Sprintf("webhook delivery %s failed: %s", delivery.ID, delivery.LastError))
+		return
+	}
+
+	deliveries, err := ss.webhookService.DeliveriesForScan(delivery.ScanID)
+	if err != nil {
+		ss.logger.Errorf("❌ Failed to check webhook deliveries for scan ID %s: %v", delivery.ScanID, err)
+		return
+	}
+	for _, d := range deliveries {
+		if d.EventType == webhooks.EventScanCompleted && d.ReplayOf == "" && d.Status != webhooks.DeliveryStatusDelivered {
+			return
+		}
+	}
+
+	if job.State == JobStateWebhookQueued {
+		if _, err := ss.jobs.SetState(job.ID, JobStateWebhookDelivered, ""); err != nil {
+			ss.logger.Errorf("❌ Failed to update scan job %s: %v", job.ID, err)
+		}
+	}
+}
+
+func (ss *ScanService) failJob(job *ScanJob, reason string) {
+	if _, err := ss.jobs.SetState(job.ID, JobStateFailed, reason); err != nil {
+		ss.logger.Errorf("❌ Failed to mark scan job %s as failed: %v", job.ID, err)
+	}
+}
+
+// sendWebhook publishes the completion event to every matching subscription and returns how many deliveries were queued
+func (ss *ScanService) sendWebhook(job *ScanJob, scan *cx1.Scan) (queued int, err error) {
+	// Create payload
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("a panic occurred while sending webhook: %v", r)
+		}
+	}()
+
+	scanResponse := ss.buildWebhookPayload(scan)
+
+	now := time.Now().UTC()
+	v2 := newScanEventV2(job, scan, webhooks.EventScanCompleted, now)
+	v2.Result = &webhooks.ScanResultV2{
+		Link:            scanResponse.Link,
+		IsFastScan:      scanResponse.IsFastScan,
+		IsPolicyBlocked: scanResponse.BreakBuild,
+		TotalResults:    scanResponse.Summary.TotalResults,
+	}
+	if scanResponse.PolicyWarning != nil {
+		v2.Result.PolicyWarning = *scanResponse.PolicyWarning
+	}
+	if scanResponse.Error != nil {
+		v2.Result.Error = *scanResponse.Error
+	}
+
+	// Hand off to the delivery queue, which retries with backoff and dead-letters on permanent failure
+	return ss.publishEvent(job, scan, webhooks.EventScanCompleted, now, scanResponse, v2)
+}
+
+// buildWebhookPayload constructs the ScanResultResponse, similar to GetAllScanResultsByCommitID but for a single scan.
+func (ss *ScanService) buildWebhookPayload(scan *cx1.Scan) *ScanResultResponse {
+	resultsLink := fmt.Sprintf("https://sng.ast.checkmarx.net/projects/%s/scans?branch=%s&id=%s",
+		scan.ProjectID, scan.Branch, scan.ScanID)
+
+	scanResponse := &ScanResultResponse{
+		Link:      resultsLink,
+		ScanID:    scan.ScanID,
+		CommitID:  scan.Tags["commit_id"], // Assumes commit_id is in tags
+		ProjectID: scan.ProjectID,
+		Branch:    scan.Branch,
+		Status:    scan.Status,
+		CreatedAt: scan.CreatedAt,
+		UpdatedAt: scan.UpdatedAt,
+		Tags:      scan.Tags,
+	}
+
+	if scan.Status == "Completed" {
+		results, err := ss.cx1Client.GetAllScanResultsByID(scan.ScanID)
+		if err != nil {
+			ss.logger.Errorf("Failed to get results for scan ID %s: %v", scan.ScanID, err)
+			errorMsg := fmt.Sprintf("Failed to get results: %v", err)
+			scanResponse.Error = &errorMsg
+		} else {
+			scanResponse.Results = results
+			scanResponse.Summary = Summary{TotalResults: int(results.Count())}
+			ss.logger.Debugf("Retrieved %d results for scan ID %s", results.Count(), scan.ScanID)
+			ss.annotateNewFindings(scanResponse, scan, results)
+		}
+
+		config, err := ss.cx1Client.GetScanConfigurationByID(scan.ProjectID, scan.ScanID)
+		if err != nil {
+			ss.logger.Warnf("Failed to get scan configuration for scan ID %s: %v. Assuming full scan.", scan.ScanID, err)
+			scanResponse.IsFastScan = false
+		} else {
+			scanResponse.IsFastScan = ss.isFastScanMode(config)
+		}
+
+		breakbuild, policyErr := ss.cx1Client.RetrievePolicyViolationInfo(scan.ProjectID, scan.ScanID)
+		if policyErr != nil {
+			ss.logger.Warnf("Failed to retrieve policy violation info for scan ID %s: %v. Continuing without breakbuild status.", scan.ScanID, policyErr)
+			warning := fmt.Sprintf("Policy violation info unavailable: %v", policyErr)
+			scanResponse.PolicyWarning = &warning
+			scanResponse.BreakBuild = false
+		} else {
+			scanResponse.BreakBuild = breakbuild
+		}
+	} else {
+		scanResponse.IsFastScan = false
+		scanResponse.BreakBuild = false
+		statusMsg := fmt.Sprintf("Scan not completed (status: %s)", scan.Status)
+		scanResponse.StatusMessage = &statusMsg
+	}
+
+	return scanResponse
+}
+
+func (ss *ScanService) GetScanResultsByScanID(scanID string) (interface{}, error) {
+
+	// Get filtered scans
+	scan, err := ss.cx1Client.GetScanByID(scanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scans: %v", err)
+	}
+
+	ss.logger.Debugf("Found scan ID: %s with status: %s", scan.ScanID, scan.Status)
+
+	// Check if scan is completed
+	if scan.Status != "Completed" {
+		return nil, fmt.Errorf("scan is not completed yet (current status: %s). Scan ID: %s", scan.Status, scan.ScanID)
+	}
+
+	// Get detailed results using the actual scan ID
+	results, err := ss.cx1Client.GetAllScanResultsByID(scan.ScanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get results for scan %s: %v", scan.ScanID, err)
+	}
+
+	ss.logger.Infof("Successfully retrieved scan results for scan ID: %s with %d results", scan.ScanID, results.Count())
+
+	// Create a structured response with scan metadata and results
+	resultsLink := fmt.Sprintf("https://sng.ast.checkmarx.net/projects/%s/scans?branch=%s&id=%s",
+		scan.ProjectID, scan.Branch, scan.ScanID)
+
+	var breakbuild bool
+	var policyErr error
+
+	breakbuild, policyErr = ss.cx1Client.RetrievePolicyViolationInfo(scan.ProjectID, scan.ScanID)
+	if policyErr != nil {
+
+		ss.logger.Warnf("Failed to retrieve policy violation info for scan ID %s: %v. Continuing without breakbuild status.", scan.ScanID, policyErr)
+
+		// Set breakbuild to false as default when policy info is unavailable
+		breakbuild = true
+	}
+
+	response := &ScanResultResponse{
+		Link:       resultsLink,
+		BreakBuild: breakbuild,
+		ScanID:     scan.ScanID,
+		ProjectID:  scan.ProjectID,
+		Branch:     scan.Branch,
+		Status:     scan.Status,
+		CreatedAt:  scan.CreatedAt,
+		UpdatedAt:  scan.UpdatedAt,
+		Tags:       scan.Tags,
+		Results:    results,
+		Summary: Summary{
+			TotalResults: int(results.Count()),
+		},
+	}
+
+	if policyErr != nil {
+		warning := fmt.Sprintf("Policy violation info unavailable: %v", policyErr)
+		response.PolicyWarning = &warning
+	}
+
+	ss.annotateNewFindings(response, &scan, results)
+
+	return response, nil
+}
+
+func (ss *ScanService) GetAllScanResultsByCommitID(commitID string, projectName string) (*AllScansResponse, error) {
+	// Create filter to find scans by commit_id
+	filter := cx1.ScanFilter{}
+
+	// Add commit_id filter
+	filter.TagKeys = append(filter.TagKeys, "commit_id")
+	filter.TagValues = append(filter.TagValues, commitID)
+
+	// Optionally filter by project if provided
+	if projectName != "" {
+		projects, err := ss.cx1Client.GetProjectsByName(projectName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find project '%s': %v", projectName, err)
+		}
+		if len(projects) == 0 {
+			return nil, fmt.Errorf("project '%s' not found", projectName)
+		}
+		filter.ProjectID = projects[0].ProjectID
+		ss.logger.Debugf("Filtering by project '%s' (ID: %s) and commit_id '%s' for results", projectName, filter.ProjectID, commitID)
+	} else {
+		ss.logger.Debugf("Filtering by commit_id '%s' only for results", commitID)
+	}
+
+	// Get filtered scans (assuming they are sorted newest to oldest)
+	scans, err := ss.cx1Client.GetLastScansFiltered(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scans: %v", err)
+	}
+
+	if len(scans) == 0 {
+		return nil, fmt.Errorf("no scans found for commit_id: %s", commitID)
+	}
+
+	// Pointers to hold the latest fast and full scans found.
+	var latestFastScan *ScanResultResponse
+	var latestFullScan *ScanResultResponse
+
+	// Process scans to find the latest of each type
+	for _, scan := range scans {
+		// Optimization: if we've found both, we can stop processing.
+		if latestFastScan != nil && latestFullScan != nil {
+			break
+		}
+
+		ss.logger.Debugf("Processing scan ID: %s with status: %s for commit_id: %s", scan.ScanID, scan.Status, commitID)
+
+		// Initialize scan response
+		resultsLink := fmt.Sprintf("https://sng.ast.checkmarx.net/projects/%s/scans?branch=%s&id=%s",
+			scan.ProjectID, scan.Branch, scan.ScanID)
+
+		scanResponse := ScanResultResponse{
+			Link:      resultsLink,
+			ScanID:    scan.ScanID,
+			CommitID:  commitID,
+			ProjectID: scan.ProjectID,
+			Branch:    scan.Branch,
+			Status:    scan.Status,
+			CreatedAt: scan.CreatedAt,
+			UpdatedAt: scan.UpdatedAt,
+			Tags:      scan.Tags,
+		}
+
+		// Only get detailed results for completed scans
+		if scan.Status == "Completed" {
+			results, err := ss.cx1Client.GetAllScanResultsByID(scan.ScanID)
+			if err != nil {
+				ss.logger.Errorf("Failed to get results for scan ID %s: %v", scan.ScanID, err)
+				errorMsg := fmt.Sprintf("Failed to get results: %v", err)
+				scanResponse.Error = &errorMsg
+			} else {
+				scanResponse.Results = results
+				scanResponse.Summary = Summary{TotalResults: int(results.Count())}
+				ss.logger.Debugf("Retrieved %d results for scan ID %s", results.Count(), scan.ScanID)
+				ss.annotateNewFindings(&scanResponse, &scan, results)
+			}
+
+			// Determine if the scan is a fast scan
+			config, err := ss.cx1Client.GetScanConfigurationByID(scan.ProjectID, scan.ScanID)
+			if err != nil {
+				ss.logger.Warnf("Failed to get scan configuration for scan ID %s: %v. Assuming full scan.", scan.ScanID, err)
+				scanResponse.IsFastScan = false
+			} else {
+				scanResponse.IsFastScan = ss.isFastScanMode(config)
+			}
+
+			ss.logger.Infof("Is fast scan: ", config)
+
+			// Get policy violation info
+			breakbuild, policyErr := ss.cx1Client.RetrievePolicyViolationInfo(scan.ProjectID, scan.ScanID)
+			if policyErr != nil {
+				ss.logger.Warnf("Failed to retrieve policy violation info for scan ID %s: %v. Continuing without breakbuild status.", scan.ScanID, policyErr)
+				warning := fmt.Sprintf("Policy violation info unavailable: %v", policyErr)
+				scanResponse.PolicyWarning = &warning
+				scanResponse.BreakBuild = false
+			} else {
+				scanResponse.BreakBuild = breakbuild
+			}
+		} else {
+			// For non-completed scans, set defaults
+			scanResponse.IsFastScan = false
+			scanResponse.BreakBuild = false
+			statusMsg := fmt.Sprintf("Scan not completed (status: %s)", scan.Status)
+			scanResponse.StatusMessage = &statusMsg
+		}
+
+		// Assign the processed scan to the correct category if it's the first one we've found
+		if scanResponse.IsFastScan {
+			if latestFastScan == nil {
+				// Create a new variable to hold the response and assign its address
+				s := scanResponse
+				latestFastScan = &s
+			}
+		} else { // It's a full scan
+			if latestFullScan == nil {
+				s := scanResponse
+				latestFullScan = &s
+			}
+		}
+	}
+
+	// Create the final response object with the categorized scans
+	response := &AllScansResponse{
+		CommitID:    commitID,
+		ProjectName: projectName,
+		Scans: CategorizedScans{
+			Fast: latestFastScan,
+			Full: latestFullScan,
+		},
+	}
+
+	// Add summary counts based on the found scans
+	completedScans := 0
+	breakBuildCount := 0
+	totalScansInResponse := 0
+
+	if latestFastScan != nil {
+		totalScansInResponse++
+		if latestFastScan.Status == "Completed" {
+			completedScans++
+			if latestFastScan.BreakBuild {
+				breakBuildCount++
+			}
+		}
+	}
+	if latestFullScan != nil {
+		totalScansInResponse++
+		if latestFullScan.Status == "Completed" {
+			completedScans++
+			if latestFullScan.BreakBuild {
+				breakBuildCount++
+			}
+		}
+	}
+
+	response.TotalScans = totalScansInResponse
+	response.Summary = AllScansSummary{
+		CompletedScans:  completedScans,
+		BreakBuildCount: breakBuildCount,
+	}
+
+	ss.logger.Infof("✅ Successfully processed and categorized latest scans for commit_id: %s. Total scans found: %d, Completed: %d, BreakBuild: %d", commitID, totalScansInResponse, completedScans, breakBuildCount)
+
+	return response, nil
+}
+
+func (ss *ScanService) isFastScanMode(config interface{}) bool {
+	// The config is a slice of a specific struct type, not a generic interface.
+	// We perform a type assertion to the correct type from the cx1 library.
+	if configSlice, ok := config.([]cx1.ConfigurationSetting); ok {
+		for _, setting := range configSlice {
+			// Access the struct's 'Key' field directly.
+			if setting.Key == "scan.config.sast.fastScanMode" {
+
+				ss.logger.Infof("Found fastScanMode setting, checking value: %s (type: %T)", setting.Value, setting.Value)
+
+				// Access the 'Value' field and check its type (string or bool).
+				// setting.Value is of type string, so compare directly
+				return setting.Value == "true"
+			}
+		}
+	} else {
+		// Add a log to show if the initial type assertion fails.
+		ss.logger.Warnf("isFastScanMode received an unexpected config type: %T", config)
+	}
+
+	// Default to false if not found or if the type assertion fails.
+	return false
+}
+
+func (ss *ScanService) ListScansFiltered(req ListScansRequest) (*ListScansResponse, error) {
+	// Create ScanFilter based on the request
+	filter := cx1.ScanFilter{}
+
+	// Handle project_name - need to resolve to project_id
+	if req.ProjectName != "" {
+		projects, err := ss.cx1Client.GetProjectsByName(req.ProjectName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find project '%s': %v", req.ProjectName, err)
+		}
+		if len(projects) == 0 {
+			return nil, fmt.Errorf("project '%s' not found", req.ProjectName)
+		}
+		// Use the first matching project
+		filter.ProjectID = projects[0].ProjectID
+
+		ss.logger.Debugf("Resolved project name '%s' to ID '%s'", req.ProjectName, filter.ProjectID)
+	}
+
+	// Handle commit_id - add it to tag values for filtering
+	if req.CommitID != "" {
+		filter.TagKeys = append(filter.TagKeys, "commit_id")
+		filter.TagValues = append(filter.TagValues, req.CommitID)
+		ss.logger.Debugf("Added commit_id filter: %s", req.CommitID)
+	}
+
+	// Get filtered scans using the cx1Client method
+	scans, err := ss.cx1Client.GetLastScansFiltered(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get filtered scans: %v", err)
+	}
+
+	ss.logger.Debugf("Retrieved filtered scans, total count: %d", len(scans))
+
+	// Apply client-side pagination since the API might not support it directly
+	total := len(scans)
+	start := req.Offset
+	end := req.Offset + req.Limit
+
+	// Validate pagination bounds
+	if start > total {
+		start = total
+	}
+	if end > total {
+		end = total
+	}
+
+	var paginatedScans []cx1.Scan
+	if start < total {
+		paginatedScans = scans[start:end]
+	}
+
+	return &ListScansResponse{
+		Scans:  paginatedScans,
+		Total:  total,
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	}, nil
+}
+
+// GetScanStatusByCommitID gets scan status by commit_id, optionally filtered by project_name
+func (ss *ScanService) GetScanStatusByCommitID(commitID string, projectName string) (*SimpleScanStatus, error) {
+	// A scan waiting in the wrapper's queue is newer than anything Cx1 knows about
+	if job, queue := ss.queuedJobForCommit(commitID, projectName); job != nil {
+		return &SimpleScanStatus{
+			Status: "Queued",
+			JobID:  job.ID,
+			Queue:  queue,
+		}, nil
+	}
+
+	// Create filter to find scans by commit_id
+	filter := cx1.ScanFilter{}
+
+	// Add commit_id filter
+	filter.TagKeys = append(filter.TagKeys, "commit_id")
+	filter.TagValues = append(filter.TagValues, commitID)
+
+	// Optionally filter by project if provided
+	if projectName != "" {
+		projects, err := ss.cx1Client.GetProjectsByName(projectName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find project '%s': %v", projectName, err)
+		}
+		if len(projects) == 0 {
+			return nil, fmt.Errorf("project '%s' not found", projectName)
+		}
+		filter.ProjectID = projects[0].ProjectID
+
+		ss.logger.Debugf("Filtering by project '%s' (ID: %s) and commit_id '%s'", projectName, filter.ProjectID, commitID)
+	} else {
+		ss.logger.Debugf("Filtering by commit_id '%s' only", commitID)
+	}
+
+	// Get filtered scans
+	scans, err := ss.cx1Client.GetLastScansFiltered(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scans: %v", err)
+	}
+
+	if len(scans) == 0 {
+		return nil, fmt.Errorf("no scans found for commit_id: %s", commitID)
+	}
+
+	// Get the most recent scan (first one since GetLastScansFiltered sorts by created_at desc)
+	scan := scans[0]
+
+	return &SimpleScanStatus{
+		ScanID: scan.ScanID,
+		Status: scan.Status,
+	}, nil
+}
+
+func (ss *ScanService) CancelScan(commitID string, projectName string) error {
+	// A scan still in the wrapper's queue never reaches Cx1
+	if job, _ := ss.queuedJobForCommit(commitID, projectName); job != nil && ss.scheduler.Remove(job.ID) {
+		ss.logger.Infof("🛑 Removed queued scan job %s for commit_id %s", job.ID, commitID)
+		ss.failJob(job, "canceled while queued")
+		return nil
+	}
+
+	filter := cx1.ScanFilter{}
+
+	// Add commit_id filter
+	filter.TagKeys = append(filter.TagKeys, "commit_id")
+	filter.TagValues = append(filter.TagValues, commitID)
+
+	// Add project_name filter if provided
+	if projectName != "" {
+		filter.TagKeys = append(filter.TagKeys, "project_name")
+		filter.TagValues = append(filter.TagValues, projectName)
+	}
+
+	scans, err := ss.cx1Client.GetLastScansFiltered(filter)
+	if err != nil {
+		return fmt.Errorf("failed to get scans: %v", err)
+	}
+
+	if len(scans) == 0 {
+		if projectName != "" {
+			return fmt.Errorf("no scans found for commit_id: %s and project_name: %s", commitID, projectName)
+		}
+		return fmt.Errorf("no scans found for commit_id: %s", commitID)
+	}
+
+	// Get the most recent scan (first one since GetLastScansFiltered sorts by created_at desc)
+	scan := scans[0]
+
+	err = ss.cx1Client.CancelScanByID(scan.ScanID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel scan: %v", err)
+	}
+
+	return nil
+}
+
+// func (ss *ScanService) TempGetConfig(project_Id string) ([]cx1.ConfigurationSetting, error) {
+//  config, err := ss.cx1Client.GetScanConfigurationByProjectID(project_Id)
+//  if err != nil {
+//      return nil, fmt.Errorf("failed to get config: %v", err)
+//  }
+
+//  return config, nil
+// }
+
+func (s *ScanService) AssignProjectToApp(appName string, projectName string) error {
+	// Get application by name.
+	application, err := s.cx1Client.GetApplicationByName(appName)
+	if err != nil {
+		s.logger.Infof("Application '%s' not found, creating a new one.", appName)
+		// Create the application if it doesn't exist.
+		newApplication, createErr := s.cx1Client.CreateApplication(appName)
+		if createErr != nil {
+			s.logger.Errorf("Error creating application '%s': %v", appName, createErr)
+			return createErr
+		}
+		application = newApplication
+	} else {
+		s.logger.Infof("Application fetched: %v", application.Name)
+	}
+
+	// Get project by name.
+	projects, err := s.cx1Client.GetProjectsByName(projectName)
+	if err != nil {
+		return fmt.Errorf("failed to get project '%s': %v", projectName, err)
+	}
+
+	var project cx1.Project
+	if len(projects) == 0 {
+		s.logger.Infof("Project '%s' not found, creating a new one.", projectName)
+		// Create the project if it doesn't exist.
+		newProject, createErr := s.cx1Client.CreateProject(projectName, []string{}, make(map[string]string))
+		if createErr != nil {
+			return fmt.Errorf("failed to create new project '%s': %v", projectName, createErr)
+		}
+		project = newProject
+		s.logger.Infof("✅ New project created with ID: %s", project.ProjectID)
+	} else {
+		project = projects[0] // Use the first project found.
+		s.logger.Infof("✅ Project found with ID: %s", project.ProjectID)
+	}
+
+	// Assign the project to the application.
+	application.AssignProject(&project)
+
+	// Update the application to save the changes.
+	if err := s.cx1Client.UpdateApplication(&application); err != nil {
+		s.logger.Errorf("Error updating application '%s': %v", appName, err)
+		return err
+	}
+
+	s.logger.Infof("✅ Successfully assigned project '%s' to application '%s'", projectName, appName)
+	return nil
+}
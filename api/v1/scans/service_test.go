@@ -0,0 +1,406 @@
+package scans
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/madhatkul/CxWrapper-v2/api/cxclient"
+	"github.com/madhatkul/CxWrapper-v2/api/v1/uploads"
+	"github.com/madhatkul/CxWrapper-v2/api/v1/webhooks"
+	bolt "go.etcd.io/bbolt"
+)
+
+type testLogger struct{ t *testing.T }
+
+func (l testLogger) Infof(format string, args ...interface{})  { l.t.Logf("INFO "+format, args...) }
+func (l testLogger) Warnf(format string, args ...interface{})  { l.t.Logf("WARN "+format, args...) }
+func (l testLogger) Errorf(format string, args ...interface{}) { l.t.Logf("ERROR "+format, args...) }
+func (l testLogger) Debugf(format string, args ...interface{}) { l.t.Logf("DEBUG "+format, args...) }
+
+// newTestService returns a scan service backed by a Fake and a temporary bolt file, polling every few milliseconds
+func newTestService(t *testing.T) (*ScanService, *cxclient.Fake) {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("SCAN_POLL_INTERVAL", "5ms")
+	t.Setenv("UPLOAD_DIR", filepath.Join(dir, "uploads"))
+	t.Setenv("STATIC_WEBHOOK_URL", "")
+
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := testLogger{t}
+
+	jobs, err := NewJobStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhookService, err := webhooks.NewWebhookService(db, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploadService, err := uploads.NewUploadService(db, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := cxclient.NewFake()
+	ss := NewScanService(fake, jobs, webhookService, uploadService, logger)
+	// Registered after TempDir's cleanup, so the database is closed before its directory is removed
+	t.Cleanup(func() {
+		settleJobs(t, ss, fake)
+		db.Close()
+	})
+	return ss, fake
+}
+
+// settleJobs completes the scans still being polled and waits for their jobs to finish, so no poller
+// outlives the test
+func settleJobs(t *testing.T, ss *ScanService, fake *cxclient.Fake) {
+	polling, err := ss.jobs.List(JobStatePolling)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range polling {
+		fake.Complete(job.ScanID)
+		waitForJob(t, ss, job.ID, JobStateWebhookDelivered, JobStateFailed)
+	}
+}
+
+func testZip(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("src/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(w, "package main\n\nfunc main() {}\n")
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testScanRequest(t *testing.T, commitID string) StaticScanRequestWithFile {
+	data := testZip(t)
+	return StaticScanRequestWithFile{
+		AppName:     "payments",
+		ProjectName: "payments-api",
+		Branch:      "main",
+		CommitID:    commitID,
+		ScanTypes:   []string{"sast", "sca"},
+		Preset:      "ASA Premium",
+		File:        bytes.NewReader(data),
+		FileSize:    int64(len(data)),
+		FileName:    "source.zip",
+	}
+}
+
+// waitForJob waits until the job reaches one of the given states and returns it
+func waitForJob(t *testing.T, ss *ScanService, jobID string, states ...JobState) *ScanJob {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := ss.jobs.Get(jobID)
+		if err != nil {
+			t.Fatalf("Get(%s) = %v", jobID, err)
+		}
+		for _, state := range states {
+			if job.State == state {
+				return job
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is %s, want one of %v", jobID, job.State, states)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestScanLifecycle(t *testing.T) {
+	ss, fake := newTestService(t)
+
+	submission, err := ss.StartStaticScanWithFile(testScanRequest(t, "commit-1"))
+	if err != nil {
+		t.Fatalf("StartStaticScanWithFile() = %v", err)
+	}
+	if submission.ScanID == "" || submission.JobID == "" {
+		t.Fatalf("submission = %+v, want a scan and job ID", submission)
+	}
+	if job := waitForJob(t, ss, submission.JobID, JobStatePolling); job.ScanID != submission.ScanID {
+		t.Fatalf("job scan ID = %q, want %q", job.ScanID, submission.ScanID)
+	}
+
+	if err := fake.Complete(submission.ScanID); err != nil {
+		t.Fatal(err)
+	}
+	job := waitForJob(t, ss, submission.JobID, JobStateWebhookDelivered, JobStateFailed)
+	if job.State != JobStateWebhookDelivered || job.LastStatus != "Completed" {
+		t.Fatalf("job = %s, last status %s (%s), want delivered after Completed", job.State, job.LastStatus, job.Error)
+	}
+
+	response, err := ss.GetScanResultsByScanID(submission.ScanID)
+	if err != nil {
+		t.Fatalf("GetScanResultsByScanID() = %v", err)
+	}
+	result := response.(*ScanResultResponse)
+	want := cxclient.SyntheticResults(job.ProjectID, []string{"sast", "sca"})
+	if result.Status != "Completed" || result.Summary.TotalResults != int(want.Count()) {
+		t.Fatalf("result = %s with %d results, want Completed with %d", result.Status, result.Summary.TotalResults, want.Count())
+	}
+
+	// Once the first scan finished, the same commit is scanned again rather than matched as a duplicate
+	again, err := ss.StartStaticScanWithFile(testScanRequest(t, "commit-1"))
+	if err != nil {
+		t.Fatalf("StartStaticScanWithFile() again = %v", err)
+	}
+	if again.Duplicate || again.ScanID == submission.ScanID {
+		t.Fatalf("second submission = %+v, want a new scan once the first one finished", again)
+	}
+	fake.Complete(again.ScanID)
+	waitForJob(t, ss, again.JobID, JobStateWebhookDelivered, JobStateFailed)
+}
+
+func TestScanHandlers(t *testing.T) {
+	ss, fake := newTestService(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewScanHandler(ss, testLogger{t}).RegisterRoutes(router.Group("/api/v1"))
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range map[string]string{
+		"app_name":     "payments",
+		"project_name": "payments-api",
+		"branch":       "main",
+		"commit_id":    "commit-1",
+		"scan_types":   "sast,sca",
+		"is_fast_scan": "false",
+		"preset":       "ASA Premium",
+	} {
+		form.WriteField(name, value)
+	}
+	part, err := form.CreateFormFile("zip_file", "source.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(testZip(t))
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scans/static", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /scans/static = %d: %s", rec.Code, rec.Body)
+	}
+	var started ScanResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil || started.ScanID == "" {
+		t.Fatalf("POST /scans/static response = %s (%v), want a scan ID", rec.Body, err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	fake.Complete(started.ScanID)
+	job, err := ss.jobs.FindByScanID(started.ScanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForJob(t, ss, job.ID, JobStateWebhookDelivered, JobStateFailed)
+
+	rec = get("/api/v1/scans/static/results?commit_id=commit-1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /results = %d: %s", rec.Code, rec.Body)
+	}
+	var results AllScansResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatalf("GET /results is not valid JSON: %v", err)
+	}
+	if !strings.Contains(rec.Body.String(), started.ScanID) {
+		t.Fatalf("GET /results does not mention scan %s: %s", started.ScanID, rec.Body)
+	}
+
+	if rec = get("/api/v1/scans/static/results?commit_id=commit-1&format=sarif"); rec.Code != http.StatusOK {
+		t.Fatalf("GET /results?format=sarif = %d: %s", rec.Code, rec.Body)
+	}
+	if rec = get("/api/v1/scans/static/results"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("GET /results without commit_id = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCancelScan(t *testing.T) {
+	ss, _ := newTestService(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewScanHandler(ss, testLogger{t}).RegisterRoutes(router.Group("/api/v1"))
+	cancel := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/scans/static/cancel?commit_id=commit-1", nil))
+		return rec
+	}
+
+	submission, err := ss.StartStaticScanWithFile(testScanRequest(t, "commit-1"))
+	if err != nil {
+		t.Fatalf("StartStaticScanWithFile() = %v", err)
+	}
+
+	if rec := cancel(); rec.Code != http.StatusOK {
+		t.Fatalf("POST /cancel = %d: %s", rec.Code, rec.Body)
+	}
+	job := waitForJob(t, ss, submission.JobID, JobStateFailed, JobStateWebhookDelivered)
+	if job.State != JobStateFailed || job.LastStatus != "Canceled" {
+		t.Fatalf("job = %s after %s, want failed after Canceled", job.State, job.LastStatus)
+	}
+
+	// A finished scan cannot be canceled again
+	if rec := cancel(); rec.Code != http.StatusBadRequest {
+		t.Fatalf("second POST /cancel = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestFailNext(t *testing.T) {
+	injected := errors.New("injected failure")
+
+	t.Run("submission", func(t *testing.T) {
+		tests := []struct {
+			method  string
+			wantErr string
+		}{
+			{method: "GetProjectsByName", wantErr: "failed to get project"},
+			{method: "CreateProject", wantErr: "failed to create new project"},
+			{method: "UploadStreamForProjectByID", wantErr: "failed to upload file"},
+			{method: "GetScanConfigurationByProjectID", wantErr: "failed to get default scan configuration"},
+			{method: "ScanProjectZipByID", wantErr: "failed to trigger scan"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.method, func(t *testing.T) {
+				ss, fake := newTestService(t)
+				fake.FailNext(tt.method, injected)
+
+				_, err := ss.StartStaticScanWithFile(testScanRequest(t, "commit-1"))
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), injected.Error()) {
+					t.Fatalf("StartStaticScanWithFile() = %v, want %q caused by the injected failure", err, tt.wantErr)
+				}
+
+				// The failure is not sticky: a retry goes through
+				submission, err := ss.StartStaticScanWithFile(testScanRequest(t, "commit-1"))
+				if err != nil {
+					t.Fatalf("retry = %v", err)
+				}
+				if submission.ScanID == "" {
+					t.Fatalf("retry = %+v, want a scan", submission)
+				}
+			})
+		}
+	})
+
+	t.Run("triggered job is failed", func(t *testing.T) {
+		ss, fake := newTestService(t)
+		fake.FailNext("ScanProjectZipByID", injected)
+
+		if _, err := ss.StartStaticScanWithFile(testScanRequest(t, "commit-1")); err == nil {
+			t.Fatal("StartStaticScanWithFile() = nil, want the trigger failure")
+		}
+		failed, err := ss.jobs.List(JobStateFailed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(failed) != 1 || !strings.Contains(failed[0].Error, injected.Error()) {
+			t.Fatalf("failed jobs = %+v, want one failed by the injected error", failed)
+		}
+	})
+
+	t.Run("polling", func(t *testing.T) {
+		ss, fake := newTestService(t)
+		for i := 0; i < maxPollFailures; i++ {
+			fake.FailNext("GetScanByID", injected)
+		}
+
+		submission, err := ss.StartStaticScanWithFile(testScanRequest(t, "commit-1"))
+		if err != nil {
+			t.Fatalf("StartStaticScanWithFile() = %v", err)
+		}
+		job := waitForJob(t, ss, submission.JobID, JobStateFailed, JobStateWebhookDelivered)
+		if job.State != JobStateFailed || !strings.Contains(job.Error, "giving up polling") {
+			t.Fatalf("job = %s (%s), want failed after giving up polling", job.State, job.Error)
+		}
+	})
+
+	t.Run("polling recovers from a few errors", func(t *testing.T) {
+		ss, fake := newTestService(t)
+		for i := 0; i < maxPollFailures-1; i++ {
+			fake.FailNext("GetScanByID", injected)
+		}
+
+		submission, err := ss.StartStaticScanWithFile(testScanRequest(t, "commit-1"))
+		if err != nil {
+			t.Fatalf("StartStaticScanWithFile() = %v", err)
+		}
+		fake.Complete(submission.ScanID)
+		job := waitForJob(t, ss, submission.JobID, JobStateFailed, JobStateWebhookDelivered)
+		if job.State != JobStateWebhookDelivered {
+			t.Fatalf("job = %s (%s), want delivered", job.State, job.Error)
+		}
+	})
+
+	t.Run("results", func(t *testing.T) {
+		ss, fake := newTestService(t)
+		fake.FailNext("GetAllScanResultsByID", injected)
+
+		submission, err := ss.StartStaticScanWithFile(testScanRequest(t, "commit-1"))
+		if err != nil {
+			t.Fatalf("StartStaticScanWithFile() = %v", err)
+		}
+		fake.Complete(submission.ScanID)
+		job := waitForJob(t, ss, submission.JobID, JobStateFailed, JobStateWebhookDelivered)
+		if job.State != JobStateFailed || !strings.Contains(job.Error, "failed to get scan results") {
+			t.Fatalf("job = %s (%s), want failed to get scan results", job.State, job.Error)
+		}
+
+		// The results are there once Cx1 answers again
+		if _, err := ss.GetScanResultsByScanID(submission.ScanID); err != nil {
+			t.Fatalf("GetScanResultsByScanID() = %v", err)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ss, fake := newTestService(t)
+
+		if _, err := ss.StartStaticScanWithFile(testScanRequest(t, "commit-1")); err != nil {
+			t.Fatalf("StartStaticScanWithFile() = %v", err)
+		}
+		fake.FailNext("CancelScanByID", injected)
+		if err := ss.CancelScan("commit-1", ""); err == nil || !strings.Contains(err.Error(), injected.Error()) {
+			t.Fatalf("CancelScan() = %v, want the injected failure", err)
+		}
+		fake.FailNext("GetLastScansFiltered", injected)
+		if err := ss.CancelScan("commit-1", ""); err == nil || !strings.Contains(err.Error(), "failed to get scans") {
+			t.Fatalf("CancelScan() = %v, want failed to get scans", err)
+		}
+		if err := ss.CancelScan("commit-1", ""); err != nil {
+			t.Fatalf("CancelScan() after the failures = %v", err)
+		}
+	})
+}
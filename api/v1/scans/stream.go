@@ -0,0 +1,359 @@
+// api/v1/scans/stream.go
+package scans
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	cx1 "github.com/madhatkul/CxWrapper-v2/Cx1ClientGo"
+)
+
+const (
+	// statusBufferSize is how many updates a subscriber may fall behind before it is dropped
+	statusBufferSize = 64
+	// statusRetention is how long the last updates of a finished commit are kept for late subscribers
+	statusRetention = 10 * time.Minute
+	// statusHeartbeatInterval is how often an idle status stream sends a comment to keep the connection open
+	statusHeartbeatInterval = 15 * time.Second
+)
+
+// StatusBroker fans scan status updates out to the clients following a commit. It keeps the last update of
+// every scan so a new subscriber starts from the current state instead of waiting for the next change.
+type StatusBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan StatusUpdate]struct{}
+	last        map[string]map[string]StatusUpdate
+	// pollers counts what is polling each commit: job pollers and commit watchers
+	pollers map[string]int
+}
+
+func NewStatusBroker() *StatusBroker {
+	return &StatusBroker{
+		subscribers: make(map[string]map[chan StatusUpdate]struct{}),
+		last:        make(map[string]map[string]StatusUpdate),
+		pollers:     make(map[string]int),
+	}
+}
+
+// Subscribe returns a channel of updates for the commit, the last known update of each of its scans and a
+// function that ends the subscription. The channel is closed if the subscriber falls too far behind.
+func (sb *StatusBroker) Subscribe(commitID string) (<-chan StatusUpdate, []StatusUpdate, func()) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	ch := make(chan StatusUpdate, statusBufferSize)
+	if sb.subscribers[commitID] == nil {
+		sb.subscribers[commitID] = make(map[chan StatusUpdate]struct{})
+	}
+	sb.subscribers[commitID][ch] = struct{}{}
+
+	snapshot := make([]StatusUpdate, 0, len(sb.last[commitID]))
+	for _, update := range sb.last[commitID] {
+		snapshot = append(snapshot, update)
+	}
+
+	unsubscribe := func() {
+		sb.mu.Lock()
+		defer sb.mu.Unlock()
+		sb.remove(commitID, ch)
+	}
+
+	return ch, snapshot, unsubscribe
+}
+
+// Publish records the update and sends it to every subscriber of its commit
+func (sb *StatusBroker) Publish(update StatusUpdate) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	sb.prune()
+
+	if sb.last[update.CommitID] == nil {
+		sb.last[update.CommitID] = make(map[string]StatusUpdate)
+	}
+	sb.last[update.CommitID][update.ScanID] = update
+
+	for ch := range sb.subscribers[update.CommitID] {
+		select {
+		case ch <- update:
+		default:
+			// A client that stopped reading must not hold up polling; it reconnects and gets the snapshot
+			sb.remove(update.CommitID, ch)
+		}
+	}
+}
+
+// Fail tells the subscribers of the commit that its status can no longer be followed and forgets the
+// scan, if any, so that the next subscriber starts polling it again
+func (sb *StatusBroker) Fail(commitID, scanID string, err error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	delete(sb.last[commitID], scanID)
+
+	update := StatusUpdate{
+		ScanID:    scanID,
+		CommitID:  commitID,
+		Status:    "Unknown",
+		Error:     err.Error(),
+		Timestamp: time.Now().UTC(),
+	}
+	for ch := range sb.subscribers[commitID] {
+		select {
+		case ch <- update:
+		default:
+			sb.remove(commitID, ch)
+		}
+	}
+}
+
+// Subscribers returns the number of clients following the commit
+func (sb *StatusBroker) Subscribers(commitID string) int {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return len(sb.subscribers[commitID])
+}
+
+// StartPolling records that something polls the commit and returns the function to call when it stops.
+// Once nothing polls the commit anymore, the updates of its unfinished scans are stale and are forgotten,
+// so the next subscriber starts from a fresh poll rather than a snapshot nobody will update.
+func (sb *StatusBroker) StartPolling(commitID string) func() {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	sb.pollers[commitID]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			sb.mu.Lock()
+			defer sb.mu.Unlock()
+
+			if sb.pollers[commitID]--; sb.pollers[commitID] > 0 {
+				return
+			}
+			delete(sb.pollers, commitID)
+			for scanID, update := range sb.last[commitID] {
+				if !update.Terminal {
+					delete(sb.last[commitID], scanID)
+				}
+			}
+			if len(sb.last[commitID]) == 0 {
+				delete(sb.last, commitID)
+			}
+		})
+	}
+}
+
+// Live reports whether something is polling the commit
+func (sb *StatusBroker) Live(commitID string) bool {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.pollers[commitID] > 0
+}
+
+func (sb *StatusBroker) lastUpdate(commitID, scanID string) (StatusUpdate, bool) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	update, ok := sb.last[commitID][scanID]
+	return update, ok
+}
+
+// remove must be called with mu held
+func (sb *StatusBroker) remove(commitID string, ch chan StatusUpdate) {
+	subscribers := sb.subscribers[commitID]
+	if _, ok := subscribers[ch]; !ok {
+		return
+	}
+	delete(subscribers, ch)
+	close(ch)
+	if len(subscribers) == 0 {
+		delete(sb.subscribers, commitID)
+	}
+}
+
+// prune forgets commits whose scans all finished more than statusRetention ago. It must be called with mu held.
+func (sb *StatusBroker) prune() {
+	cutoff := time.Now().Add(-statusRetention)
+	for commitID, updates := range sb.last {
+		expired := true
+		for _, update := range updates {
+			if !update.Terminal || update.Timestamp.After(cutoff) {
+				expired = false
+				break
+			}
+		}
+		if expired {
+			delete(sb.last, commitID)
+		}
+	}
+}
+
+// commitProgress tracks the scans of a commit seen on a status subscription
+type commitProgress struct {
+	terminal map[string]bool
+}
+
+func newCommitProgress() *commitProgress {
+	return &commitProgress{terminal: make(map[string]bool)}
+}
+
+// observe records an update
+func (cp *commitProgress) observe(update StatusUpdate) {
+	cp.terminal[update.ScanID] = update.Terminal
+}
+
+// done reports whether every scan seen so far is terminal and none of the commit's jobs still has a scan
+// to wait for: a queued job has not created its scan yet, and a polling one is only done once its scan
+// was seen terminal. Without jobs, which is the case for scans started outside the wrapper, the scans
+// seen are all there is to go by.
+func (cp *commitProgress) done(jobs []ScanJob) bool {
+	if len(cp.terminal) == 0 && len(jobs) == 0 {
+		return false
+	}
+	for _, terminal := range cp.terminal {
+		if !terminal {
+			return false
+		}
+	}
+	for _, job := range jobs {
+		switch job.State {
+		case JobStateQueued:
+			return false
+		case JobStatePolling:
+			if !cp.terminal[job.ScanID] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func newStatusUpdate(commitID, projectName string, scan *cx1.Scan) StatusUpdate {
+	if projectName == "" {
+		projectName = scan.ProjectName
+	}
+	return StatusUpdate{
+		ScanID:      scan.ScanID,
+		CommitID:    commitID,
+		ProjectName: projectName,
+		Branch:      scan.Branch,
+		Status:      scan.Status,
+		Engines:     scan.StatusDetails,
+		Terminal:    isTerminalStatus(scan.Status),
+		Timestamp:   time.Now().UTC(),
+	}
+}
+
+// SubscribeStatus follows the status of the scans of a commit. Scans triggered through this wrapper are fed
+// by their job's polling; for any other commit a single watcher polls Cx1 on behalf of all its subscribers.
+func (ss *ScanService) SubscribeStatus(commitID, projectName string) (<-chan StatusUpdate, []StatusUpdate, func()) {
+	updates, snapshot, unsubscribe := ss.broker.Subscribe(commitID)
+	ss.ensureCommitWatcher(commitID, projectName)
+	return updates, snapshot, unsubscribe
+}
+
+// ensureCommitWatcher starts polling the commit unless something already does
+func (ss *ScanService) ensureCommitWatcher(commitID, projectName string) {
+	ss.watchMu.Lock()
+	defer ss.watchMu.Unlock()
+
+	if ss.broker.Live(commitID) {
+		return
+	}
+	stop := ss.broker.StartPolling(commitID)
+
+	go func() {
+		// Stopping under watchMu means a subscriber that arrived while the watcher gave up starts a new one
+		defer func() {
+			ss.watchMu.Lock()
+			stop()
+			ss.watchMu.Unlock()
+		}()
+		ss.watchCommit(commitID, projectName)
+	}()
+}
+
+// watchCommit polls the scans of a commit until they are all terminal or nobody is following them anymore
+func (ss *ScanService) watchCommit(commitID, projectName string) {
+	ss.logger.Debugf("👀 Watching scans for commit_id %s", commitID)
+
+	filter, err := ss.commitScanFilter(commitID, projectName)
+	if err != nil {
+		ss.logger.Warnf("⚠️ Cannot watch commit_id %s: %v", commitID, err)
+		ss.broker.Fail(commitID, "", err)
+		return
+	}
+
+	failures := 0
+	for {
+		scans, err := ss.cx1Client.GetLastScansFiltered(filter)
+		if err != nil {
+			failures++
+			ss.logger.Warnf("⚠️ Failed to poll scans for commit_id %s (%d/%d): %v", commitID, failures, maxPollFailures, err)
+			if failures >= maxPollFailures {
+				ss.broker.Fail(commitID, "", fmt.Errorf("giving up polling scans for commit_id %s after %d consecutive errors: %v", commitID, failures, err))
+				return
+			}
+		} else {
+			failures = 0
+			done := true
+			for i := range scans {
+				ss.publishStatusIfChanged(commitID, projectName, &scans[i])
+				if !isTerminalStatus(scans[i].Status) {
+					done = false
+				}
+			}
+			if len(scans) > 0 && done {
+				return
+			}
+		}
+
+		if ss.broker.Subscribers(commitID) == 0 {
+			return
+		}
+		time.Sleep(ss.pollInterval)
+	}
+}
+
+// publishStatusIfChanged publishes a polled scan unless it matches the last update seen for it
+func (ss *ScanService) publishStatusIfChanged(commitID, projectName string, scan *cx1.Scan) {
+	update := newStatusUpdate(commitID, projectName, scan)
+
+	if previous, ok := ss.broker.lastUpdate(commitID, scan.ScanID); ok && previous.Status == update.Status && sameEngineStatuses(previous.Engines, update.Engines) {
+		return
+	}
+	ss.broker.Publish(update)
+}
+
+// commitScanFilter builds the Cx1 filter for the scans tagged with a commit, optionally within a project
+func (ss *ScanService) commitScanFilter(commitID, projectName string) (cx1.ScanFilter, error) {
+	filter := cx1.ScanFilter{}
+	filter.TagKeys = append(filter.TagKeys, "commit_id")
+	filter.TagValues = append(filter.TagValues, commitID)
+
+	if projectName != "" {
+		projects, err := ss.cx1Client.GetProjectsByName(projectName)
+		if err != nil {
+			return filter, fmt.Errorf("failed to find project '%s': %v", projectName, err)
+		}
+		if len(projects) == 0 {
+			return filter, fmt.Errorf("project '%s' not found", projectName)
+		}
+		filter.ProjectID = projects[0].ProjectID
+	}
+
+	return filter, nil
+}
+
+func sameEngineStatuses(a, b []cx1.ScanStatusDetails) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Status != b[i].Status {
+			return false
+		}
+	}
+	return true
+}
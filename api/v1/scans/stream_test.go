@@ -0,0 +1,44 @@
+package scans
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatusBrokerPollers(t *testing.T) {
+	sb := NewStatusBroker()
+	if sb.Live("c1") {
+		t.Fatal("Live() = true before anything polls the commit")
+	}
+
+	stopJob := sb.StartPolling("c1")
+	stopWatcher := sb.StartPolling("c1")
+	sb.Publish(StatusUpdate{CommitID: "c1", ScanID: "running", Status: "Running", Timestamp: time.Now()})
+	sb.Publish(StatusUpdate{CommitID: "c1", ScanID: "done", Status: "Completed", Terminal: true, Timestamp: time.Now()})
+
+	stopWatcher()
+	stopWatcher()
+	if !sb.Live("c1") {
+		t.Fatal("Live() = false while the job poller still runs")
+	}
+	if _, ok := sb.lastUpdate("c1", "running"); !ok {
+		t.Fatal("running scan forgotten while the job poller still runs")
+	}
+
+	stopJob()
+	if sb.Live("c1") {
+		t.Fatal("Live() = true after every poller stopped")
+	}
+	if _, ok := sb.lastUpdate("c1", "running"); ok {
+		t.Fatal("stale update of a running scan kept after every poller stopped")
+	}
+	if _, ok := sb.lastUpdate("c1", "done"); !ok {
+		t.Fatal("terminal update forgotten after every poller stopped")
+	}
+
+	_, snapshot, unsubscribe := sb.Subscribe("c1")
+	defer unsubscribe()
+	if len(snapshot) != 1 || snapshot[0].ScanID != "done" {
+		t.Fatalf("snapshot = %+v, want only the terminal update", snapshot)
+	}
+}
@@ -0,0 +1,190 @@
+// api/v1/scans/supersede.go
+package scans
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	cx1 "github.com/madhatkul/CxWrapper-v2/Cx1ClientGo"
+	"github.com/madhatkul/CxWrapper-v2/api/v1/webhooks"
+)
+
+// SupersedePolicy decides which projects have older scans of a branch canceled when a newer one is submitted
+type SupersedePolicy struct {
+	all      bool
+	projects map[string]bool
+}
+
+// supersedePolicyFromEnv reads SCAN_CANCEL_SUPERSEDED_PROJECTS, the comma-separated projects whose
+// superseded scans are canceled, or "*" for every project. It is off for all projects by default.
+func supersedePolicyFromEnv() *SupersedePolicy {
+	policy := &SupersedePolicy{projects: make(map[string]bool)}
+	for _, project := range strings.Split(os.Getenv("SCAN_CANCEL_SUPERSEDED_PROJECTS"), ",") {
+		switch project = strings.TrimSpace(project); project {
+		case "":
+		case "*":
+			policy.all = true
+		default:
+			policy.projects[project] = true
+		}
+	}
+	return policy
+}
+
+// Enabled reports whether a scan of projectName cancels the scans it supersedes; requested, when set,
+// overrides the project's setting
+func (p *SupersedePolicy) Enabled(projectName string, requested *bool) bool {
+	if requested != nil {
+		return *requested
+	}
+	return p.all || p.projects[projectName]
+}
+
+// SupersededScan is an older scan canceled because a newer one of the same branch was submitted
+type SupersededScan struct {
+	JobID    string `json:"job_id"`
+	ScanID   string `json:"scan_id,omitempty"`
+	CommitID string `json:"commit_id"`
+}
+
+// supersedeKey identifies scans that replace each other: the same project, branch and scan mode
+func supersedeKey(projectName, branch string, isFastScan bool, scanTypes []string) string {
+	types := make([]string, 0, len(scanTypes))
+	for _, scanType := range scanTypes {
+		types = append(types, strings.ToLower(strings.TrimSpace(scanType)))
+	}
+	sort.Strings(types)
+	return fmt.Sprintf("%s|%s|%t|%s", projectName, branch, isFastScan, strings.Join(types, ","))
+}
+
+// supersedeOlder cancels the queued and running scans that job replaces. Queued ones are taken out of the
+// wrapper's queue and running ones canceled in Cx1; either way the job is marked superseded and a
+// scan.superseded event is sent in place of scan.canceled. Failures are logged, the new scan goes ahead.
+func (ss *ScanService) supersedeOlder(job *ScanJob) []SupersededScan {
+	jobs, err := ss.jobs.List(JobStateQueued, JobStatePolling)
+	if err != nil {
+		ss.logger.Errorf("❌ Failed to list scan jobs to supersede for project %s: %v", job.ProjectName, err)
+		return nil
+	}
+
+	key := supersedeKey(job.ProjectName, job.Branch, job.IsFastScan, job.ScanTypes)
+	var superseded []SupersededScan
+	for i := range jobs {
+		older := &jobs[i]
+		if older.ID == job.ID || !older.CreatedAt.Before(job.CreatedAt) || isTerminalStatus(older.LastStatus) {
+			continue
+		}
+		if supersedeKey(older.ProjectName, older.Branch, older.IsFastScan, older.ScanTypes) != key {
+			continue
+		}
+
+		if err := ss.supersede(older, job); err != nil {
+			ss.logger.Warnf("⚠️ Could not cancel superseded scan job %s: %v", older.ID, err)
+			continue
+		}
+		superseded = append(superseded, SupersededScan{JobID: older.ID, ScanID: older.ScanID, CommitID: older.CommitID})
+	}
+	return superseded
+}
+
+// supersede cancels one older job in favour of job
+func (ss *ScanService) supersede(older, job *ScanJob) error {
+	reason := fmt.Sprintf("superseded by commit %s", job.CommitID)
+	previousStatus := older.LastStatus
+
+	switch older.State {
+	case JobStateQueued:
+		if !ss.scheduler.Remove(older.ID) {
+			return fmt.Errorf("it is already starting")
+		}
+		if err := ss.markSuperseded(older, job, reason); err != nil {
+			// Back in line rather than dropped, unless it is known to have left the queue meanwhile
+			if stored, getErr := ss.jobs.Get(older.ID); getErr != nil || stored.State == JobStateQueued {
+				if ss.scheduler.Enqueue(older) {
+					go ss.dispatchQueued(older)
+				}
+			}
+			return err
+		}
+
+	case JobStatePolling:
+		// Marked before canceling, so the poller that sees the scan canceled knows not to treat it as a failure
+		if err := ss.markSuperseded(older, job, reason); err != nil {
+			return err
+		}
+		if err := ss.cx1Client.CancelScanByID(older.ScanID); err != nil {
+			// Only a job still marked by this supersede is restored; one whose scan finished meanwhile keeps its state
+			if _, restoreErr := ss.jobs.Update(older.ID, func(stored *ScanJob) {
+				if stored.State != JobStateSuperseded || stored.SupersededBy != job.ID {
+					return
+				}
+				stored.State = JobStatePolling
+				stored.Error = ""
+				stored.SupersededBy = ""
+			}); restoreErr != nil {
+				ss.logger.Errorf("❌ Failed to restore scan job %s: %v", older.ID, restoreErr)
+			}
+			return fmt.Errorf("failed to cancel scan %s: %v", older.ScanID, err)
+		}
+	}
+
+	ss.logger.Infof("⏭️ Scan job %s (scan ID %s, commit_id %s) superseded by commit_id %s", older.ID, older.ScanID, older.CommitID, job.CommitID)
+
+	scan := &cx1.Scan{ScanID: older.ScanID, ProjectID: older.ProjectID, Branch: older.Branch, Status: "Canceled"}
+	ss.publishSuperseded(older, scan, previousStatus, job.CommitID)
+	return nil
+}
+
+// markSuperseded marks older superseded by job, provided it is still in the state it was listed in; a
+// scan that finished since keeps its terminal state
+func (ss *ScanService) markSuperseded(older, job *ScanJob, reason string) error {
+	stored, err := ss.jobs.Update(older.ID, func(stored *ScanJob) {
+		if stored.State != older.State {
+			return
+		}
+		stored.State = JobStateSuperseded
+		stored.Error = reason
+		stored.SupersededBy = job.ID
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark scan job superseded: %v", err)
+	}
+	if stored.State != JobStateSuperseded || stored.SupersededBy != job.ID {
+		return fmt.Errorf("it is %s now", stored.State)
+	}
+	return nil
+}
+
+// isSuperseded reports whether a job was marked superseded while it was being polled
+func (ss *ScanService) isSuperseded(jobID string) bool {
+	job, err := ss.jobs.Get(jobID)
+	return err == nil && job.State == JobStateSuperseded
+}
+
+// publishSuperseded sends scan.superseded for a job replaced by a scan of supersededBy
+func (ss *ScanService) publishSuperseded(job *ScanJob, scan *cx1.Scan, previousStatus, supersededBy string) {
+	now := time.Now().UTC()
+
+	v1 := WebhookPayload{
+		Event:       webhooks.EventScanSuperseded,
+		ScanID:      scan.ScanID,
+		CommitID:    job.CommitID,
+		ProjectName: job.ProjectName,
+		Branch:      scan.Branch,
+		Status:      scan.Status,
+		Timestamp:   now,
+		Details: map[string]interface{}{
+			"previous_status": previousStatus,
+			"superseded_by":   supersededBy,
+		},
+	}
+
+	v2 := newScanEventV2(job, scan, webhooks.EventScanSuperseded, now)
+	v2.PreviousStatus = previousStatus
+	v2.SupersededBy = supersededBy
+
+	ss.publishEvent(job, scan, webhooks.EventScanSuperseded, now, v1, v2)
+}
@@ -0,0 +1,73 @@
+package scans
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSupersedeFinishedScan(t *testing.T) {
+	ss, fake := newTestService(t)
+
+	submission, err := ss.StartStaticScanWithFile(testScanRequest(t, "commit-1"))
+	if err != nil {
+		t.Fatalf("StartStaticScanWithFile() = %v", err)
+	}
+	// The job as supersedeOlder listed it, before its scan finished
+	listed := *waitForJob(t, ss, submission.JobID, JobStatePolling)
+	fake.Complete(submission.ScanID)
+	waitForJob(t, ss, submission.JobID, JobStateWebhookDelivered)
+
+	newer := &ScanJob{ID: "newer", CommitID: "commit-2"}
+	if err := ss.supersede(&listed, newer); err == nil {
+		t.Fatalf("supersede() superseded a scan that already finished")
+	}
+	if job, _ := ss.jobs.Get(submission.JobID); job.State != JobStateWebhookDelivered || job.SupersededBy != "" {
+		t.Fatalf("job = %s, superseded by %q; want it left delivered", job.State, job.SupersededBy)
+	}
+}
+
+func TestSupersedeCancelFails(t *testing.T) {
+	ss, fake := newTestService(t)
+
+	submission, err := ss.StartStaticScanWithFile(testScanRequest(t, "commit-1"))
+	if err != nil {
+		t.Fatalf("StartStaticScanWithFile() = %v", err)
+	}
+	listed := *waitForJob(t, ss, submission.JobID, JobStatePolling)
+
+	fake.FailNext("CancelScanByID", errors.New("cx1 unavailable"))
+	if err := ss.supersede(&listed, &ScanJob{ID: "newer", CommitID: "commit-2"}); err == nil {
+		t.Fatalf("supersede() = nil, want the cancel failure")
+	}
+	if job, _ := ss.jobs.Get(submission.JobID); job.State != JobStatePolling || job.SupersededBy != "" || job.Error != "" {
+		t.Fatalf("job = %s, superseded by %q (%s); want it restored to polling", job.State, job.SupersededBy, job.Error)
+	}
+}
+
+func TestSupersedeQueuedJobThatMovedOn(t *testing.T) {
+	t.Setenv("SCAN_MAX_CONCURRENT", "1")
+	ss, _ := newTestService(t)
+
+	if _, err := ss.StartStaticScanWithFile(testScanRequest(t, "commit-1")); err != nil {
+		t.Fatalf("StartStaticScanWithFile() = %v", err)
+	}
+	queued, err := ss.StartStaticScanWithFile(testScanRequest(t, "commit-2"))
+	if err != nil || queued.Queue == nil {
+		t.Fatalf("StartStaticScanWithFile() = %+v, %v; want it queued", queued, err)
+	}
+	listed := *waitForJob(t, ss, queued.JobID, JobStateQueued)
+
+	// Failed by someone else after supersedeOlder listed it
+	if _, err := ss.jobs.SetState(queued.JobID, JobStateFailed, "canceled"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ss.supersede(&listed, &ScanJob{ID: "newer", CommitID: "commit-3"}); err == nil {
+		t.Fatalf("supersede() superseded a job that left the queue")
+	}
+	if job, _ := ss.jobs.Get(queued.JobID); job.State != JobStateFailed {
+		t.Fatalf("job = %s, want it left failed", job.State)
+	}
+	if _, ok := ss.scheduler.Position(queued.JobID); ok {
+		t.Fatalf("supersede() put a failed job back in the queue")
+	}
+}
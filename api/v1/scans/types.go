@@ -1,164 +1,271 @@
-package scans
-
-import (
-	"io"
-	"time"
-
-	cx1 "github.com/madhatkul/CxWrapper-v2/Cx1ClientGo"
-)
-
-// Base scan request structure
-type ScanRequest struct {
-	Branch      string `json:"branch" binding:"required"`
-	CommitID    string `json:"commit_id" binding:"required"`
-	ProjectName string `json:"project_name" binding:"required"`
-}
-
-// Form request for static scans (matches OpenAPI spec)
-type StaticScanFormRequest struct {
-	ProjectName string `form:"project_name" binding:"required"`
-	ScanTypes   string `form:"scan_types"` // comma-separated: sast,sca,secrets,kics,containersec,apisec
-	Branch      string `form:"branch" binding:"required"`
-	// Config      string `form:"config"`
-	IsFastScan string `form:"is_fast_scan"`
-	CommitID   string `form:"commit_id" binding:"required"`
-	Tags       string `form:"tags"`
-	// ZipFile is handled by multipart form, not included in struct
-}
-
-// Internal request structure with file contents
-type StaticScanRequestWithFile struct {
-	AppName     string
-	ProjectName string
-	Branch      string
-	CommitID    string
-	ScanTypes   []string
-	IsFastScan  bool
-	Preset      string
-	Tags        map[string]string
-	// FileContents []byte
-	File     io.Reader
-	FileSize int64
-	FileName string
-}
-
-// Response structures for API
-type ScanResponse struct {
-	ScanID  string `json:"scan_id"`
-	Status  string `json:"status"`
-	Message string `json:"message"`
-}
-
-type ErrorResponse struct {
-	Error     string `json:"error"`
-	Details   string `json:"details,omitempty"`
-	Timestamp string `json:"timestamp"`
-	Path      string `json:"path"`
-}
-
-type ScanLogsResponse struct {
-	ScanID string   `json:"scan_id"`
-	Logs   []string `json:"logs"`
-}
-
-// Health check response
-type HealthResponse struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
-}
-
-// ScanListRequest represents the request parameters for listing scans
-type ScanListRequest struct {
-	ProjectID   string     `json:"project_id,omitempty"`
-	ProjectName string     `json:"project_name,omitempty"`
-	ScanType    string     `json:"scan_type,omitempty"`
-	Status      []string   `json:"status,omitempty"`
-	Limit       int        `json:"limit,omitempty"`
-	Offset      int        `json:"offset,omitempty"`
-	FromDate    *time.Time `json:"from_date,omitempty"`
-	ToDate      *time.Time `json:"to_date,omitempty"`
-}
-
-// ScanListResponse represents the response for listing scans
-type ScanListResponse struct {
-	Scans  interface{} `json:"scans"` // Can be []cx1.Scan or []ScanStatusInternal
-	Total  int         `json:"total"`
-	Limit  int         `json:"limit"`
-	Offset int         `json:"offset"`
-}
-
-type ListScansRequest struct {
-	ProjectName string `json:"project_name,omitempty" form:"project_name"`
-	CommitID    string `json:"commit_id,omitempty" form:"commit_id"`
-	Limit       int    `json:"limit,omitempty" form:"limit"`
-	Offset      int    `json:"offset,omitempty" form:"offset"`
-}
-
-type ListScansResponse struct {
-	Scans  []cx1.Scan `json:"scans"`
-	Total  int        `json:"total"`
-	Limit  int        `json:"limit"`
-	Offset int        `json:"offset"`
-}
-
-type SimpleScanStatus struct {
-	ScanID string `json:"scan_id"`
-	Status string `json:"status"`
-}
-
-// WebhookPayload represents the data sent to the webhook
-type WebhookPayload struct {
-	ScanID    string                 `json:"scan_id"`
-	Status    string                 `json:"status"`
-	Timestamp time.Time              `json:"timestamp"`
-	Details   map[string]interface{} `json:"details,omitempty"`
-}
-
-// WebhookConfig holds webhook configuration
-type WebhookConfig struct {
-	URL     string
-	Timeout time.Duration
-	Headers map[string]string
-}
-type AllScansResponse struct {
-	CommitID    string           `json:"commit_id"`
-	ProjectName string           `json:"project_name,omitempty"`
-	TotalScans  int              `json:"total_scans"`
-	Summary     AllScansSummary  `json:"summary"`
-	Scans       CategorizedScans `json:"scans"`
-}
-
-type CategorizedScans struct {
-	Fast *ScanResultResponse `json:"fast,omitempty"`
-	Full *ScanResultResponse `json:"full,omitempty"`
-}
-
-type AllScansSummary struct {
-	CompletedScans  int `json:"completed_scans"`
-	BreakBuildCount int `json:"break_build_count"`
-}
-
-// Update ScanResultResponse to include optional fields
-type ScanResultResponse struct {
-	Link          string      `json:"link"`
-	IsFastScan    bool        `json:"is_fast_scan"`
-	BreakBuild    bool        `json:"is_policy_blocked"`
-	ScanID        string      `json:"scan_id"`
-	CommitID      string      `json:"commit_id"`
-	ProjectID     string      `json:"project_id"`
-	Branch        string      `json:"branch"`
-	Status        string      `json:"status"`
-	CreatedAt     string      `json:"created_at"`
-	UpdatedAt     string      `json:"updated_at"`
-	Tags          interface{} `json:"tags"`
-	Results       interface{} `json:"results"`
-	Summary       Summary     `json:"summary"`
-	PolicyWarning *string     `json:"policy_warning,omitempty"`
-	Error         *string     `json:"error,omitempty"`
-	StatusMessage *string     `json:"status_message,omitempty"`
-}
-
-// Summary represents the summary section of the scan response
-type Summary struct {
-	TotalResults int `json:"total_results"`
-}
+package scans
+
+import (
+	"io"
+	"time"
+
+	cx1 "github.com/madhatkul/CxWrapper-v2/Cx1ClientGo"
+	"github.com/madhatkul/CxWrapper-v2/api/archive"
+	"github.com/madhatkul/CxWrapper-v2/api/gitsource"
+	"github.com/madhatkul/CxWrapper-v2/api/v1/scans/reports"
+)
+
+// Base scan request structure
+type ScanRequest struct {
+	Branch      string `json:"branch" binding:"required"`
+	CommitID    string `json:"commit_id" binding:"required"`
+	ProjectName string `json:"project_name" binding:"required"`
+}
+
+// Form request for static scans (matches OpenAPI spec)
+type StaticScanFormRequest struct {
+	ProjectName string `form:"project_name" binding:"required"`
+	ScanTypes   string `form:"scan_types"` // comma-separated: sast,sca,secrets,kics,containersec,apisec
+	Branch      string `form:"branch" binding:"required"`
+	// Config      string `form:"config"`
+	IsFastScan string `form:"is_fast_scan"`
+	CommitID   string `form:"commit_id" binding:"required"`
+	Tags       string `form:"tags"`
+	// TargetBranch is the branch a feature branch will merge into; findings not on it are flagged as new
+	TargetBranch string `form:"target_branch"`
+	// ZipFile is handled by multipart form, not included in struct
+}
+
+// Internal request structure with file contents
+type StaticScanRequestWithFile struct {
+	AppName      string
+	ProjectName  string
+	Branch       string
+	CommitID     string
+	ScanTypes    []string
+	IsFastScan   bool
+	Preset       string
+	TargetBranch string
+	Tags         map[string]string
+	// IdempotencyKey makes retries of the same request return the scan the first one started
+	IdempotencyKey string
+	// Force starts a new scan even if an equivalent one is already queued or running
+	Force bool
+	// Excludes are globs stripped from the archive on top of the project's exclude rules
+	Excludes []string
+	// CancelSuperseded, when set, overrides the project's policy of canceling older scans of the same branch
+	CancelSuperseded *bool
+	// Source, when set, is fetched and archived by the wrapper in place of File
+	Source *gitsource.Source
+	// FileContents []byte
+	File     io.Reader
+	FileSize int64
+	FileName string
+}
+
+// Response structures for API
+type ScanResponse struct {
+	ScanID    string `json:"scan_id"`
+	Status    string `json:"status"`
+	Message   string `json:"message"`
+	Duplicate bool   `json:"duplicate,omitempty"`
+	// JobID and Queue are set when the scan waits in the wrapper's queue and has no scan ID yet
+	JobID string       `json:"job_id,omitempty"`
+	Queue *QueueStatus `json:"queue,omitempty"`
+	// Archive reports what was stripped from the uploaded archive before it was sent to Cx1
+	Archive *archive.Report `json:"archive,omitempty"`
+	// Superseded lists the older scans of the branch canceled in favour of this one
+	Superseded []SupersededScan `json:"superseded,omitempty"`
+}
+
+// ScanSubmission is the outcome of a scan request: a newly started scan or an existing one returned instead
+type ScanSubmission struct {
+	ScanID string
+	JobID  string
+	// Duplicate is set when an equivalent scan was already queued or running
+	Duplicate bool
+	// Replayed is set when the Idempotency-Key had already been used for this request
+	Replayed bool
+	// Queue is set while the scan waits in the wrapper's queue; ScanID is empty until it starts
+	Queue *QueueStatus
+	// Archive is set when a new scan was started
+	Archive *archive.Report
+	// Superseded lists the older scans canceled in favour of a new one
+	Superseded []SupersededScan
+}
+
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	Details   string `json:"details,omitempty"`
+	Timestamp string `json:"timestamp"`
+	Path      string `json:"path"`
+}
+
+type ScanLogsResponse struct {
+	ScanID string   `json:"scan_id"`
+	Logs   []string `json:"logs"`
+}
+
+// Health check response
+type HealthResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// ScanListRequest represents the request parameters for listing scans
+type ScanListRequest struct {
+	ProjectID   string     `json:"project_id,omitempty"`
+	ProjectName string     `json:"project_name,omitempty"`
+	ScanType    string     `json:"scan_type,omitempty"`
+	Status      []string   `json:"status,omitempty"`
+	Limit       int        `json:"limit,omitempty"`
+	Offset      int        `json:"offset,omitempty"`
+	FromDate    *time.Time `json:"from_date,omitempty"`
+	ToDate      *time.Time `json:"to_date,omitempty"`
+}
+
+// ScanListResponse represents the response for listing scans
+type ScanListResponse struct {
+	Scans  interface{} `json:"scans"` // Can be []cx1.Scan or []ScanStatusInternal
+	Total  int         `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+type ListScansRequest struct {
+	ProjectName string `json:"project_name,omitempty" form:"project_name"`
+	CommitID    string `json:"commit_id,omitempty" form:"commit_id"`
+	Limit       int    `json:"limit,omitempty" form:"limit"`
+	Offset      int    `json:"offset,omitempty" form:"offset"`
+}
+
+type ListScansResponse struct {
+	Scans  []cx1.Scan `json:"scans"`
+	Total  int        `json:"total"`
+	Limit  int        `json:"limit"`
+	Offset int        `json:"offset"`
+}
+
+type SimpleScanStatus struct {
+	ScanID string `json:"scan_id"`
+	Status string `json:"status"`
+	// JobID and Queue are set when the commit's latest scan waits in the wrapper's queue
+	JobID string       `json:"job_id,omitempty"`
+	Queue *QueueStatus `json:"queue,omitempty"`
+}
+
+// StatusUpdate is pushed to status stream subscribers every time a scan's status or engine statuses change
+type StatusUpdate struct {
+	ScanID      string                  `json:"scan_id"`
+	CommitID    string                  `json:"commit_id"`
+	ProjectName string                  `json:"project_name,omitempty"`
+	Branch      string                  `json:"branch,omitempty"`
+	Status      string                  `json:"status"`
+	Engines     []cx1.ScanStatusDetails `json:"engines,omitempty"`
+	Terminal    bool                    `json:"terminal"`
+	Error       string                  `json:"error,omitempty"`
+	Timestamp   time.Time               `json:"timestamp"`
+}
+
+// WaitScanResponse is the body of the wait endpoint: the commit's results plus what a CI script should do
+type WaitScanResponse struct {
+	*AllScansResponse
+	TimedOut bool `json:"timed_out"`
+	// ExitRecommendation is one of pass, fail (blocked by policy), error (a scan did not complete) or timeout
+	ExitRecommendation string `json:"exit_recommendation"`
+	ExitCode           int    `json:"exit_code"`
+}
+
+// WebhookPayload represents the data sent to the webhook for scan lifecycle events
+type WebhookPayload struct {
+	Event       string                 `json:"event"`
+	ScanID      string                 `json:"scan_id"`
+	CommitID    string                 `json:"commit_id"`
+	ProjectName string                 `json:"project_name"`
+	Branch      string                 `json:"branch"`
+	Status      string                 `json:"status"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Details     map[string]interface{} `json:"details,omitempty"`
+}
+
+type AllScansResponse struct {
+	CommitID    string           `json:"commit_id"`
+	ProjectName string           `json:"project_name,omitempty"`
+	TotalScans  int              `json:"total_scans"`
+	Summary     AllScansSummary  `json:"summary"`
+	Scans       CategorizedScans `json:"scans"`
+}
+
+type CategorizedScans struct {
+	Fast *ScanResultResponse `json:"fast,omitempty"`
+	Full *ScanResultResponse `json:"full,omitempty"`
+}
+
+type AllScansSummary struct {
+	CompletedScans  int `json:"completed_scans"`
+	BreakBuildCount int `json:"break_build_count"`
+}
+
+// Update ScanResultResponse to include optional fields
+type ScanResultResponse struct {
+	Link       string      `json:"link"`
+	IsFastScan bool        `json:"is_fast_scan"`
+	BreakBuild bool        `json:"is_policy_blocked"`
+	ScanID     string      `json:"scan_id"`
+	CommitID   string      `json:"commit_id"`
+	ProjectID  string      `json:"project_id"`
+	Branch     string      `json:"branch"`
+	Status     string      `json:"status"`
+	CreatedAt  string      `json:"created_at"`
+	UpdatedAt  string      `json:"updated_at"`
+	Tags       interface{} `json:"tags"`
+	Results    interface{} `json:"results"`
+	Summary    Summary     `json:"summary"`
+	// TargetBranch and BaselineScanID are set when results are annotated with is_new
+	TargetBranch   string  `json:"target_branch,omitempty"`
+	BaselineScanID string  `json:"baseline_scan_id,omitempty"`
+	BaselineError  *string `json:"baseline_error,omitempty"`
+	PolicyWarning  *string `json:"policy_warning,omitempty"`
+	Error          *string `json:"error,omitempty"`
+	StatusMessage  *string `json:"status_message,omitempty"`
+
+	// resultSet keeps the typed results once Results has been replaced by the annotated form
+	resultSet *cx1.ScanResultSet
+}
+
+// Summary represents the summary section of the scan response
+type Summary struct {
+	TotalResults int  `json:"total_results"`
+	NewResults   *int `json:"new_results,omitempty"`
+}
+
+// CompareScansRequest selects a scan (by ID, or the latest completed scan of a commit) and the baseline
+// it is compared with (by ID, or the latest completed scan on a branch of the same project)
+type CompareScansRequest struct {
+	ScanID         string `form:"scan_id"`
+	CommitID       string `form:"commit_id"`
+	ProjectName    string `form:"project_name"`
+	BaselineScanID string `form:"baseline_scan_id"`
+	BaselineBranch string `form:"baseline_branch"`
+}
+
+type ScanRef struct {
+	ScanID    string `json:"scan_id"`
+	ProjectID string `json:"project_id"`
+	Branch    string `json:"branch"`
+	CommitID  string `json:"commit_id,omitempty"`
+	Status    string `json:"status"`
+	CreatedAt string `json:"created_at"`
+}
+
+type CompareSummary struct {
+	New       int `json:"new"`
+	Fixed     int `json:"fixed"`
+	Unchanged int `json:"unchanged"`
+}
+
+// CompareScansResponse lists findings keyed by similarity ID: new in the scan, fixed since the baseline,
+// and present in both
+type CompareScansResponse struct {
+	Scan      ScanRef           `json:"scan"`
+	Baseline  ScanRef           `json:"baseline"`
+	Summary   CompareSummary    `json:"summary"`
+	New       []reports.Finding `json:"new"`
+	Fixed     []reports.Finding `json:"fixed"`
+	Unchanged []reports.Finding `json:"unchanged"`
+}
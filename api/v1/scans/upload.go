@@ -0,0 +1,184 @@
+// api/v1/scans/upload.go
+package scans
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"strconv"
+
+	"github.com/madhatkul/CxWrapper-v2/api/archive"
+)
+
+const (
+	defaultMaxUploadSize = 1 << 30 // 1 GiB
+	// maxFormFieldSize bounds every non-file part of the scan form
+	maxFormFieldSize = 64 << 10
+	// maxFormOverhead is what the form may add to the archive: field parts, boundaries and part headers
+	maxFormOverhead = 1 << 20
+)
+
+var (
+	// ErrUploadTooLarge is returned when an archive exceeds the maximum upload size
+	ErrUploadTooLarge = errors.New("upload exceeds the maximum allowed size")
+	// ErrNothingToScan is returned when the exclude rules strip every file from an archive
+	ErrNothingToScan = errors.New("no files left to scan")
+)
+
+// maxUploadSizeFromEnv reads SCAN_MAX_UPLOAD_BYTES, falling back to 1 GiB
+func maxUploadSizeFromEnv() int64 {
+	if v, err := strconv.ParseInt(os.Getenv("SCAN_MAX_UPLOAD_BYTES"), 10, 64); err == nil && v > 0 {
+		return v
+	}
+	return defaultMaxUploadSize
+}
+
+// scanForm is a multipart scan request read up to its file part. Fields sent after the file are not seen,
+// so clients must send zip_file last.
+type scanForm struct {
+	fields map[string]string
+	// file is the zip_file part, positioned at its first byte; nil when the form has no file
+	file *multipart.Part
+}
+
+func (f *scanForm) value(name string) string {
+	return f.fields[name]
+}
+
+// readScanForm reads form fields until the part named fileField, leaving the file unread
+func readScanForm(reader *multipart.Reader, fileField string) (*scanForm, error) {
+	form := &scanForm{fields: make(map[string]string)}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return form, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read multipart form: %v", err)
+		}
+
+		if part.FormName() == fileField {
+			form.file = part
+			return form, nil
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxFormFieldSize+1))
+		part.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read form field %s: %v", part.FormName(), err)
+		}
+		if len(value) > maxFormFieldSize {
+			return nil, fmt.Errorf("form field %s exceeds %d bytes", part.FormName(), maxFormFieldSize)
+		}
+		form.fields[part.FormName()] = string(value)
+	}
+}
+
+// sizedReader passes an upload through while enforcing its size: it fails with ErrUploadTooLarge past
+// limit and, when size is known, if the stream is longer or shorter than announced
+type sizedReader struct {
+	r     io.Reader
+	size  int64 // expected size, or -1 when unknown
+	limit int64
+	read  int64
+}
+
+func newSizedReader(r io.Reader, size, limit int64) *sizedReader {
+	return &sizedReader{r: r, size: size, limit: limit}
+}
+
+func (sr *sizedReader) Read(p []byte) (int, error) {
+	n, err := sr.r.Read(p)
+	sr.read += int64(n)
+
+	if sr.read > sr.limit {
+		return n, fmt.Errorf("%w: more than %d bytes", ErrUploadTooLarge, sr.limit)
+	}
+	if sr.size >= 0 && sr.read > sr.size {
+		return n, fmt.Errorf("upload is larger than the announced %d bytes", sr.size)
+	}
+	if err == io.EOF && sr.size >= 0 && sr.read < sr.size {
+		return n, fmt.Errorf("upload ended after %d of the announced %d bytes", sr.read, sr.size)
+	}
+	return n, err
+}
+
+// spoolUpload copies an upload of unknown size to a temporary file so its size is known before it is
+// sent to Cx1. The caller must close and remove the file.
+func spoolUpload(r io.Reader, limit int64) (*os.File, int64, error) {
+	tmp, err := os.CreateTemp("", "cxwrapper-upload-*.zip")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create temporary file: %v", err)
+	}
+
+	size, err := io.Copy(tmp, newSizedReader(r, -1, limit))
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, 0, err
+	}
+
+	return tmp, size, nil
+}
+
+// sanitizeArchive checks the request's archive, a zip, tar, tar.gz or tar.zst told apart by its first bytes,
+// and re-packs it as a zip without the excluded entries into a temporary file, positioned at its start.
+// The caller must close and remove it.
+func (ss *ScanService) sanitizeArchive(req StaticScanRequestWithFile) (*os.File, *archive.Report, error) {
+	br := bufio.NewReader(req.File)
+	format, err := archive.Detect(br)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dst, err := os.CreateTemp("", "cxwrapper-sanitized-*.zip")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temporary file: %v", err)
+	}
+
+	options := ss.archiveRules.For(req.ProjectName, req.Excludes...)
+	var report *archive.Report
+	if format == archive.FormatZip {
+		report, err = sanitizeZip(req.File, br, req.FileSize, dst, options)
+	} else {
+		// Tar archives are converted as they stream in, without being spooled first
+		report, err = archive.FromTar(br, format, dst, options)
+	}
+	if err == nil && report.KeptFiles == 0 {
+		err = fmt.Errorf("%w: all %d files were excluded", ErrNothingToScan, report.StrippedFiles)
+	}
+	if err == nil {
+		_, err = dst.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return nil, nil, err
+	}
+
+	return dst, report, nil
+}
+
+// sanitizeZip sanitizes a zip archive. A zip is read from its central directory at the end, so unless
+// file is already on disk, the archive is spooled to a temporary file first; br holds what Detect read.
+func sanitizeZip(file io.Reader, br *bufio.Reader, size int64, dst io.Writer, options archive.Options) (*archive.Report, error) {
+	src, ok := file.(*os.File)
+	if !ok {
+		spooled, _, err := spoolUpload(br, size)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(spooled.Name())
+		defer spooled.Close()
+		src = spooled
+	}
+
+	return archive.Sanitize(src, size, dst, options)
+}
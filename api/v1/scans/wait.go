@@ -0,0 +1,182 @@
+// api/v1/scans/wait.go
+package scans
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultWaitTimeout = 10 * time.Minute
+	maxWaitTimeout     = 60 * time.Minute
+)
+
+// Exit recommendations returned by the wait endpoint, with the process exit code CI scripts should use
+const (
+	ExitPass    = "pass"
+	ExitFail    = "fail"
+	ExitError   = "error"
+	ExitTimeout = "timeout"
+)
+
+var exitCodes = map[string]int{
+	ExitPass:    0,
+	ExitFail:    1,
+	ExitError:   2,
+	ExitTimeout: 3,
+}
+
+// resultCall is a GetAllScanResultsByCommitID call shared by the waiters of a commit
+type resultCall struct {
+	done     chan struct{}
+	response *AllScansResponse
+	err      error
+}
+
+// parseWaitTimeout accepts a duration such as "90s" or "5m", or a number of seconds. Empty means the default.
+func parseWaitTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultWaitTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, fmt.Errorf("invalid timeout %q: expected a duration such as 90s or a number of seconds", value)
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	if timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q: must be positive", value)
+	}
+	if timeout > maxWaitTimeout {
+		return 0, fmt.Errorf("invalid timeout %q: must not exceed %s", value, maxWaitTimeout)
+	}
+	return timeout, nil
+}
+
+// WaitForCommitResults blocks until every scan of the commit is terminal, the timeout elapses or ctx is
+// done, then returns the commit's results with an exit recommendation. The scans expected are those of
+// the commit's jobs, so a finished fast scan does not end the wait while the full scan is still queued.
+// Waiters share the status polling of the commit and, when they finish together, the fetch of its results.
+func (ss *ScanService) WaitForCommitResults(ctx context.Context, commitID, projectName string, timeout time.Duration) (*WaitScanResponse, error) {
+	updates, snapshot, unsubscribe := ss.SubscribeStatus(commitID, projectName)
+	defer func() { unsubscribe() }()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	// A queued job that fails to start publishes no update, so the jobs are looked at again on every poll
+	ticker := time.NewTicker(ss.pollInterval)
+	defer ticker.Stop()
+
+	progress := newCommitProgress()
+	for _, update := range snapshot {
+		progress.observe(update)
+	}
+	finished, err := ss.commitFinished(progress, commitID, projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	timedOut := false
+	for !finished && !timedOut {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			timedOut = true
+			continue
+		case <-ticker.C:
+		case update, ok := <-updates:
+			if !ok {
+				// Dropped by the broker for falling behind; resubscribe and continue from the current state
+				updates, snapshot, unsubscribe = ss.SubscribeStatus(commitID, projectName)
+				for _, update := range snapshot {
+					progress.observe(update)
+				}
+				break
+			}
+			if update.Error != "" {
+				return nil, fmt.Errorf("failed to follow scans for commit_id %s: %s", commitID, update.Error)
+			}
+			if projectName != "" && update.ProjectName != "" && update.ProjectName != projectName {
+				continue
+			}
+			progress.observe(update)
+		}
+
+		if finished, err = ss.commitFinished(progress, commitID, projectName); err != nil {
+			return nil, err
+		}
+	}
+
+	results, err := ss.sharedCommitResults(commitID, projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &WaitScanResponse{
+		AllScansResponse:   results,
+		TimedOut:           timedOut,
+		ExitRecommendation: exitRecommendation(results, timedOut),
+	}
+	response.ExitCode = exitCodes[response.ExitRecommendation]
+
+	ss.logger.Infof("⏱️ Wait for commit_id %s finished: %s (timed out: %t)", commitID, response.ExitRecommendation, timedOut)
+
+	return response, nil
+}
+
+// commitFinished reports whether the wait for a commit is over, given the scans seen and the commit's jobs
+func (ss *ScanService) commitFinished(progress *commitProgress, commitID, projectName string) (bool, error) {
+	jobs, err := ss.jobs.ForCommit(commitID, projectName)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up scan jobs for commit_id %s: %w", commitID, err)
+	}
+	return progress.done(jobs), nil
+}
+
+// sharedCommitResults fetches the results of a commit once for every waiter asking at the same time
+func (ss *ScanService) sharedCommitResults(commitID, projectName string) (*AllScansResponse, error) {
+	key := commitID + "\x00" + projectName
+
+	ss.resultsMu.Lock()
+	if call, ok := ss.resultCalls[key]; ok {
+		ss.resultsMu.Unlock()
+		<-call.done
+		return call.response, call.err
+	}
+	call := &resultCall{done: make(chan struct{})}
+	ss.resultCalls[key] = call
+	ss.resultsMu.Unlock()
+
+	call.response, call.err = ss.GetAllScanResultsByCommitID(commitID, projectName)
+
+	ss.resultsMu.Lock()
+	delete(ss.resultCalls, key)
+	ss.resultsMu.Unlock()
+	close(call.done)
+
+	return call.response, call.err
+}
+
+// exitRecommendation tells a CI script what to do with the build: fail it when a scan is blocked by policy,
+// error when a scan did not complete, pass otherwise
+func exitRecommendation(results *AllScansResponse, timedOut bool) string {
+	if results.Summary.BreakBuildCount > 0 {
+		return ExitFail
+	}
+	if timedOut {
+		return ExitTimeout
+	}
+	for _, scan := range []*ScanResultResponse{results.Scans.Fast, results.Scans.Full} {
+		if scan != nil && scan.Status != "Completed" {
+			return ExitError
+		}
+	}
+	return ExitPass
+}
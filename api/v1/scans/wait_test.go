@@ -0,0 +1,119 @@
+package scans
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCommitProgressDone(t *testing.T) {
+	tests := []struct {
+		name    string
+		updates []StatusUpdate
+		jobs    []ScanJob
+		want    bool
+	}{
+		{name: "nothing seen", want: false},
+		{name: "scan running", updates: []StatusUpdate{{ScanID: "fast", Status: "Running"}}, want: false},
+		{name: "scans without jobs finished", updates: []StatusUpdate{{ScanID: "fast", Status: "Completed", Terminal: true}}, want: true},
+		{
+			name:    "full scan still queued",
+			updates: []StatusUpdate{{ScanID: "fast", Status: "Completed", Terminal: true}},
+			jobs:    []ScanJob{{ScanID: "fast", State: JobStateWebhookDelivered}, {State: JobStateQueued}},
+			want:    false,
+		},
+		{
+			name:    "full scan not seen yet",
+			updates: []StatusUpdate{{ScanID: "fast", Status: "Completed", Terminal: true}},
+			jobs:    []ScanJob{{ScanID: "fast", State: JobStateWebhookDelivered}, {ScanID: "full", State: JobStatePolling}},
+			want:    false,
+		},
+		{
+			name:    "both finished",
+			updates: []StatusUpdate{{ScanID: "fast", Status: "Completed", Terminal: true}, {ScanID: "full", Status: "Failed", Terminal: true}},
+			jobs:    []ScanJob{{ScanID: "fast", State: JobStateWebhookDelivered}, {ScanID: "full", State: JobStatePolling}},
+			want:    true,
+		},
+		{
+			name:    "full scan finished before the wait began",
+			updates: []StatusUpdate{{ScanID: "fast", Status: "Completed", Terminal: true}},
+			jobs:    []ScanJob{{ScanID: "fast", State: JobStateWebhookDelivered}, {ScanID: "full", State: JobStateResultsFetched}},
+			want:    true,
+		},
+		{name: "queued job failed to start", jobs: []ScanJob{{State: JobStateFailed}}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			progress := newCommitProgress()
+			for _, update := range tt.updates {
+				progress.observe(update)
+			}
+			if got := progress.done(tt.jobs); got != tt.want {
+				t.Fatalf("done() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitForQueuedFullScan(t *testing.T) {
+	t.Setenv("SCAN_MAX_CONCURRENT", "1")
+	t.Setenv("SCAN_MAX_CONCURRENT_PER_APP", "")
+	t.Setenv("SCAN_APP_CONCURRENCY", "")
+	t.Setenv("SCAN_DEFAULT_BRANCHES", "")
+	ss, fake := newTestService(t)
+
+	fastRequest := testScanRequest(t, "commit-1")
+	fastRequest.IsFastScan = true
+	fast, err := ss.StartStaticScanWithFile(fastRequest)
+	if err != nil {
+		t.Fatalf("StartStaticScanWithFile(fast) = %v", err)
+	}
+	waitForJob(t, ss, fast.JobID, JobStatePolling)
+	// The only slot is taken, so the full scan waits in the queue without a scan ID
+	full, err := ss.StartStaticScanWithFile(testScanRequest(t, "commit-1"))
+	if err != nil {
+		t.Fatalf("StartStaticScanWithFile(full) = %v", err)
+	}
+	if full.Queue == nil {
+		t.Fatalf("full submission = %+v, want it queued", full)
+	}
+
+	type result struct {
+		response *WaitScanResponse
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		response, err := ss.WaitForCommitResults(context.Background(), "commit-1", "", 5*time.Second)
+		done <- result{response, err}
+	}()
+
+	fake.Complete(fast.ScanID)
+	waitForJob(t, ss, fast.JobID, JobStateWebhookDelivered, JobStateFailed)
+	select {
+	case r := <-done:
+		t.Fatalf("WaitForCommitResults() returned %+v, %v while the full scan had not run", r.response, r.err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	job := waitForJob(t, ss, full.JobID, JobStatePolling)
+	fake.Complete(job.ScanID)
+
+	var r result
+	select {
+	case r = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("WaitForCommitResults() did not return once both scans finished")
+	}
+	if r.err != nil {
+		t.Fatalf("WaitForCommitResults() = %v", r.err)
+	}
+	if r.response.TimedOut || r.response.ExitRecommendation != ExitPass {
+		t.Fatalf("WaitForCommitResults() = %s (timed out: %t), want %s", r.response.ExitRecommendation, r.response.TimedOut, ExitPass)
+	}
+	scans := r.response.Scans
+	if scans.Fast == nil || scans.Fast.ScanID != fast.ScanID || scans.Full == nil || scans.Full.ScanID != job.ScanID {
+		t.Fatalf("WaitForCommitResults() scans = %+v, want fast %s and full %s", scans, fast.ScanID, job.ScanID)
+	}
+}
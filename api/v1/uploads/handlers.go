@@ -0,0 +1,235 @@
+// api/v1/uploads/handlers.go
+package uploads
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/madhatkul/CxWrapper-v2/util"
+)
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,expiration,checksum,termination"
+
+	// contentTypeOffset is the media type of PATCH bodies in the tus protocol
+	contentTypeOffset = "application/offset+octet-stream"
+
+	// statusChecksumMismatch is the status tus defines for a chunk that fails its checksum
+	statusChecksumMismatch = 460
+)
+
+type UploadHandler struct {
+	service *UploadService
+	logger  util.Logger
+}
+
+func NewUploadHandler(service *UploadService, logger util.Logger) *UploadHandler {
+	return &UploadHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes registers the resumable upload routes, a subset of the tus 1.0.0 protocol, with the given router group
+func (uh *UploadHandler) RegisterRoutes(v1 *gin.RouterGroup) {
+	uploads := v1.Group("/uploads")
+	uploads.Use(tusHeaders)
+	{
+		uploads.OPTIONS("", uh.Options)
+		uploads.POST("", uh.CreateUpload)
+		uploads.HEAD("/:id", uh.GetUploadOffset)
+		uploads.GET("/:id", uh.GetUpload)
+		uploads.PATCH("/:id", uh.WriteChunk)
+		uploads.DELETE("/:id", uh.DeleteUpload)
+	}
+}
+
+// tusHeaders sets Tus-Resumable on every response and rejects clients speaking another protocol version
+func tusHeaders(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+
+	if version := c.GetHeader("Tus-Resumable"); version != "" && version != tusVersion && c.Request.Method != http.MethodOptions {
+		c.Header("Tus-Version", tusVersion)
+		c.AbortWithStatusJSON(http.StatusPreconditionFailed, ErrorResponse{
+			Error:     "Unsupported tus version",
+			Details:   fmt.Sprintf("only tus %s is supported", tusVersion),
+			Timestamp: time.Now().Format(time.RFC3339),
+			Path:      c.Request.URL.Path,
+		})
+		return
+	}
+	c.Next()
+}
+
+// Options handles OPTIONS /v1/uploads and advertises what the server supports
+func (uh *UploadHandler) Options(c *gin.Context) {
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(uh.service.MaxSize(), 10))
+	c.Header("Tus-Checksum-Algorithm", "sha256")
+	c.Status(http.StatusNoContent)
+}
+
+// CreateUpload handles POST /v1/uploads. Upload-Length is required; Upload-Metadata may carry "filename"
+// and "checksum" ("sha256 <hex or base64>") for the whole archive.
+func (uh *UploadHandler) CreateUpload(c *gin.Context) {
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		uh.respondError(c, http.StatusBadRequest, "Invalid Upload-Length header", "Upload-Length must be a positive number of bytes")
+		return
+	}
+
+	metadata, err := parseMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		uh.respondError(c, http.StatusBadRequest, "Invalid Upload-Metadata header", err.Error())
+		return
+	}
+
+	upload, err := uh.service.Create(length, metadata)
+	if err != nil {
+		uh.logger.Errorf("❌ Failed to create upload: %v", err)
+		uh.respondError(c, statusFor(err), "Failed to create upload", err.Error())
+		return
+	}
+
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+upload.ID)
+	setUploadHeaders(c, upload)
+	c.JSON(http.StatusCreated, toUploadResponse(upload))
+}
+
+// GetUploadOffset handles HEAD /v1/uploads/:id, which clients use to find where to resume
+func (uh *UploadHandler) GetUploadOffset(c *gin.Context) {
+	upload, err := uh.service.Get(c.Param("id"))
+	if err != nil {
+		c.Status(statusFor(err))
+		return
+	}
+
+	setUploadHeaders(c, upload)
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+}
+
+// GetUpload handles GET /v1/uploads/:id
+func (uh *UploadHandler) GetUpload(c *gin.Context) {
+	upload, err := uh.service.Get(c.Param("id"))
+	if err != nil {
+		uh.respondError(c, statusFor(err), "Failed to get upload", err.Error())
+		return
+	}
+
+	setUploadHeaders(c, upload)
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, toUploadResponse(upload))
+}
+
+// WriteChunk handles PATCH /v1/uploads/:id. Upload-Offset must equal the upload's current offset and
+// Upload-Checksum, when sent, must match the chunk.
+func (uh *UploadHandler) WriteChunk(c *gin.Context) {
+	if contentType := c.ContentType(); contentType != contentTypeOffset {
+		uh.respondError(c, http.StatusUnsupportedMediaType, "Invalid Content-Type", "Content-Type must be "+contentTypeOffset)
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		uh.respondError(c, http.StatusBadRequest, "Invalid Upload-Offset header", "Upload-Offset must be a non-negative number of bytes")
+		return
+	}
+
+	upload, err := uh.service.WriteChunk(c.Param("id"), offset, c.Request.Body, c.GetHeader("Upload-Checksum"))
+	if upload != nil {
+		setUploadHeaders(c, upload)
+	}
+	if err != nil {
+		uh.logger.Warnf("⚠️ Failed to write chunk at offset %d of upload %s: %v", offset, c.Param("id"), err)
+		uh.respondError(c, statusFor(err), "Failed to write chunk", err.Error())
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteUpload handles DELETE /v1/uploads/:id
+func (uh *UploadHandler) DeleteUpload(c *gin.Context) {
+	if err := uh.service.Terminate(c.Param("id")); err != nil {
+		uh.respondError(c, statusFor(err), "Failed to delete upload", err.Error())
+		return
+	}
+
+	uh.logger.Infof("🗑️ Upload %s deleted", c.Param("id"))
+	c.Status(http.StatusNoContent)
+}
+
+func (uh *UploadHandler) respondError(c *gin.Context, statusCode int, message, details string) {
+	c.JSON(statusCode, ErrorResponse{
+		Error:     message,
+		Details:   details,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Path:      c.Request.URL.Path,
+	})
+}
+
+// statusFor maps service errors to the status codes tus clients expect
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, ErrUploadNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrUploadExpired):
+		return http.StatusGone
+	case errors.Is(err, ErrOffsetMismatch), errors.Is(err, ErrUploadIncomplete):
+		return http.StatusConflict
+	case errors.Is(err, ErrUploadLocked):
+		return http.StatusLocked
+	case errors.Is(err, ErrUploadTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrChecksumMismatch):
+		return statusChecksumMismatch
+	case errors.Is(err, ErrUnsupportedChecksum):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func setUploadHeaders(c *gin.Context, upload *Upload) {
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+// parseMetadata decodes an Upload-Metadata header: comma-separated "key base64(value)" pairs
+func parseMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("value of %s is not valid base64", key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+func toUploadResponse(upload *Upload) UploadResponse {
+	return UploadResponse{
+		ID:        upload.ID,
+		Length:    upload.Length,
+		Offset:    upload.Offset,
+		FileName:  upload.FileName,
+		Completed: upload.Completed,
+		ExpiresAt: upload.ExpiresAt,
+	}
+}
@@ -0,0 +1,394 @@
+// api/v1/uploads/service.go
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/madhatkul/CxWrapper-v2/util"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	defaultMaxUploadSize = 20 << 30 // 20 GiB
+	defaultUploadExpiry  = 24 * time.Hour
+	// consumedUploadExpiry is how long an upload is kept after a scan used it, so a retried scan request still finds it
+	consumedUploadExpiry = time.Hour
+	cleanupInterval      = 10 * time.Minute
+)
+
+var (
+	ErrUploadNotFound      = errors.New("upload not found")
+	ErrUploadExpired       = errors.New("upload expired")
+	ErrUploadIncomplete    = errors.New("upload is not complete")
+	ErrUploadLocked        = errors.New("upload is being written by another request")
+	ErrUploadTooLarge      = errors.New("upload exceeds the maximum allowed size")
+	ErrOffsetMismatch      = errors.New("upload offset does not match")
+	ErrChecksumMismatch    = errors.New("checksum mismatch")
+	ErrUnsupportedChecksum = errors.New("unsupported checksum algorithm")
+)
+
+// UploadService assembles resumable uploads on disk
+type UploadService struct {
+	store   *UploadStore
+	dir     string
+	maxSize int64
+	expiry  time.Duration
+	logger  util.Logger
+
+	mu      sync.Mutex
+	writing map[string]bool
+}
+
+// NewUploadService reads UPLOAD_DIR (default: a directory under the system temp dir), UPLOAD_MAX_BYTES
+// (default 20 GiB) and UPLOAD_EXPIRY (default 24h)
+func NewUploadService(db *bolt.DB, logger util.Logger) (*UploadService, error) {
+	store, err := NewUploadStore(db)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := os.Getenv("UPLOAD_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "cxwrapper-uploads")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory %s: %v", dir, err)
+	}
+
+	us := &UploadService{
+		store:   store,
+		dir:     dir,
+		maxSize: defaultMaxUploadSize,
+		expiry:  defaultUploadExpiry,
+		logger:  logger,
+		writing: make(map[string]bool),
+	}
+	if v, err := strconv.ParseInt(os.Getenv("UPLOAD_MAX_BYTES"), 10, 64); err == nil && v > 0 {
+		us.maxSize = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("UPLOAD_EXPIRY")); err == nil && v > 0 {
+		us.expiry = v
+	}
+
+	return us, nil
+}
+
+// MaxSize is the largest upload accepted
+func (us *UploadService) MaxSize() int64 {
+	return us.maxSize
+}
+
+// Start removes expired uploads until ctx is cancelled
+func (us *UploadService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(cleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			us.purgeExpired()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Create starts an upload of length bytes. The "filename" metadata names the archive and "checksum",
+// "sha256 <hex or base64>", is verified against the whole archive once it is complete.
+func (us *UploadService) Create(length int64, metadata map[string]string) (*Upload, error) {
+	if length <= 0 {
+		return nil, fmt.Errorf("upload length must be positive")
+	}
+	if length > us.maxSize {
+		return nil, fmt.Errorf("%w: %d bytes is more than %d", ErrUploadTooLarge, length, us.maxSize)
+	}
+
+	upload := &Upload{
+		ID:        newUploadID(),
+		Length:    length,
+		FileName:  metadata["filename"],
+		Metadata:  metadata,
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: time.Now().UTC().Add(us.expiry),
+	}
+
+	if checksum := metadata["checksum"]; checksum != "" {
+		algorithm, sum, err := parseChecksum(checksum)
+		if err != nil {
+			return nil, err
+		}
+		upload.Checksum = algorithm + " " + hex.EncodeToString(sum)
+	}
+
+	state, err := sha256.New().(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize upload checksum: %v", err)
+	}
+	upload.HashState = state
+
+	file, err := os.OpenFile(us.path(upload.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload file: %v", err)
+	}
+	file.Close()
+
+	if err := us.store.Put(upload); err != nil {
+		os.Remove(us.path(upload.ID))
+		return nil, fmt.Errorf("failed to save upload: %v", err)
+	}
+
+	us.logger.Infof("📦 Upload %s created: %d bytes (%s)", upload.ID, upload.Length, upload.FileName)
+	return upload, nil
+}
+
+// Get returns an upload that has not expired
+func (us *UploadService) Get(id string) (*Upload, error) {
+	upload, err := us.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if upload.ExpiresAt.Before(time.Now()) {
+		return nil, fmt.Errorf("%w: %s", ErrUploadExpired, id)
+	}
+	return upload, nil
+}
+
+// WriteChunk appends the bytes read from r at offset, which must be the upload's current offset. When
+// chunkChecksum ("sha256 <base64>") is given, the chunk is discarded unless it matches. Bytes received
+// before the client went away are kept, so it can resume from the new offset.
+func (us *UploadService) WriteChunk(id string, offset int64, r io.Reader, chunkChecksum string) (*Upload, error) {
+	if !us.lock(id) {
+		return nil, ErrUploadLocked
+	}
+	defer us.unlock(id)
+
+	upload, err := us.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != upload.Offset {
+		return upload, fmt.Errorf("%w: expected %d, got %d", ErrOffsetMismatch, upload.Offset, offset)
+	}
+
+	var chunkHash hash.Hash
+	var expectedChunkSum []byte
+	if chunkChecksum != "" {
+		_, sum, err := parseChecksum(chunkChecksum)
+		if err != nil {
+			return upload, err
+		}
+		chunkHash = sha256.New()
+		expectedChunkSum = sum
+	}
+
+	archiveHash := sha256.New()
+	if err := archiveHash.(encoding.BinaryUnmarshaler).UnmarshalBinary(upload.HashState); err != nil {
+		return upload, fmt.Errorf("failed to restore upload checksum: %v", err)
+	}
+
+	file, err := os.OpenFile(us.path(id), os.O_WRONLY, 0o600)
+	if err != nil {
+		return upload, fmt.Errorf("failed to open upload file: %v", err)
+	}
+	defer file.Close()
+
+	// Drop anything past the recorded offset, left by a write whose record was never saved
+	if err := file.Truncate(offset); err != nil {
+		return upload, fmt.Errorf("failed to prepare upload file: %v", err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return upload, fmt.Errorf("failed to prepare upload file: %v", err)
+	}
+
+	writers := []io.Writer{file, archiveHash}
+	if chunkHash != nil {
+		writers = append(writers, chunkHash)
+	}
+
+	// Read one byte past the declared length to detect clients sending too much
+	remaining := upload.Length - offset
+	n, copyErr := io.Copy(io.MultiWriter(writers...), io.LimitReader(r, remaining+1))
+
+	discard := func(reason error) (*Upload, error) {
+		file.Truncate(offset)
+		upload.Offset = offset
+		return upload, reason
+	}
+
+	if n > remaining {
+		return discard(fmt.Errorf("%w: chunk goes past the declared length of %d bytes", ErrUploadTooLarge, upload.Length))
+	}
+	if chunkHash != nil {
+		if copyErr != nil {
+			return discard(fmt.Errorf("failed to read chunk: %v", copyErr))
+		}
+		if !bytes.Equal(chunkHash.Sum(nil), expectedChunkSum) {
+			return discard(fmt.Errorf("%w: chunk at offset %d", ErrChecksumMismatch, offset))
+		}
+	}
+	if err := file.Sync(); err != nil {
+		return discard(fmt.Errorf("failed to write upload file: %v", err))
+	}
+
+	upload.Offset += n
+	state, err := archiveHash.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return discard(fmt.Errorf("failed to save upload checksum: %v", err))
+	}
+	upload.HashState = state
+
+	if upload.Offset == upload.Length {
+		if upload.Checksum != "" && upload.Checksum != "sha256 "+hex.EncodeToString(archiveHash.Sum(nil)) {
+			// The archive is corrupt and cannot be repaired by resuming, the client has to start over
+			us.remove(id)
+			return upload, fmt.Errorf("%w: archive does not match %s, upload discarded", ErrChecksumMismatch, upload.Checksum)
+		}
+		upload.Completed = true
+		us.logger.Infof("✅ Upload %s completed: %d bytes", id, upload.Length)
+	}
+
+	if err := us.store.Put(upload); err != nil {
+		return discard(fmt.Errorf("failed to save upload: %v", err))
+	}
+
+	if copyErr != nil {
+		us.logger.Warnf("⚠️ Upload %s interrupted at offset %d: %v", id, upload.Offset, copyErr)
+		return upload, fmt.Errorf("failed to read chunk: %v", copyErr)
+	}
+	return upload, nil
+}
+
+// Open returns the archive of a completed upload for reading. The caller must close it.
+func (us *UploadService) Open(id string) (*os.File, *Upload, error) {
+	upload, err := us.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !upload.Completed {
+		return nil, nil, fmt.Errorf("%w: %d of %d bytes received", ErrUploadIncomplete, upload.Offset, upload.Length)
+	}
+
+	file, err := os.Open(us.path(id))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open upload file: %v", err)
+	}
+	return file, upload, nil
+}
+
+// MarkConsumed shortens the life of an upload a scan was started from
+func (us *UploadService) MarkConsumed(id string) error {
+	upload, err := us.store.Get(id)
+	if err != nil {
+		return err
+	}
+	if expiresAt := time.Now().UTC().Add(consumedUploadExpiry); expiresAt.Before(upload.ExpiresAt) {
+		upload.ExpiresAt = expiresAt
+	}
+	return us.store.Put(upload)
+}
+
+// Terminate deletes an upload and its data
+func (us *UploadService) Terminate(id string) error {
+	if !us.lock(id) {
+		return ErrUploadLocked
+	}
+	defer us.unlock(id)
+
+	if _, err := us.store.Get(id); err != nil {
+		return err
+	}
+	return us.remove(id)
+}
+
+func (us *UploadService) remove(id string) error {
+	if err := os.Remove(us.path(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove upload file: %v", err)
+	}
+	return us.store.Delete(id)
+}
+
+func (us *UploadService) purgeExpired() {
+	expired, err := us.store.Expired(time.Now())
+	if err != nil {
+		us.logger.Errorf("❌ Failed to list expired uploads: %v", err)
+		return
+	}
+
+	for _, upload := range expired {
+		if !us.lock(upload.ID) {
+			continue
+		}
+		if err := us.remove(upload.ID); err != nil {
+			us.logger.Errorf("❌ Failed to remove expired upload %s: %v", upload.ID, err)
+		} else {
+			us.logger.Infof("🧹 Removed expired upload %s", upload.ID)
+		}
+		us.unlock(upload.ID)
+	}
+}
+
+func (us *UploadService) lock(id string) bool {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	if us.writing[id] {
+		return false
+	}
+	us.writing[id] = true
+	return true
+}
+
+func (us *UploadService) unlock(id string) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	delete(us.writing, id)
+}
+
+func (us *UploadService) path(id string) string {
+	return filepath.Join(us.dir, id+".zip")
+}
+
+// parseChecksum parses "<algorithm> <digest>" with the digest in base64, as tus sends it, or hex
+func parseChecksum(value string) (string, []byte, error) {
+	algorithm, digest, ok := strings.Cut(strings.TrimSpace(value), " ")
+	if !ok {
+		return "", nil, fmt.Errorf("invalid checksum %q: expected \"<algorithm> <digest>\"", value)
+	}
+	algorithm = strings.ToLower(algorithm)
+	if algorithm != "sha256" {
+		return "", nil, fmt.Errorf("%w: %s", ErrUnsupportedChecksum, algorithm)
+	}
+
+	digest = strings.TrimSpace(digest)
+	if sum, err := hex.DecodeString(digest); err == nil && len(sum) == sha256.Size {
+		return algorithm, sum, nil
+	}
+	if sum, err := base64.StdEncoding.DecodeString(digest); err == nil && len(sum) == sha256.Size {
+		return algorithm, sum, nil
+	}
+	return "", nil, fmt.Errorf("invalid checksum %q: digest is not a hex or base64 encoded SHA-256", value)
+}
+
+func newUploadID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
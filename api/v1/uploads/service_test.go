@@ -0,0 +1,367 @@
+package uploads
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	bolt "go.etcd.io/bbolt"
+)
+
+type testLogger struct{ t *testing.T }
+
+func (l testLogger) Infof(format string, args ...interface{})  { l.t.Logf("INFO "+format, args...) }
+func (l testLogger) Warnf(format string, args ...interface{})  { l.t.Logf("WARN "+format, args...) }
+func (l testLogger) Errorf(format string, args ...interface{}) { l.t.Logf("ERROR "+format, args...) }
+func (l testLogger) Debugf(format string, args ...interface{}) { l.t.Logf("DEBUG "+format, args...) }
+
+// newTestUploadService returns an upload service keeping its files and bolt database in dir, accepting uploads of up to 1 KiB
+func newTestUploadService(t *testing.T, dir string) (*UploadService, *bolt.DB) {
+	t.Helper()
+
+	t.Setenv("UPLOAD_DIR", filepath.Join(dir, "uploads"))
+	t.Setenv("UPLOAD_MAX_BYTES", "1024")
+	t.Setenv("UPLOAD_EXPIRY", "")
+
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	us, err := NewUploadService(db, testLogger{t})
+	if err != nil {
+		t.Fatalf("NewUploadService() = %v", err)
+	}
+	return us, db
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func sha256Base64(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// failingReader returns err once the bytes before it are read, like a client that went away
+type failingReader struct{ err error }
+
+func (r failingReader) Read([]byte) (int, error) { return 0, r.err }
+
+func TestCreate(t *testing.T) {
+	us, _ := newTestUploadService(t, t.TempDir())
+
+	tests := []struct {
+		name         string
+		length       int64
+		checksum     string
+		wantErr      error
+		wantChecksum string
+	}{
+		{name: "no checksum", length: 12},
+		{name: "hex checksum", length: 12, checksum: "sha256 " + sha256Hex("hello world!"), wantChecksum: "sha256 " + sha256Hex("hello world!")},
+		{name: "base64 checksum", length: 12, checksum: "SHA256 " + sha256Base64("hello world!"), wantChecksum: "sha256 " + sha256Hex("hello world!")},
+		{name: "largest allowed", length: 1024},
+		{name: "too large", length: 1025, wantErr: ErrUploadTooLarge},
+		{name: "empty", length: 0, wantErr: errors.New("upload length must be positive")},
+		{name: "unsupported algorithm", length: 12, checksum: "md5 XrY7u+Ae7tCTyyK7j1rNww==", wantErr: ErrUnsupportedChecksum},
+		{name: "malformed digest", length: 12, checksum: "sha256 not-a-digest", wantErr: errors.New("digest is not a hex or base64 encoded SHA-256")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upload, err := us.Create(tt.length, map[string]string{"filename": "source.zip", "checksum": tt.checksum})
+			if tt.wantErr != nil {
+				if err == nil || !errors.Is(err, tt.wantErr) && !strings.Contains(err.Error(), tt.wantErr.Error()) {
+					t.Fatalf("Create() = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Create() = %v", err)
+			}
+
+			if upload.Checksum != tt.wantChecksum || upload.FileName != "source.zip" || upload.Offset != 0 {
+				t.Fatalf("Create() = %+v, want checksum %q", upload, tt.wantChecksum)
+			}
+			info, err := os.Stat(us.path(upload.ID))
+			if err != nil || info.Size() != 0 {
+				t.Fatalf("upload file = %v, %v; want an empty file", info, err)
+			}
+			if _, err := us.Get(upload.ID); err != nil {
+				t.Fatalf("Get() = %v", err)
+			}
+		})
+	}
+}
+
+func TestWriteChunk(t *testing.T) {
+	const archive = "hello world!"
+
+	type chunk struct {
+		offset   int64
+		data     string
+		checksum string
+		// interrupted makes the client go away after sending data
+		interrupted bool
+	}
+
+	tests := []struct {
+		name          string
+		length        int64
+		checksum      string
+		chunks        []chunk
+		wantErr       error
+		wantStatus    int
+		wantOffset    int64
+		wantCompleted bool
+		wantDiscarded bool
+	}{
+		{
+			name:          "whole archive",
+			length:        12,
+			checksum:      "sha256 " + sha256Hex(archive),
+			chunks:        []chunk{{data: archive}},
+			wantOffset:    12,
+			wantCompleted: true,
+		},
+		{
+			name:          "chunks with checksums",
+			length:        12,
+			checksum:      "sha256 " + sha256Hex(archive),
+			chunks:        []chunk{{data: "hello ", checksum: "sha256 " + sha256Base64("hello ")}, {offset: 6, data: "world!", checksum: "sha256 " + sha256Base64("world!")}},
+			wantOffset:    12,
+			wantCompleted: true,
+		},
+		{
+			name:       "offset behind",
+			length:     12,
+			chunks:     []chunk{{data: "hello "}, {offset: 0, data: "hello "}},
+			wantErr:    ErrOffsetMismatch,
+			wantStatus: http.StatusConflict,
+			wantOffset: 6,
+		},
+		{
+			name:       "offset ahead",
+			length:     12,
+			chunks:     []chunk{{offset: 6, data: "world!"}},
+			wantErr:    ErrOffsetMismatch,
+			wantStatus: http.StatusConflict,
+			wantOffset: 0,
+		},
+		{
+			name:       "chunk checksum mismatch",
+			length:     12,
+			chunks:     []chunk{{data: "hello "}, {offset: 6, data: "w0rld!", checksum: "sha256 " + sha256Base64("world!")}},
+			wantErr:    ErrChecksumMismatch,
+			wantStatus: statusChecksumMismatch,
+			wantOffset: 6,
+		},
+		{
+			name:       "unsupported chunk checksum",
+			length:     12,
+			chunks:     []chunk{{data: "hello ", checksum: "md5 XrY7u+Ae7tCTyyK7j1rNww=="}},
+			wantErr:    ErrUnsupportedChecksum,
+			wantStatus: http.StatusBadRequest,
+			wantOffset: 0,
+		},
+		{
+			name:          "archive checksum mismatch",
+			length:        12,
+			checksum:      "sha256 " + sha256Hex("hello w0rld!"),
+			chunks:        []chunk{{data: "hello "}, {offset: 6, data: "world!"}},
+			wantErr:       ErrChecksumMismatch,
+			wantStatus:    statusChecksumMismatch,
+			wantDiscarded: true,
+		},
+		{
+			name:       "past Upload-Length",
+			length:     12,
+			chunks:     []chunk{{data: "hello "}, {offset: 6, data: "world!!"}},
+			wantErr:    ErrUploadTooLarge,
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantOffset: 6,
+		},
+		{
+			name:       "interrupted chunk keeps what arrived",
+			length:     12,
+			chunks:     []chunk{{data: "hello ", interrupted: true}},
+			wantErr:    io.ErrUnexpectedEOF,
+			wantStatus: http.StatusInternalServerError,
+			wantOffset: 6,
+		},
+		{
+			name:       "interrupted chunk with a checksum is dropped",
+			length:     12,
+			chunks:     []chunk{{data: "hello ", checksum: "sha256 " + sha256Base64("hello "), interrupted: true}},
+			wantErr:    io.ErrUnexpectedEOF,
+			wantStatus: http.StatusInternalServerError,
+			wantOffset: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			us, _ := newTestUploadService(t, t.TempDir())
+			upload, err := us.Create(tt.length, map[string]string{"checksum": tt.checksum})
+			if err != nil {
+				t.Fatalf("Create() = %v", err)
+			}
+
+			for i, c := range tt.chunks {
+				var r io.Reader = strings.NewReader(c.data)
+				if c.interrupted {
+					r = io.MultiReader(r, failingReader{io.ErrUnexpectedEOF})
+				}
+				_, err = us.WriteChunk(upload.ID, c.offset, r, c.checksum)
+				if i < len(tt.chunks)-1 && err != nil {
+					t.Fatalf("WriteChunk(%d) = %v", c.offset, err)
+				}
+			}
+
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("WriteChunk() = %v", err)
+				}
+			} else {
+				if err == nil || !errors.Is(err, tt.wantErr) && !strings.Contains(err.Error(), tt.wantErr.Error()) {
+					t.Fatalf("WriteChunk() = %v, want %v", err, tt.wantErr)
+				}
+				if got := statusFor(err); got != tt.wantStatus {
+					t.Fatalf("statusFor(%v) = %d, want %d", err, got, tt.wantStatus)
+				}
+			}
+
+			if tt.wantDiscarded {
+				if _, err := us.Get(upload.ID); !errors.Is(err, ErrUploadNotFound) {
+					t.Fatalf("Get() = %v, want %v", err, ErrUploadNotFound)
+				}
+				if _, err := os.Stat(us.path(upload.ID)); !os.IsNotExist(err) {
+					t.Fatalf("upload file still exists: %v", err)
+				}
+				return
+			}
+
+			stored, err := us.Get(upload.ID)
+			if err != nil {
+				t.Fatalf("Get() = %v", err)
+			}
+			if stored.Offset != tt.wantOffset || stored.Completed != tt.wantCompleted {
+				t.Fatalf("upload at offset %d, completed %t; want %d, %t", stored.Offset, stored.Completed, tt.wantOffset, tt.wantCompleted)
+			}
+			data, err := os.ReadFile(us.path(upload.ID))
+			if err != nil || string(data) != archive[:tt.wantOffset] {
+				t.Fatalf("upload file = %q, %v; want %q", data, err, archive[:tt.wantOffset])
+			}
+		})
+	}
+}
+
+func TestWriteChunkResumesChecksumAfterRestart(t *testing.T) {
+	tests := []struct {
+		name     string
+		checksum string
+		wantErr  error
+	}{
+		{name: "matching archive", checksum: "sha256 " + sha256Hex("hello world!")},
+		{name: "corrupt archive", checksum: "sha256 " + sha256Hex("hello w0rld!"), wantErr: ErrChecksumMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			us, db := newTestUploadService(t, dir)
+			upload, err := us.Create(12, map[string]string{"checksum": tt.checksum})
+			if err != nil {
+				t.Fatalf("Create() = %v", err)
+			}
+			if _, err := us.WriteChunk(upload.ID, 0, strings.NewReader("hello "), ""); err != nil {
+				t.Fatalf("WriteChunk(0) = %v", err)
+			}
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// The checksum of the first chunk is carried by the saved hash state, not by reading the file back
+			us, _ = newTestUploadService(t, dir)
+			completed, err := us.WriteChunk(upload.ID, 6, strings.NewReader("world!"), "")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("WriteChunk(6) = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !completed.Completed {
+				t.Fatalf("WriteChunk(6) = %+v, %v; want the upload completed", completed, err)
+			}
+
+			file, _, err := us.Open(upload.ID)
+			if err != nil {
+				t.Fatalf("Open() = %v", err)
+			}
+			defer file.Close()
+			if data, _ := io.ReadAll(file); string(data) != "hello world!" {
+				t.Fatalf("archive = %q", data)
+			}
+		})
+	}
+}
+
+func TestWriteChunkHandler(t *testing.T) {
+	us, _ := newTestUploadService(t, t.TempDir())
+	upload, err := us.Create(12, nil)
+	if err != nil {
+		t.Fatalf("Create() = %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewUploadHandler(us, testLogger{t}).RegisterRoutes(router.Group("/api/v1"))
+
+	patch := func(offset, checksum, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/uploads/"+upload.ID, bytes.NewBufferString(body))
+		req.Header.Set("Tus-Resumable", tusVersion)
+		req.Header.Set("Content-Type", contentTypeOffset)
+		req.Header.Set("Upload-Offset", offset)
+		if checksum != "" {
+			req.Header.Set("Upload-Checksum", checksum)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name       string
+		offset     string
+		checksum   string
+		body       string
+		wantStatus int
+		wantOffset string
+	}{
+		{name: "chunk checksum mismatch", offset: "0", checksum: "sha256 " + sha256Base64("hello "), body: "hell0 ", wantStatus: statusChecksumMismatch, wantOffset: "0"},
+		{name: "chunk accepted", offset: "0", checksum: "sha256 " + sha256Base64("hello "), body: "hello ", wantStatus: http.StatusNoContent, wantOffset: "6"},
+		{name: "stale offset", offset: "0", body: "hello ", wantStatus: http.StatusConflict, wantOffset: "6"},
+		{name: "past Upload-Length", offset: "6", body: "world!!", wantStatus: http.StatusRequestEntityTooLarge, wantOffset: "6"},
+		{name: "last chunk", offset: "6", body: "world!", wantStatus: http.StatusNoContent, wantOffset: "12"},
+	}
+	for _, tt := range tests {
+		rec := patch(tt.offset, tt.checksum, tt.body)
+		if rec.Code != tt.wantStatus || rec.Header().Get("Upload-Offset") != tt.wantOffset {
+			t.Fatalf("%s: PATCH = %d at offset %q, want %d at %q: %s", tt.name, rec.Code, rec.Header().Get("Upload-Offset"), tt.wantStatus, tt.wantOffset, rec.Body)
+		}
+	}
+}
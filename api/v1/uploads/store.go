@@ -0,0 +1,89 @@
+// api/v1/uploads/store.go
+package uploads
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var uploadsBucket = []byte("uploads")
+
+// UploadStore persists upload records
+type UploadStore struct {
+	db *bolt.DB
+}
+
+func NewUploadStore(db *bolt.DB) (*UploadStore, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(uploadsBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize upload store: %w", err)
+	}
+
+	return &UploadStore{db: db}, nil
+}
+
+// Put creates or replaces an upload record
+func (s *UploadStore) Put(upload *Upload) error {
+	upload.UpdatedAt = time.Now().UTC()
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(uploadsBucket).Put([]byte(upload.ID), data)
+	})
+}
+
+// Get returns the upload with the given ID
+func (s *UploadStore) Get(id string) (*Upload, error) {
+	var upload *Upload
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(uploadsBucket).Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("%w: %s", ErrUploadNotFound, id)
+		}
+		upload = &Upload{}
+		return json.Unmarshal(data, upload)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+// Delete removes an upload record
+func (s *UploadStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(uploadsBucket).Delete([]byte(id))
+	})
+}
+
+// Expired returns the uploads whose expiry is before now
+func (s *UploadStore) Expired(now time.Time) ([]Upload, error) {
+	var expired []Upload
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(uploadsBucket).ForEach(func(_, data []byte) error {
+			var upload Upload
+			if err := json.Unmarshal(data, &upload); err != nil {
+				return err
+			}
+			if upload.ExpiresAt.Before(now) {
+				expired = append(expired, upload)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list uploads: %w", err)
+	}
+
+	return expired, nil
+}
@@ -0,0 +1,39 @@
+// api/v1/uploads/types.go
+package uploads
+
+import "time"
+
+// Upload is a resumable upload of a source archive. The bytes live in a file on disk, the record in bolt.
+type Upload struct {
+	ID       string            `json:"id"`
+	Length   int64             `json:"length"`
+	Offset   int64             `json:"offset"`
+	FileName string            `json:"file_name,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Checksum is the expected checksum of the whole archive, "sha256 <hex>", verified once the last byte arrives
+	Checksum  string `json:"checksum,omitempty"`
+	Completed bool   `json:"completed"`
+	// HashState is the marshaled SHA-256 state over the bytes received so far, so the checksum of a
+	// multi-gigabyte archive never has to be computed by reading it back
+	HashState []byte    `json:"hash_state,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UploadResponse is the JSON view of an upload, returned alongside the tus headers
+type UploadResponse struct {
+	ID        string    `json:"id"`
+	Length    int64     `json:"length"`
+	Offset    int64     `json:"offset"`
+	FileName  string    `json:"file_name,omitempty"`
+	Completed bool      `json:"completed"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	Details   string `json:"details,omitempty"`
+	Timestamp string `json:"timestamp"`
+	Path      string `json:"path"`
+}
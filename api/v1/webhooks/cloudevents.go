@@ -0,0 +1,145 @@
+// api/v1/webhooks/cloudevents.go
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Payload formats a subscription can choose
+const (
+	PayloadFormatLegacy                = "legacy"
+	PayloadFormatCloudEventsStructured = "cloudevents-structured"
+	PayloadFormatCloudEventsBinary     = "cloudevents-binary"
+)
+
+// Payload versions. v1 is the original body (ScanResultResponse for completion, WebhookPayload for
+// lifecycle events); v2 is the ScanEventV2 shape shared by every event type.
+const (
+	PayloadVersionV1 = "v1"
+	PayloadVersionV2 = "v2"
+
+	DefaultPayloadVersion = PayloadVersionV1
+)
+
+var (
+	payloadFormats  = map[string]bool{PayloadFormatLegacy: true, PayloadFormatCloudEventsStructured: true, PayloadFormatCloudEventsBinary: true}
+	payloadVersions = map[string]bool{PayloadVersionV1: true, PayloadVersionV2: true}
+)
+
+const (
+	cloudEventsSpecVersion  = "1.0"
+	cloudEventTypePrefix    = "com.cxwrapper."
+	defaultCloudEventSource = "/cxwrapper/scans"
+
+	contentTypeJSON        = "application/json"
+	contentTypeCloudEvents = "application/cloudevents+json"
+)
+
+// CloudEvent is a CloudEvents 1.0 envelope in structured JSON mode
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	DataSchema      string          `json:"dataschema"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// renderedPayload is what is persisted on a delivery: the body plus the headers that describe it
+type renderedPayload struct {
+	Body        []byte
+	ContentType string
+	Headers     map[string]string
+}
+
+// renderPayload builds the request body for an event in the given format and payload version
+func renderPayload(event Event, format, version string) (*renderedPayload, error) {
+	if format == "" {
+		format = PayloadFormatLegacy
+	}
+	if version == "" {
+		version = DefaultPayloadVersion
+	}
+
+	data, ok := event.Payloads[version]
+	if !ok {
+		return nil, fmt.Errorf("event %s has no %s payload", event.Type, version)
+	}
+
+	switch format {
+	case PayloadFormatLegacy:
+		return &renderedPayload{Body: data, ContentType: contentTypeJSON}, nil
+
+	case PayloadFormatCloudEventsStructured:
+		body, err := json.Marshal(CloudEvent{
+			SpecVersion:     cloudEventsSpecVersion,
+			ID:              event.ID,
+			Source:          cloudEventSource(),
+			Type:            cloudEventType(event.Type),
+			Subject:         event.ScanID,
+			Time:            event.Time,
+			DataContentType: contentTypeJSON,
+			DataSchema:      SchemaURI(event.Type, version),
+			Data:            data,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal cloudevent: %w", err)
+		}
+		return &renderedPayload{Body: body, ContentType: contentTypeCloudEvents}, nil
+
+	case PayloadFormatCloudEventsBinary:
+		return &renderedPayload{
+			Body:        data,
+			ContentType: contentTypeJSON,
+			Headers: map[string]string{
+				"ce-specversion": cloudEventsSpecVersion,
+				"ce-id":          event.ID,
+				"ce-source":      cloudEventSource(),
+				"ce-type":        cloudEventType(event.Type),
+				"ce-subject":     event.ScanID,
+				"ce-time":        event.Time.Format(time.RFC3339Nano),
+				"ce-dataschema":  SchemaURI(event.Type, version),
+			},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown payload format: %s", format)
+}
+
+func cloudEventType(eventType string) string {
+	return cloudEventTypePrefix + eventType
+}
+
+// cloudEventSource reads CLOUDEVENTS_SOURCE, falling back to a fixed path
+func cloudEventSource() string {
+	if source := os.Getenv("CLOUDEVENTS_SOURCE"); source != "" {
+		return source
+	}
+	return defaultCloudEventSource
+}
+
+// SchemaURI returns the dataschema for an event type and version. When WEBHOOK_SCHEMA_BASE_URL is set
+// (e.g. https://cxwrapper.example.com) it points at the schema endpoint, otherwise a stable URN is used.
+func SchemaURI(eventType, version string) string {
+	if base := strings.TrimRight(os.Getenv("WEBHOOK_SCHEMA_BASE_URL"), "/"); base != "" {
+		return fmt.Sprintf("%s/v1/webhooks/schemas/%s/%s", base, eventType, version)
+	}
+	return fmt.Sprintf("urn:cxwrapper:schema:%s:%s", eventType, version)
+}
+
+func validatePayloadOptions(format, version string) error {
+	if format != "" && !payloadFormats[format] {
+		return fmt.Errorf("unknown payload_format: %s", format)
+	}
+	if version != "" && !payloadVersions[version] {
+		return fmt.Errorf("unknown payload_version: %s", version)
+	}
+	return nil
+}
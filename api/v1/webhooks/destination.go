@@ -0,0 +1,301 @@
+// api/v1/webhooks/destination.go
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultMaxRedirects = 3
+	resolveTimeout      = 5 * time.Second
+)
+
+// ErrDestinationBlocked is returned when a webhook URL, or another outbound destination checked against the
+// policy, or the address it resolves to is not allowed
+var ErrDestinationBlocked = errors.New("destination blocked")
+
+// blockedNetworks are ranges that are not covered by the net.IP helpers but must never be reached
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",      // "this" network
+	"100.64.0.0/10",  // carrier-grade NAT
+	"192.0.0.0/24",   // IETF protocol assignments
+	"198.18.0.0/15",  // benchmarking
+	"240.0.0.0/4",    // reserved
+	"64:ff9b::/96",   // NAT64, can map onto private IPv4
+	"64:ff9b:1::/48", // local-use NAT64
+	"2002::/16",      // 6to4, can embed private IPv4
+)
+
+// DestinationPolicy decides which URLs webhooks may be sent to. It is applied when a subscription is saved
+// and again on every connection, after DNS resolution, so a hostname cannot be re-pointed at an internal address.
+type DestinationPolicy struct {
+	AllowedSchemes map[string]bool
+	// AllowedHosts are path.Match patterns such as "hooks.example.com" or "*.example.com"; empty allows any host
+	AllowedHosts []string
+	// AllowedNetworks are exempt from the private address check, e.g. an internal receiver's subnet
+	AllowedNetworks []*net.IPNet
+	AllowPrivate    bool
+	MaxRedirects    int
+}
+
+// destinationPolicyFromEnv reads WEBHOOK_ALLOWED_SCHEMES (default "http,https"), WEBHOOK_ALLOWED_HOSTS,
+// WEBHOOK_ALLOWED_NETWORKS (CIDRs), WEBHOOK_ALLOW_PRIVATE_NETWORKS and WEBHOOK_MAX_REDIRECTS
+func destinationPolicyFromEnv() (*DestinationPolicy, error) {
+	policy, err := DestinationPolicyFromEnv("WEBHOOK")
+	if err != nil {
+		return nil, err
+	}
+	policy.AllowedSchemes = map[string]bool{"http": true, "https": true}
+	policy.MaxRedirects = defaultMaxRedirects
+
+	if schemes := splitList(os.Getenv("WEBHOOK_ALLOWED_SCHEMES")); len(schemes) > 0 {
+		policy.AllowedSchemes = make(map[string]bool)
+		for _, scheme := range schemes {
+			scheme = strings.ToLower(scheme)
+			if scheme != "http" && scheme != "https" {
+				return nil, fmt.Errorf("invalid WEBHOOK_ALLOWED_SCHEMES: unsupported scheme %s", scheme)
+			}
+			policy.AllowedSchemes[scheme] = true
+		}
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("WEBHOOK_MAX_REDIRECTS")); err == nil && v >= 0 {
+		policy.MaxRedirects = v
+	}
+
+	return policy, nil
+}
+
+// DestinationPolicyFromEnv reads the allowlists shared by every kind of outbound connection from
+// <prefix>_ALLOWED_HOSTS, <prefix>_ALLOWED_NETWORKS (CIDRs) and <prefix>_ALLOW_PRIVATE_NETWORKS, so
+// webhooks and git fetches, say, can be opened up to internal hosts independently
+func DestinationPolicyFromEnv(prefix string) (*DestinationPolicy, error) {
+	policy := &DestinationPolicy{}
+
+	for _, host := range splitList(os.Getenv(prefix + "_ALLOWED_HOSTS")) {
+		if _, err := path.Match(host, ""); err != nil {
+			return nil, fmt.Errorf("invalid %s_ALLOWED_HOSTS pattern %s: %v", prefix, host, err)
+		}
+		policy.AllowedHosts = append(policy.AllowedHosts, strings.ToLower(host))
+	}
+
+	for _, cidr := range splitList(os.Getenv(prefix + "_ALLOWED_NETWORKS")) {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s_ALLOWED_NETWORKS entry %s: %v", prefix, cidr, err)
+		}
+		policy.AllowedNetworks = append(policy.AllowedNetworks, network)
+	}
+
+	if v, err := strconv.ParseBool(os.Getenv(prefix + "_ALLOW_PRIVATE_NETWORKS")); err == nil {
+		policy.AllowPrivate = v
+	}
+
+	return policy, nil
+}
+
+// CheckURL validates the scheme and host of a URL and, when resolve is set, every address its host
+// resolves to. Without resolve only IP literals are checked; direct connections are checked by the dial hook.
+func (p *DestinationPolicy) CheckURL(ctx context.Context, u *url.URL, resolve bool) error {
+	if !p.AllowedSchemes[strings.ToLower(u.Scheme)] {
+		return fmt.Errorf("%w: scheme %s is not allowed", ErrDestinationBlocked, u.Scheme)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return fmt.Errorf("%w: host is required", ErrDestinationBlocked)
+	}
+	if !p.hostAllowed(host) {
+		return fmt.Errorf("%w: host %s is not in the allowlist", ErrDestinationBlocked, host)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return p.checkIP(ip)
+	}
+	if !resolve {
+		return nil
+	}
+
+	_, err := p.ResolveAllowed(ctx, host)
+	return err
+}
+
+// ResolveAllowed checks host, a hostname or IP literal, against the host allowlist and returns its
+// addresses if the policy allows every one of them. Schemes are left to the caller, so fetches over
+// other protocols, such as git repositories, can use it.
+func (p *DestinationPolicy) ResolveAllowed(ctx context.Context, host string) ([]net.IP, error) {
+	host = strings.ToLower(host)
+	if !p.hostAllowed(host) {
+		return nil, fmt.Errorf("%w: host %s is not in the allowlist", ErrDestinationBlocked, host)
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if err := p.checkIP(ip); err != nil {
+			return nil, err
+		}
+		return []net.IP{ip}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve host %s: %v", host, err)
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		if err := p.checkIP(addr.IP); err != nil {
+			return nil, fmt.Errorf("%w (%s resolves to %s)", err, host, addr.IP)
+		}
+		ips = append(ips, addr.IP)
+	}
+
+	return ips, nil
+}
+
+func (p *DestinationPolicy) hostAllowed(host string) bool {
+	if len(p.AllowedHosts) == 0 {
+		return true
+	}
+	for _, pattern := range p.AllowedHosts {
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *DestinationPolicy) checkIP(ip net.IP) error {
+	for _, network := range p.AllowedNetworks {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+
+	// Loopback, unspecified and link-local (cloud metadata endpoints) are never allowed, private ranges only on opt-in
+	if ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("%w: address %s is not routable", ErrDestinationBlocked, ip)
+	}
+	if p.AllowPrivate {
+		return nil
+	}
+	if ip.IsPrivate() {
+		return fmt.Errorf("%w: address %s is in a private range", ErrDestinationBlocked, ip)
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return fmt.Errorf("%w: address %s is in reserved range %s", ErrDestinationBlocked, ip, network)
+		}
+	}
+
+	return nil
+}
+
+// control runs on every outbound connection after DNS resolution and rejects addresses the policy blocks
+func (p *DestinationPolicy) control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: invalid address %s", ErrDestinationBlocked, address)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("%w: invalid address %s", ErrDestinationBlocked, address)
+	}
+	return p.checkIP(ip)
+}
+
+type proxyAddrKey struct{}
+
+// transport returns an http.Transport that honours the proxy environment variables and checks every
+// address it connects to. Connections to the proxy itself are not checked; the target was validated
+// before the request was sent, see prepareRequest.
+func (p *DestinationPolicy) transport() *http.Transport {
+	checked := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: p.control}
+	unchecked := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+	return &http.Transport{
+		// This single line tells the client to use HTTP_PROXY, HTTPS_PROXY,
+		// and NO_PROXY from your environment variables.
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if proxyAddr, ok := ctx.Value(proxyAddrKey{}).(string); ok && proxyAddr == addr {
+				return unchecked.DialContext(ctx, network, addr)
+			}
+			return checked.DialContext(ctx, network, addr)
+		},
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+}
+
+// prepareRequest checks the request URL against the policy. When the request goes through a proxy the
+// proxy resolves the target, so it is resolved and checked here and the proxy address is marked as trusted.
+func (p *DestinationPolicy) prepareRequest(req *http.Request) (*http.Request, error) {
+	proxyURL, err := http.ProxyFromEnvironment(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine proxy: %v", err)
+	}
+
+	if err := p.CheckURL(req.Context(), req.URL, proxyURL != nil); err != nil {
+		return nil, err
+	}
+	if proxyURL == nil {
+		return req, nil
+	}
+
+	return req.WithContext(context.WithValue(req.Context(), proxyAddrKey{}, hostPort(proxyURL))), nil
+}
+
+// checkRedirect limits the number of redirects and applies the policy to every redirect target
+func (p *DestinationPolicy) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > p.MaxRedirects {
+		return fmt.Errorf("%w: stopped after %d redirects", ErrDestinationBlocked, p.MaxRedirects)
+	}
+	_, err := p.prepareRequest(req)
+	return err
+}
+
+// hostPort returns the host:port a URL is dialed at, filling in the scheme's default port
+func hostPort(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
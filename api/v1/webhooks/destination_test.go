@@ -0,0 +1,307 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCheckIP(t *testing.T) {
+	strict := &DestinationPolicy{}
+	private := &DestinationPolicy{AllowPrivate: true}
+	exempt := &DestinationPolicy{AllowedNetworks: mustParseCIDRs("10.1.0.0/16", "127.0.0.0/8")}
+
+	tests := []struct {
+		name    string
+		policy  *DestinationPolicy
+		ip      string
+		blocked bool
+	}{
+		{name: "public IPv4", policy: strict, ip: "93.184.216.34"},
+		{name: "public IPv6", policy: strict, ip: "2606:2800:220:1:248:1893:25c8:1946"},
+		{name: "loopback", policy: strict, ip: "127.0.0.1", blocked: true},
+		{name: "loopback range", policy: strict, ip: "127.8.9.10", blocked: true},
+		{name: "IPv6 loopback", policy: strict, ip: "::1", blocked: true},
+		{name: "unspecified", policy: strict, ip: "0.0.0.0", blocked: true},
+		{name: "IPv6 unspecified", policy: strict, ip: "::", blocked: true},
+		{name: "cloud metadata", policy: strict, ip: "169.254.169.254", blocked: true},
+		{name: "IPv6 link-local", policy: strict, ip: "fe80::1", blocked: true},
+		{name: "multicast", policy: strict, ip: "224.0.0.1", blocked: true},
+		{name: "10/8", policy: strict, ip: "10.0.0.5", blocked: true},
+		{name: "172.16/12", policy: strict, ip: "172.31.255.1", blocked: true},
+		{name: "192.168/16", policy: strict, ip: "192.168.1.1", blocked: true},
+		{name: "IPv6 unique local", policy: strict, ip: "fd00::1", blocked: true},
+		{name: "carrier-grade NAT", policy: strict, ip: "100.64.0.1", blocked: true},
+		{name: "this network", policy: strict, ip: "0.1.2.3", blocked: true},
+		{name: "benchmarking", policy: strict, ip: "198.18.0.1", blocked: true},
+		{name: "reserved", policy: strict, ip: "240.0.0.1", blocked: true},
+		{name: "NAT64 of a private address", policy: strict, ip: "64:ff9b::a00:1", blocked: true},
+		{name: "6to4", policy: strict, ip: "2002:a00:1::1", blocked: true},
+		{name: "IPv4-mapped loopback", policy: strict, ip: "::ffff:127.0.0.1", blocked: true},
+		{name: "IPv4-mapped private", policy: strict, ip: "::ffff:10.0.0.1", blocked: true},
+		{name: "private allowed on opt-in", policy: private, ip: "10.0.0.5"},
+		{name: "metadata blocked despite opt-in", policy: private, ip: "169.254.169.254", blocked: true},
+		{name: "loopback blocked despite opt-in", policy: private, ip: "127.0.0.1", blocked: true},
+		{name: "allowed network", policy: exempt, ip: "10.1.2.3"},
+		{name: "outside allowed network", policy: exempt, ip: "10.2.0.1", blocked: true},
+		{name: "loopback in allowed network", policy: exempt, ip: "127.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip := net.ParseIP(tt.ip)
+			if ip == nil {
+				t.Fatalf("bad test address %s", tt.ip)
+			}
+			err := tt.policy.checkIP(ip)
+			if tt.blocked != errors.Is(err, ErrDestinationBlocked) {
+				t.Fatalf("checkIP(%s) = %v, blocked = %v", tt.ip, err, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	policy := &DestinationPolicy{
+		AllowedSchemes: map[string]bool{"http": true, "https": true},
+		AllowedHosts:   []string{"hooks.example.com", "*.internal.example.com", "93.184.216.34", "127.0.0.1"},
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		blocked bool
+	}{
+		{name: "allowed host", url: "https://hooks.example.com/scan"},
+		{name: "host case is ignored", url: "https://HOOKS.example.com/scan"},
+		{name: "wildcard host", url: "https://ci.internal.example.com/scan"},
+		{name: "host not in allowlist", url: "https://evil.example.net/scan", blocked: true},
+		{name: "wildcard does not match the apex", url: "https://internal.example.com/scan", blocked: true},
+		{name: "scheme not allowed", url: "ftp://hooks.example.com/scan", blocked: true},
+		{name: "gopher", url: "gopher://hooks.example.com/", blocked: true},
+		{name: "missing host", url: "https:///scan", blocked: true},
+		{name: "allowed public IP literal", url: "http://93.184.216.34/scan"},
+		{name: "allowlisted loopback literal is still blocked", url: "http://127.0.0.1:8080/scan", blocked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatalf("bad test URL %s: %v", tt.url, err)
+			}
+			// Without resolve only IP literals are checked, so the test never touches DNS
+			err = policy.CheckURL(context.Background(), u, false)
+			if tt.blocked != errors.Is(err, ErrDestinationBlocked) {
+				t.Fatalf("CheckURL(%s) = %v, blocked = %v", tt.url, err, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestCheckURLResolvesHostnames(t *testing.T) {
+	policy := &DestinationPolicy{AllowedSchemes: map[string]bool{"http": true}}
+
+	u, _ := url.Parse("http://localhost/scan")
+	if err := policy.CheckURL(context.Background(), u, true); !errors.Is(err, ErrDestinationBlocked) {
+		t.Fatalf("CheckURL(localhost) with resolve = %v, want it blocked", err)
+	}
+}
+
+func TestControl(t *testing.T) {
+	policy := &DestinationPolicy{}
+
+	tests := []struct {
+		address string
+		blocked bool
+	}{
+		{address: "93.184.216.34:443"},
+		{address: "[2606:2800:220:1:248:1893:25c8:1946]:443"},
+		{address: "127.0.0.1:80", blocked: true},
+		{address: "[::1]:80", blocked: true},
+		{address: "169.254.169.254:80", blocked: true},
+		{address: "10.0.0.1:443", blocked: true},
+		{address: "example.com:443", blocked: true},
+		{address: "no-port", blocked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := policy.control("tcp", tt.address, nil)
+			if tt.blocked != errors.Is(err, ErrDestinationBlocked) {
+				t.Fatalf("control(%s) = %v, blocked = %v", tt.address, err, tt.blocked)
+			}
+		})
+	}
+}
+
+// A hostname that passes the allowlist but resolves to a blocked address, as after a DNS rebinding, is
+// stopped when the connection is dialed
+func TestTransportBlocksRebinding(t *testing.T) {
+	var reached atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached.Store(true)
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	rebound := "http://localhost:" + target.Port() + "/scan"
+
+	tests := []struct {
+		name    string
+		policy  *DestinationPolicy
+		blocked bool
+	}{
+		{name: "blocked", policy: &DestinationPolicy{AllowedSchemes: map[string]bool{"http": true}, AllowedHosts: []string{"localhost"}}, blocked: true},
+		{name: "exempt network", policy: &DestinationPolicy{AllowedSchemes: map[string]bool{"http": true}, AllowedNetworks: mustParseCIDRs("127.0.0.0/8", "::1/128")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached.Store(false)
+			req, _ := http.NewRequest(http.MethodPost, rebound, nil)
+
+			// The hostname is not resolved before sending when there is no proxy; only the dial check applies
+			u, _ := url.Parse(rebound)
+			if err := tt.policy.CheckURL(context.Background(), u, false); err != nil {
+				t.Fatalf("CheckURL() = %v, want the hostname to pass before resolution", err)
+			}
+
+			client := &http.Client{Transport: tt.policy.transport(), CheckRedirect: tt.policy.checkRedirect}
+			resp, err := client.Do(req)
+			if resp != nil {
+				resp.Body.Close()
+			}
+
+			if tt.blocked {
+				if !errors.Is(err, ErrDestinationBlocked) {
+					t.Fatalf("Do() = %v, want ErrDestinationBlocked", err)
+				}
+				if reached.Load() {
+					t.Fatal("the blocked receiver was reached")
+				}
+				return
+			}
+			if err != nil || !reached.Load() {
+				t.Fatalf("Do() = %v, reached = %v, want the exempt receiver reached", err, reached.Load())
+			}
+		})
+	}
+}
+
+func TestCheckRedirect(t *testing.T) {
+	policy := &DestinationPolicy{AllowedSchemes: map[string]bool{"http": true, "https": true}, MaxRedirects: 2}
+
+	public, _ := http.NewRequest(http.MethodGet, "https://93.184.216.34/next", nil)
+	metadata, _ := http.NewRequest(http.MethodGet, "http://169.254.169.254/latest/meta-data/", nil)
+	via := []*http.Request{public}
+
+	if err := policy.checkRedirect(public, via); err != nil {
+		t.Fatalf("checkRedirect() to a public address = %v", err)
+	}
+	if err := policy.checkRedirect(metadata, via); !errors.Is(err, ErrDestinationBlocked) {
+		t.Fatalf("checkRedirect() to the metadata endpoint = %v, want it blocked", err)
+	}
+	if err := policy.checkRedirect(public, []*http.Request{public, public, public}); !errors.Is(err, ErrDestinationBlocked) {
+		t.Fatalf("checkRedirect() past the limit = %v, want it blocked", err)
+	}
+}
+
+func TestDestinationPolicyFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+		check   func(t *testing.T, p *DestinationPolicy)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, p *DestinationPolicy) {
+				if !p.AllowedSchemes["http"] || !p.AllowedSchemes["https"] || p.AllowPrivate || p.MaxRedirects != defaultMaxRedirects {
+					t.Fatalf("unexpected defaults: %+v", p)
+				}
+			},
+		},
+		{
+			name: "settings",
+			env: map[string]string{
+				"WEBHOOK_ALLOWED_SCHEMES":        "HTTPS",
+				"WEBHOOK_ALLOWED_HOSTS":          "Hooks.Example.com, *.ci.example.com",
+				"WEBHOOK_ALLOWED_NETWORKS":       "10.1.0.0/16",
+				"WEBHOOK_ALLOW_PRIVATE_NETWORKS": "true",
+				"WEBHOOK_MAX_REDIRECTS":          "0",
+			},
+			check: func(t *testing.T, p *DestinationPolicy) {
+				if p.AllowedSchemes["http"] || !p.AllowedSchemes["https"] {
+					t.Fatalf("schemes = %v", p.AllowedSchemes)
+				}
+				if len(p.AllowedHosts) != 2 || p.AllowedHosts[0] != "hooks.example.com" {
+					t.Fatalf("hosts = %v", p.AllowedHosts)
+				}
+				if len(p.AllowedNetworks) != 1 || !p.AllowPrivate || p.MaxRedirects != 0 {
+					t.Fatalf("unexpected policy: %+v", p)
+				}
+			},
+		},
+		{name: "unsupported scheme", env: map[string]string{"WEBHOOK_ALLOWED_SCHEMES": "file"}, wantErr: true},
+		{name: "bad host pattern", env: map[string]string{"WEBHOOK_ALLOWED_HOSTS": "[a-"}, wantErr: true},
+		{name: "bad network", env: map[string]string{"WEBHOOK_ALLOWED_NETWORKS": "10.0.0.0"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"WEBHOOK_ALLOWED_SCHEMES", "WEBHOOK_ALLOWED_HOSTS", "WEBHOOK_ALLOWED_NETWORKS", "WEBHOOK_ALLOW_PRIVATE_NETWORKS", "WEBHOOK_MAX_REDIRECTS"} {
+				t.Setenv(key, tt.env[key])
+			}
+			policy, err := destinationPolicyFromEnv()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("destinationPolicyFromEnv() = %v", err)
+			}
+			tt.check(t, policy)
+		})
+	}
+}
+
+func TestDestinationPoliciesAreIndependent(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOWED_HOSTS", "")
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "")
+	t.Setenv("GIT_SOURCE_ALLOWED_HOSTS", "git.internal.example.com")
+	t.Setenv("GIT_SOURCE_ALLOW_PRIVATE_NETWORKS", "true")
+
+	hooks, err := destinationPolicyFromEnv()
+	if err != nil {
+		t.Fatalf("destinationPolicyFromEnv() = %v", err)
+	}
+	git, err := DestinationPolicyFromEnv("GIT_SOURCE")
+	if err != nil {
+		t.Fatalf("DestinationPolicyFromEnv() = %v", err)
+	}
+	if hooks.AllowPrivate || len(hooks.AllowedHosts) != 0 {
+		t.Fatalf("webhook policy = %+v, want the git settings not to apply", hooks)
+	}
+	if len(git.AllowedSchemes) != 0 || !git.AllowPrivate {
+		t.Fatalf("git policy = %+v", git)
+	}
+
+	if _, err := git.ResolveAllowed(context.Background(), "10.0.0.8"); !errors.Is(err, ErrDestinationBlocked) {
+		t.Fatalf("ResolveAllowed() = %v, want the host allowlist applied", err)
+	}
+	git.AllowedHosts = nil
+	if ips, err := git.ResolveAllowed(context.Background(), "10.0.0.8"); err != nil || len(ips) != 1 {
+		t.Fatalf("ResolveAllowed() = %v, %v; want the private address allowed for git", ips, err)
+	}
+	if _, err := hooks.ResolveAllowed(context.Background(), "10.0.0.8"); !errors.Is(err, ErrDestinationBlocked) {
+		t.Fatalf("ResolveAllowed() = %v, want private addresses blocked for webhooks", err)
+	}
+}
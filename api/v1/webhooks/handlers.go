@@ -0,0 +1,363 @@
+// api/v1/webhooks/handlers.go
+package webhooks
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/madhatkul/CxWrapper-v2/util"
+)
+
+type WebhookHandler struct {
+	service *WebhookService
+	logger  util.Logger
+}
+
+func NewWebhookHandler(service *WebhookService, logger util.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes registers all webhook routes with the given router group
+func (wh *WebhookHandler) RegisterRoutes(v1 *gin.RouterGroup) {
+	webhooks := v1.Group("/webhooks")
+	{
+		subscriptions := webhooks.Group("/subscriptions")
+		{
+			subscriptions.POST("", wh.CreateSubscription)
+			subscriptions.GET("", wh.ListSubscriptions)
+			subscriptions.GET("/:id", wh.GetSubscription)
+			subscriptions.PUT("/:id", wh.UpdateSubscription)
+			subscriptions.DELETE("/:id", wh.DeleteSubscription)
+		}
+
+		deliveries := webhooks.Group("/deliveries")
+		{
+			deliveries.GET("", wh.ListDeliveries)
+			deliveries.GET("/:id", wh.GetDelivery)
+			deliveries.POST("/:id/replay", wh.ReplayDelivery)
+		}
+
+		webhooks.GET("/schemas", wh.ListSchemas)
+		webhooks.GET("/schemas/:event/:version", wh.GetSchema)
+	}
+}
+
+// CreateSubscription handles POST /v1/webhooks/subscriptions
+func (wh *WebhookHandler) CreateSubscription(c *gin.Context) {
+	var req CreateSubscriptionRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		wh.logger.Errorf("Invalid request body: %v", err)
+		wh.error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	sub, err := wh.service.CreateSubscription(&req)
+	if err != nil {
+		wh.logger.Errorf("❌ Failed to create webhook subscription: %v", err)
+		wh.error(c, http.StatusBadRequest, "Failed to create subscription", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, toSubscriptionResponse(sub))
+}
+
+// ListSubscriptions handles GET /v1/webhooks/subscriptions
+func (wh *WebhookHandler) ListSubscriptions(c *gin.Context) {
+	subs, err := wh.service.ListSubscriptions(c.Query("app_name"), c.Query("project_name"))
+	if err != nil {
+		wh.logger.Errorf("❌ Failed to list webhook subscriptions: %v", err)
+		wh.error(c, http.StatusInternalServerError, "Failed to list subscriptions", err.Error())
+		return
+	}
+
+	response := SubscriptionListResponse{
+		Subscriptions: make([]SubscriptionResponse, 0, len(subs)),
+		Total:         len(subs),
+	}
+	for i := range subs {
+		response.Subscriptions = append(response.Subscriptions, toSubscriptionResponse(&subs[i]))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetSubscription handles GET /v1/webhooks/subscriptions/{id}
+func (wh *WebhookHandler) GetSubscription(c *gin.Context) {
+	sub, err := wh.service.GetSubscription(c.Param("id"))
+	if err != nil {
+		wh.error(c, http.StatusNotFound, "Subscription not found", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, toSubscriptionResponse(sub))
+}
+
+// UpdateSubscription handles PUT /v1/webhooks/subscriptions/{id}
+func (wh *WebhookHandler) UpdateSubscription(c *gin.Context) {
+	var req UpdateSubscriptionRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		wh.logger.Errorf("Invalid request body: %v", err)
+		wh.error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	sub, err := wh.service.UpdateSubscription(c.Param("id"), &req)
+	if err != nil {
+		wh.logger.Errorf("❌ Failed to update webhook subscription %s: %v", c.Param("id"), err)
+		statusCode := http.StatusBadRequest
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+		wh.error(c, statusCode, "Failed to update subscription", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, toSubscriptionResponse(sub))
+}
+
+// DeleteSubscription handles DELETE /v1/webhooks/subscriptions/{id}
+func (wh *WebhookHandler) DeleteSubscription(c *gin.Context) {
+	if err := wh.service.DeleteSubscription(c.Param("id")); err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+		wh.error(c, statusCode, "Failed to delete subscription", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Subscription deleted successfully",
+	})
+}
+
+// ListDeliveries handles GET /v1/webhooks/deliveries
+func (wh *WebhookHandler) ListDeliveries(c *gin.Context) {
+	filter := DeliveryFilter{
+		ScanID:         c.Query("scan_id"),
+		CommitID:       c.Query("commit_id"),
+		SubscriptionID: c.Query("subscription_id"),
+		Status:         DeliveryStatus(c.Query("status")),
+	}
+
+	// Parse pagination parameters
+	limit := 20
+	offset := 0
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if o, err := strconv.Atoi(c.Query("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	deliveries, err := wh.service.ListDeliveries(filter)
+	if err != nil {
+		wh.logger.Errorf("❌ Failed to list webhook deliveries: %v", err)
+		wh.error(c, http.StatusInternalServerError, "Failed to list deliveries", err.Error())
+		return
+	}
+
+	total := len(deliveries)
+	start := offset
+	end := offset + limit
+	if start > total {
+		start = total
+	}
+	if end > total {
+		end = total
+	}
+
+	response := DeliveryListResponse{
+		Deliveries: make([]DeliveryResponse, 0, end-start),
+		Total:      total,
+		Limit:      limit,
+		Offset:     offset,
+	}
+	for _, delivery := range deliveries[start:end] {
+		attempts, err := wh.service.Attempts(delivery.ID)
+		if err != nil {
+			wh.logger.Warnf("Failed to load attempts for delivery %s: %v", delivery.ID, err)
+		}
+		response.Deliveries = append(response.Deliveries, toDeliveryResponse(delivery, attempts))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetDelivery handles GET /v1/webhooks/deliveries/{id}
+func (wh *WebhookHandler) GetDelivery(c *gin.Context) {
+	delivery, attempts, err := wh.service.GetDelivery(c.Param("id"))
+	if err != nil {
+		wh.error(c, http.StatusNotFound, "Delivery not found", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, toDeliveryResponse(*delivery, attempts))
+}
+
+// ReplayDelivery handles POST /v1/webhooks/deliveries/{id}/replay
+func (wh *WebhookHandler) ReplayDelivery(c *gin.Context) {
+	var req ReplayDeliveryRequest
+
+	// The body is optional; without it the payload goes back to the original URL
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			wh.logger.Errorf("Invalid request body: %v", err)
+			wh.error(c, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+	}
+
+	replay, err := wh.service.ReplayDelivery(c.Param("id"), req.URL)
+	if err != nil {
+		wh.logger.Errorf("❌ Failed to replay webhook delivery %s: %v", c.Param("id"), err)
+		statusCode := http.StatusBadRequest
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+		wh.error(c, statusCode, "Failed to replay delivery", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusAccepted, toDeliveryResponse(*replay, nil))
+}
+
+// ListSchemas handles GET /v1/webhooks/schemas
+func (wh *WebhookHandler) ListSchemas(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"schemas": SchemaIndex(),
+	})
+}
+
+// GetSchema handles GET /v1/webhooks/schemas/{event}/{version}
+func (wh *WebhookHandler) GetSchema(c *gin.Context) {
+	schema, err := Schema(c.Param("event"), c.Param("version"))
+	if err != nil {
+		wh.error(c, http.StatusNotFound, "Schema not found", err.Error())
+		return
+	}
+
+	c.Data(http.StatusOK, "application/schema+json", schema)
+}
+
+func (wh *WebhookHandler) error(c *gin.Context, statusCode int, message, details string) {
+	c.JSON(statusCode, ErrorResponse{
+		Error:     message,
+		Details:   details,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Path:      c.Request.URL.Path,
+	})
+}
+
+func toSubscriptionResponse(sub *Subscription) SubscriptionResponse {
+	return SubscriptionResponse{
+		ID:             sub.ID,
+		Name:           sub.Name,
+		URL:            sub.URL,
+		AppName:        sub.AppName,
+		ProjectName:    sub.ProjectName,
+		BranchPattern:  sub.BranchPattern,
+		HeaderNames:    headerNames(sub.Headers),
+		HasSecret:      sub.Secret != "",
+		TLS:            toTLSInfo(sub.TLS),
+		Events:         sub.Events,
+		PayloadFormat:  payloadFormatOrDefault(sub.PayloadFormat),
+		PayloadVersion: payloadVersionOrDefault(sub.PayloadVersion),
+		Enabled:        sub.Enabled,
+		CreatedAt:      sub.CreatedAt,
+		UpdatedAt:      sub.UpdatedAt,
+	}
+}
+
+func headerNames(headers map[string]string) []string {
+	if len(headers) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func toTLSInfo(settings *TLSSettings) *TLSInfo {
+	if settings == nil {
+		return nil
+	}
+	return &TLSInfo{
+		HasCABundle:        settings.CABundle != "",
+		HasClientCert:      settings.ClientCert != "",
+		MinVersion:         settings.MinVersion,
+		InsecureSkipVerify: settings.InsecureSkipVerify,
+	}
+}
+
+func payloadFormatOrDefault(format string) string {
+	if format == "" {
+		return PayloadFormatLegacy
+	}
+	return format
+}
+
+func payloadVersionOrDefault(version string) string {
+	if version == "" {
+		return DefaultPayloadVersion
+	}
+	return version
+}
+
+func toDeliveryResponse(delivery Delivery, attempts []DeliveryAttempt) DeliveryResponse {
+	response := DeliveryResponse{
+		ID:             delivery.ID,
+		ScanID:         delivery.ScanID,
+		CommitID:       delivery.CommitID,
+		EventType:      delivery.EventType,
+		SubscriptionID: delivery.SubscriptionID,
+		Target:         toTargetResponse(delivery.Target),
+		ContentType:    delivery.ContentType,
+		EventHeaders:   delivery.EventHeaders,
+		Payload:        delivery.Payload,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		UpdatedAt:      delivery.UpdatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+		ReplayOf:       delivery.ReplayOf,
+		AttemptHistory: attempts,
+	}
+	if response.AttemptHistory == nil {
+		response.AttemptHistory = []DeliveryAttempt{}
+	}
+	if len(attempts) > 0 {
+		last := attempts[len(attempts)-1]
+		response.LastStatusCode = last.StatusCode
+		response.LastLatencyMs = last.LatencyMs
+	}
+	return response
+}
+
+func toTargetResponse(target WebhookConfig) TargetResponse {
+	response := TargetResponse{
+		URL:         target.URL,
+		HeaderNames: headerNames(target.Headers),
+	}
+	if target.Timeout > 0 {
+		response.Timeout = target.Timeout.String()
+	}
+	return response
+}
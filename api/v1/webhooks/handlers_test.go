@@ -0,0 +1,46 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeliveryResponseWithholdsHeaderValues(t *testing.T) {
+	delivery := Delivery{
+		ID:     "delivery-1",
+		ScanID: "scan-1",
+		Target: WebhookConfig{
+			URL:     "https://hooks.example.com/cx",
+			Timeout: 30 * time.Second,
+			Headers: map[string]string{"X-Api-Key": "k3y-value", "Authorization": "Bearer t0ken-value"},
+		},
+		Payload: json.RawMessage(`{"scan_id":"scan-1"}`),
+		Status:  DeliveryStatusDelivered,
+	}
+	attempts := []DeliveryAttempt{{DeliveryID: "delivery-1", Number: 1, StatusCode: 200, LatencyMs: 12}}
+
+	response := toDeliveryResponse(delivery, attempts)
+	want := TargetResponse{URL: "https://hooks.example.com/cx", Timeout: "30s", HeaderNames: []string{"Authorization", "X-Api-Key"}}
+	if !reflect.DeepEqual(response.Target, want) {
+		t.Fatalf("target = %+v, want %+v", response.Target, want)
+	}
+	if response.LastStatusCode != 200 || response.LastLatencyMs != 12 {
+		t.Fatalf("last attempt = %d in %dms, want 200 in 12ms", response.LastStatusCode, response.LastLatencyMs)
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"k3y-value", "t0ken-value"} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("response %s contains a header value", data)
+		}
+	}
+	if !strings.Contains(string(data), `"timeout":"30s"`) {
+		t.Fatalf("response %s does not give the timeout as a duration", data)
+	}
+}
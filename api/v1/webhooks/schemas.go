@@ -0,0 +1,67 @@
+// api/v1/webhooks/schemas.go
+package webhooks
+
+import (
+	"embed"
+	"fmt"
+	"sort"
+)
+
+//go:embed schemas/*.json
+var schemaFS embed.FS
+
+// schemaFiles maps payload version and event type to the JSON Schema describing that payload
+var schemaFiles = map[string]map[string]string{
+	PayloadVersionV1: {
+		EventScanQueued:         "scan.status.v1.json",
+		EventScanRunning:        "scan.status.v1.json",
+		EventScanCompleted:      "scan.completed.v1.json",
+		EventScanPartial:        "scan.status.v1.json",
+		EventScanFailed:         "scan.status.v1.json",
+		EventScanCanceled:       "scan.status.v1.json",
+		EventScanEngineProgress: "scan.engine_progress.v1.json",
+		EventScanSuperseded:     "scan.status.v1.json",
+	},
+	PayloadVersionV2: {
+		EventScanQueued:         "scan.event.v2.json",
+		EventScanRunning:        "scan.event.v2.json",
+		EventScanCompleted:      "scan.event.v2.json",
+		EventScanPartial:        "scan.event.v2.json",
+		EventScanFailed:         "scan.event.v2.json",
+		EventScanCanceled:       "scan.event.v2.json",
+		EventScanEngineProgress: "scan.event.v2.json",
+		EventScanSuperseded:     "scan.event.v2.json",
+	},
+}
+
+// Schema returns the JSON Schema for an event type's payload in the given version
+func Schema(eventType, version string) ([]byte, error) {
+	file, ok := schemaFiles[version][eventType]
+	if !ok {
+		return nil, fmt.Errorf("no schema for event %s version %s", eventType, version)
+	}
+	return schemaFS.ReadFile("schemas/" + file)
+}
+
+// SchemaIndex lists every published schema with its dataschema URI
+func SchemaIndex() []SchemaInfo {
+	var index []SchemaInfo
+	for version, events := range schemaFiles {
+		for eventType := range events {
+			index = append(index, SchemaInfo{
+				Event:      eventType,
+				Version:    version,
+				DataSchema: SchemaURI(eventType, version),
+			})
+		}
+	}
+
+	sort.Slice(index, func(i, j int) bool {
+		if index[i].Event != index[j].Event {
+			return index[i].Event < index[j].Event
+		}
+		return index[i].Version < index[j].Version
+	})
+
+	return index
+}
@@ -0,0 +1,768 @@
+// api/v1/webhooks/service.go
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mathrand "math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/madhatkul/CxWrapper-v2/api/v1/webhooks/signature"
+	"github.com/madhatkul/CxWrapper-v2/util"
+	bolt "go.etcd.io/bbolt"
+)
+
+// HeaderEventType carries the event type so receivers can route deliveries without parsing the body
+const HeaderEventType = "X-CxWrapper-Event"
+
+const (
+	defaultTimeout     = 30 * time.Second
+	queuePollInterval  = 5 * time.Second
+	defaultMaxAttempts = 8
+	defaultBaseDelay   = 10 * time.Second
+	defaultMaxDelay    = 30 * time.Minute
+	// maxTransports bounds the cached transports, one per distinct TLS settings
+	maxTransports = 64
+)
+
+type WebhookService struct {
+	store         *DeliveryStore
+	subscriptions *SubscriptionStore
+	legacyURL     string
+	policy        RetryPolicy
+	signingSecret []byte
+	defaultTLS    *TLSSettings
+	destinations  *DestinationPolicy
+	logger        util.Logger
+	wake          chan struct{}
+	mu            sync.Mutex
+	inFlight      map[string]bool
+	listeners     []DeliveryListener
+	// transports are shared by the deliveries with the same TLS settings, see transportFor
+	transportMu sync.Mutex
+	transports  map[string]*http.Transport
+}
+
+func NewWebhookService(db *bolt.DB, logger util.Logger) (*WebhookService, error) {
+	store, err := NewDeliveryStore(db)
+	if err != nil {
+		return nil, err
+	}
+	subscriptions, err := NewSubscriptionStore(db)
+	if err != nil {
+		return nil, err
+	}
+	defaultTLS, err := defaultTLSFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	destinations, err := destinationPolicyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	ws := &WebhookService{
+		store:         store,
+		subscriptions: subscriptions,
+		legacyURL:     os.Getenv("STATIC_WEBHOOK_URL"),
+		policy:        retryPolicyFromEnv(),
+		signingSecret: []byte(os.Getenv("WEBHOOK_SIGNING_SECRET")),
+		defaultTLS:    defaultTLS,
+		destinations:  destinations,
+		logger:        logger,
+		wake:          make(chan struct{}, 1),
+		inFlight:      make(map[string]bool),
+		transports:    make(map[string]*http.Transport),
+	}
+
+	if ws.legacyURL != "" {
+		if err := ws.validateTargetURL(ws.legacyURL); err != nil {
+			logger.Warnf("⚠️ STATIC_WEBHOOK_URL is not an allowed destination, deliveries to it will fail: %v", err)
+		}
+	}
+
+	return ws, nil
+}
+
+// retryPolicyFromEnv reads WEBHOOK_MAX_ATTEMPTS, WEBHOOK_BACKOFF_BASE and WEBHOOK_BACKOFF_MAX, falling back to defaults
+func retryPolicyFromEnv() RetryPolicy {
+	policy := RetryPolicy{
+		MaxAttempts: defaultMaxAttempts,
+		BaseDelay:   defaultBaseDelay,
+		MaxDelay:    defaultMaxDelay,
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS")); err == nil && v > 0 {
+		policy.MaxAttempts = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("WEBHOOK_BACKOFF_BASE")); err == nil && v > 0 {
+		policy.BaseDelay = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("WEBHOOK_BACKOFF_MAX")); err == nil && v > 0 {
+		policy.MaxDelay = v
+	}
+
+	return policy
+}
+
+// OnFinished registers a listener called when a delivery is delivered or dead-lettered
+func (ws *WebhookService) OnFinished(listener DeliveryListener) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.listeners = append(ws.listeners, listener)
+}
+
+// Start runs the delivery worker until ctx is cancelled. Deliveries left in the queue by a previous run are picked up on the first pass.
+func (ws *WebhookService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(queuePollInterval)
+		defer ticker.Stop()
+
+		for {
+			ws.dispatchDue()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-ws.wake:
+			}
+		}
+	}()
+}
+
+// Publish queues a delivery of the event to every enabled subscription that matches it. STATIC_WEBHOOK_URL,
+// when set, is kept as a catch-all receiver for completion events.
+func (ws *WebhookService) Publish(event Event) ([]*Delivery, error) {
+	subs, err := ws.subscriptions.List()
+	if err != nil {
+		return nil, err
+	}
+
+	if event.ID == "" {
+		event.ID = newDeliveryID()
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	var deliveries []*Delivery
+	for _, sub := range subs {
+		if !sub.Matches(event) {
+			continue
+		}
+
+		payload, err := renderPayload(event, sub.PayloadFormat, sub.PayloadVersion)
+		if err != nil {
+			ws.logger.Errorf("❌ Failed to render %s for subscription %s: %v", event.Type, sub.ID, err)
+			continue
+		}
+
+		target := WebhookConfig{URL: sub.URL, Timeout: defaultTimeout, Headers: sub.Headers}
+		delivery, err := ws.enqueue(target, sub.ID, event, payload)
+		if err != nil {
+			return deliveries, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	// The legacy receiver predates lifecycle events and only understands the v1 completion payload
+	if ws.legacyURL != "" && event.Type == EventScanCompleted {
+		payload, err := renderPayload(event, PayloadFormatLegacy, PayloadVersionV1)
+		if err != nil {
+			return deliveries, err
+		}
+
+		delivery, err := ws.enqueue(WebhookConfig{URL: ws.legacyURL, Timeout: defaultTimeout}, "", event, payload)
+		if err != nil {
+			return deliveries, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+// Matches reports whether the subscription is enabled and scoped to the event's application, project, branch and type
+func (sub *Subscription) Matches(event Event) bool {
+	if !sub.Enabled {
+		return false
+	}
+	if sub.AppName != "" && sub.AppName != event.AppName {
+		return false
+	}
+	if sub.ProjectName != "" && sub.ProjectName != event.ProjectName {
+		return false
+	}
+	if sub.BranchPattern != "" {
+		if ok, err := path.Match(sub.BranchPattern, event.Branch); err != nil || !ok {
+			return false
+		}
+	}
+	if len(sub.Events) == 0 {
+		return true
+	}
+	for _, eventType := range sub.Events {
+		if eventType == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueue persists a delivery of the rendered event payload to target and schedules its first attempt immediately
+func (ws *WebhookService) enqueue(target WebhookConfig, subscriptionID string, event Event, payload *renderedPayload) (*Delivery, error) {
+	if target.URL == "" {
+		return nil, fmt.Errorf("webhook URL is required")
+	}
+	if target.Timeout <= 0 {
+		target.Timeout = defaultTimeout
+	}
+
+	now := time.Now().UTC()
+	delivery := &Delivery{
+		ID:             newDeliveryID(),
+		ScanID:         event.ScanID,
+		CommitID:       event.CommitID,
+		EventType:      event.Type,
+		SubscriptionID: subscriptionID,
+		Target:         target,
+		ContentType:    payload.ContentType,
+		EventHeaders:   payload.Headers,
+		Payload:        payload.Body,
+		Status:         DeliveryStatusPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if err := ws.store.Enqueue(delivery); err != nil {
+		return nil, fmt.Errorf("failed to enqueue webhook delivery: %w", err)
+	}
+
+	ws.logger.Infof("📬 Webhook delivery %s (%s) queued for scan ID %s to %s", delivery.ID, event.Type, event.ScanID, target.URL)
+
+	ws.wakeWorker()
+
+	return delivery, nil
+}
+
+// wakeWorker asks the delivery worker to check the queue without waiting for the next tick
+func (ws *WebhookService) wakeWorker() {
+	select {
+	case ws.wake <- struct{}{}:
+	default:
+	}
+}
+
+// DeadLetters returns deliveries that exhausted their retries
+func (ws *WebhookService) DeadLetters() ([]DeadLetter, error) {
+	return ws.store.DeadLetters()
+}
+
+// DeliveriesForScan returns every delivery queued for the given scan ID
+func (ws *WebhookService) DeliveriesForScan(scanID string) ([]Delivery, error) {
+	return ws.store.List(DeliveryFilter{ScanID: scanID})
+}
+
+// ListDeliveries returns deliveries matching the filter, newest first
+func (ws *WebhookService) ListDeliveries(filter DeliveryFilter) ([]Delivery, error) {
+	return ws.store.List(filter)
+}
+
+// GetDelivery returns a delivery together with its attempts
+func (ws *WebhookService) GetDelivery(id string) (*Delivery, []DeliveryAttempt, error) {
+	delivery, err := ws.store.Get(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("delivery not found: %s", id)
+	}
+
+	attempts, err := ws.store.Attempts(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return delivery, attempts, nil
+}
+
+// Attempts returns the recorded attempts of a delivery
+func (ws *WebhookService) Attempts(deliveryID string) ([]DeliveryAttempt, error) {
+	return ws.store.Attempts(deliveryID)
+}
+
+// ReplayDelivery queues a new delivery with the stored payload of an earlier one, sent to its original URL
+// or to overrideURL when given. The payload is signed again when it is sent.
+func (ws *WebhookService) ReplayDelivery(id string, overrideURL string) (*Delivery, error) {
+	original, err := ws.store.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("delivery not found: %s", id)
+	}
+
+	target := original.Target
+	subscriptionID := original.SubscriptionID
+	if overrideURL != "" {
+		if err := ws.validateTargetURL(overrideURL); err != nil {
+			return nil, err
+		}
+		// A different receiver does not get the subscription's custom headers or secret
+		target = WebhookConfig{URL: overrideURL, Timeout: original.Target.Timeout}
+		subscriptionID = ""
+	}
+
+	now := time.Now().UTC()
+	replay := &Delivery{
+		ID:             newDeliveryID(),
+		ScanID:         original.ScanID,
+		CommitID:       original.CommitID,
+		EventType:      original.EventType,
+		SubscriptionID: subscriptionID,
+		Target:         target,
+		ContentType:    original.ContentType,
+		EventHeaders:   original.EventHeaders,
+		Payload:        original.Payload,
+		Status:         DeliveryStatusPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		ReplayOf:       original.ID,
+	}
+
+	if err := ws.store.Enqueue(replay); err != nil {
+		return nil, fmt.Errorf("failed to enqueue replay: %w", err)
+	}
+
+	ws.logger.Infof("🔁 Webhook delivery %s queued as replay of %s to %s", replay.ID, original.ID, target.URL)
+	ws.wakeWorker()
+
+	return replay, nil
+}
+
+// CreateSubscription validates and stores a new subscription
+func (ws *WebhookService) CreateSubscription(req *CreateSubscriptionRequest) (*Subscription, error) {
+	if err := ws.validateTargetURL(req.URL); err != nil {
+		return nil, err
+	}
+	if err := validateSubscriptionScope(req.BranchPattern, req.Events); err != nil {
+		return nil, err
+	}
+	if err := validatePayloadOptions(req.PayloadFormat, req.PayloadVersion); err != nil {
+		return nil, err
+	}
+	if err := validateTLSSettings(req.TLS); err != nil {
+		return nil, err
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	now := time.Now().UTC()
+	sub := &Subscription{
+		ID:             newDeliveryID(),
+		Name:           req.Name,
+		URL:            req.URL,
+		AppName:        req.AppName,
+		ProjectName:    req.ProjectName,
+		BranchPattern:  req.BranchPattern,
+		Headers:        req.Headers,
+		Secret:         req.Secret,
+		TLS:            req.TLS,
+		Events:         req.Events,
+		PayloadFormat:  req.PayloadFormat,
+		PayloadVersion: req.PayloadVersion,
+		Enabled:        enabled,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if err := ws.subscriptions.Save(sub); err != nil {
+		return nil, fmt.Errorf("failed to save subscription: %w", err)
+	}
+
+	if sub.TLS != nil && sub.TLS.InsecureSkipVerify {
+		ws.logger.Warnf("⚠️ Webhook subscription %s has certificate verification disabled", sub.ID)
+	}
+
+	ws.logger.Infof("✅ Webhook subscription %s created for %s", sub.ID, sub.URL)
+	return sub, nil
+}
+
+// GetSubscription returns the subscription with the given ID
+func (ws *WebhookService) GetSubscription(id string) (*Subscription, error) {
+	return ws.subscriptions.Get(id)
+}
+
+// ListSubscriptions returns subscriptions, optionally narrowed to an application and project
+func (ws *WebhookService) ListSubscriptions(appName, projectName string) ([]Subscription, error) {
+	subs, err := ws.subscriptions.List()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]Subscription, 0, len(subs))
+	for _, sub := range subs {
+		if appName != "" && sub.AppName != appName {
+			continue
+		}
+		if projectName != "" && sub.ProjectName != projectName {
+			continue
+		}
+		filtered = append(filtered, sub)
+	}
+
+	return filtered, nil
+}
+
+// UpdateSubscription applies the provided fields to an existing subscription
+func (ws *WebhookService) UpdateSubscription(id string, req *UpdateSubscriptionRequest) (*Subscription, error) {
+	sub, err := ws.subscriptions.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		sub.Name = *req.Name
+	}
+	if req.URL != nil {
+		if err := ws.validateTargetURL(*req.URL); err != nil {
+			return nil, err
+		}
+		sub.URL = *req.URL
+	}
+	if req.AppName != nil {
+		sub.AppName = *req.AppName
+	}
+	if req.ProjectName != nil {
+		sub.ProjectName = *req.ProjectName
+	}
+	if req.BranchPattern != nil {
+		sub.BranchPattern = *req.BranchPattern
+	}
+	if req.Headers != nil {
+		sub.Headers = *req.Headers
+	}
+	if req.Secret != nil {
+		sub.Secret = *req.Secret
+	}
+	if req.TLS != nil {
+		// An empty object clears the subscription's TLS settings
+		if *req.TLS == (TLSSettings{}) {
+			sub.TLS = nil
+		} else {
+			sub.TLS = req.TLS
+		}
+	}
+	if req.Events != nil {
+		sub.Events = *req.Events
+	}
+	if req.PayloadFormat != nil {
+		sub.PayloadFormat = *req.PayloadFormat
+	}
+	if req.PayloadVersion != nil {
+		sub.PayloadVersion = *req.PayloadVersion
+	}
+	if req.Enabled != nil {
+		sub.Enabled = *req.Enabled
+	}
+
+	if err := validateSubscriptionScope(sub.BranchPattern, sub.Events); err != nil {
+		return nil, err
+	}
+	if err := validatePayloadOptions(sub.PayloadFormat, sub.PayloadVersion); err != nil {
+		return nil, err
+	}
+	if err := validateTLSSettings(sub.TLS); err != nil {
+		return nil, err
+	}
+
+	sub.UpdatedAt = time.Now().UTC()
+	if err := ws.subscriptions.Save(sub); err != nil {
+		return nil, fmt.Errorf("failed to save subscription: %w", err)
+	}
+
+	return sub, nil
+}
+
+// DeleteSubscription removes a subscription. Deliveries already queued for it are still attempted.
+func (ws *WebhookService) DeleteSubscription(id string) error {
+	return ws.subscriptions.Delete(id)
+}
+
+// validateTargetURL checks that a receiver URL is well formed and that the destination policy allows it
+func (ws *WebhookService) validateTargetURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid webhook URL: scheme must be http or https")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid webhook URL: host is required")
+	}
+
+	if err := ws.destinations.CheckURL(context.Background(), u, true); err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	return nil
+}
+
+func validateSubscriptionScope(branchPattern string, events []string) error {
+	if branchPattern != "" {
+		if _, err := path.Match(branchPattern, ""); err != nil {
+			return fmt.Errorf("invalid branch_pattern: %v", err)
+		}
+	}
+	for _, eventType := range events {
+		if !knownEvents[eventType] {
+			return fmt.Errorf("unknown event type: %s", eventType)
+		}
+	}
+	return nil
+}
+
+// secretFor returns the signing secret for a delivery: the subscription's own secret, or the global one
+func (ws *WebhookService) secretFor(delivery *Delivery) []byte {
+	if delivery.SubscriptionID != "" {
+		if sub, err := ws.subscriptions.Get(delivery.SubscriptionID); err == nil && sub.Secret != "" {
+			return []byte(sub.Secret)
+		}
+	}
+	return ws.signingSecret
+}
+
+func (ws *WebhookService) dispatchDue() {
+	due, err := ws.store.Due(time.Now().UTC())
+	if err != nil {
+		ws.logger.Errorf("❌ %v", err)
+		return
+	}
+
+	for _, delivery := range due {
+		if !ws.claim(delivery.ID) {
+			continue
+		}
+
+		go func(delivery Delivery) {
+			defer ws.release(delivery.ID)
+			ws.attempt(&delivery)
+		}(delivery)
+	}
+}
+
+func (ws *WebhookService) claim(id string) bool {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.inFlight[id] {
+		return false
+	}
+	ws.inFlight[id] = true
+	return true
+}
+
+func (ws *WebhookService) release(id string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	delete(ws.inFlight, id)
+}
+
+// attempt makes one HTTP call for the delivery and schedules a retry, marks it delivered, or dead-letters it
+func (ws *WebhookService) attempt(delivery *Delivery) {
+	started := time.Now().UTC()
+	statusCode, sendErr := ws.send(delivery)
+
+	delivery.Attempts++
+	delivery.UpdatedAt = time.Now().UTC()
+
+	attempt := DeliveryAttempt{
+		DeliveryID: delivery.ID,
+		Number:     delivery.Attempts,
+		StartedAt:  started,
+		LatencyMs:  time.Since(started).Milliseconds(),
+		StatusCode: statusCode,
+	}
+
+	finished := false
+	switch {
+	case sendErr == nil:
+		delivery.Status = DeliveryStatusDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &delivery.UpdatedAt
+		finished = true
+		ws.logger.Infof("✅ Webhook delivery %s succeeded on attempt %d", delivery.ID, delivery.Attempts)
+	case delivery.Attempts >= ws.policy.MaxAttempts || !isRetryable(statusCode, sendErr):
+		attempt.Error = sendErr.Error()
+		delivery.Status = DeliveryStatusDeadLettered
+		delivery.LastError = sendErr.Error()
+		finished = true
+		ws.logger.Errorf("❌ Webhook delivery %s failed permanently after %d attempts: %v", delivery.ID, delivery.Attempts, sendErr)
+	default:
+		attempt.Error = sendErr.Error()
+		delivery.LastError = sendErr.Error()
+		delivery.NextAttemptAt = delivery.UpdatedAt.Add(ws.backoff(delivery.Attempts))
+		ws.logger.Warnf("⚠️ Webhook delivery %s attempt %d failed: %v. Retrying at %s", delivery.ID, delivery.Attempts, sendErr, delivery.NextAttemptAt.Format(time.RFC3339))
+	}
+
+	if err := ws.store.RecordAttempt(delivery, attempt); err != nil {
+		ws.logger.Errorf("❌ Failed to record attempt for webhook delivery %s: %v", delivery.ID, err)
+	}
+
+	if delivery.Status == DeliveryStatusDeadLettered {
+		if err := ws.store.DeadLetter(delivery, delivery.LastError); err != nil {
+			ws.logger.Errorf("❌ Failed to dead-letter webhook delivery %s: %v", delivery.ID, err)
+		}
+	}
+
+	if finished {
+		ws.notify(*delivery)
+	}
+}
+
+func (ws *WebhookService) send(delivery *Delivery) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), delivery.Target.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", delivery.Target.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+
+	// Set headers
+	contentType := delivery.ContentType
+	if contentType == "" {
+		contentType = contentTypeJSON
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "CX1-ScanService/1.0")
+	req.Header.Set(signature.HeaderDeliveryID, delivery.ID)
+	req.Header.Set(HeaderEventType, delivery.EventType)
+	for name, value := range delivery.Target.Headers {
+		req.Header.Set(name, value)
+	}
+	for name, value := range delivery.EventHeaders {
+		req.Header.Set(name, value)
+	}
+
+	// Sign each attempt with a fresh timestamp so retries stay within the receiver's tolerance
+	if secret := ws.secretFor(delivery); len(secret) > 0 {
+		signature.SignRequest(req, secret, delivery.ID, delivery.Payload, time.Now())
+	}
+
+	transport, err := ws.transportFor(ws.tlsSettingsFor(delivery))
+	if err != nil {
+		return 0, err
+	}
+
+	req, err = ws.destinations.prepareRequest(req)
+	if err != nil {
+		return 0, err
+	}
+
+	// Send request
+	client := &http.Client{
+		Timeout:       delivery.Target.Timeout,
+		Transport:     transport,
+		CheckRedirect: ws.destinations.checkRedirect,
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send webhook request: %w", describeTLSError(err))
+	}
+	defer resp.Body.Close()
+
+	// Check response status
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook returned non-success status: %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// transportFor returns the transport for a set of TLS settings. Deliveries with the same settings share
+// it, so their keep-alive connections are reused rather than each attempt leaving its own idle ones behind.
+func (ws *WebhookService) transportFor(settings *TLSSettings) (*http.Transport, error) {
+	key, err := tlsSettingsKey(settings)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTLSSettings, err)
+	}
+
+	ws.transportMu.Lock()
+	defer ws.transportMu.Unlock()
+
+	if transport, ok := ws.transports[key]; ok {
+		return transport, nil
+	}
+
+	tlsConfig, err := buildTLSConfig(settings)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTLSSettings, err)
+	}
+	// Settings that changed leave their transport behind; start over rather than let them accumulate
+	if len(ws.transports) >= maxTransports {
+		for _, transport := range ws.transports {
+			transport.CloseIdleConnections()
+		}
+		clear(ws.transports)
+	}
+
+	transport := ws.destinations.transport()
+	transport.TLSClientConfig = tlsConfig
+	ws.transports[key] = transport
+	return transport, nil
+}
+
+// backoff returns the exponential delay before the next attempt, jittered between half and the full delay
+func (ws *WebhookService) backoff(attempts int) time.Duration {
+	delay := ws.policy.BaseDelay
+	for i := 1; i < attempts && delay < ws.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > ws.policy.MaxDelay {
+		delay = ws.policy.MaxDelay
+	}
+
+	half := delay / 2
+	return half + time.Duration(mathrand.Int63n(int64(half)+1))
+}
+
+func (ws *WebhookService) notify(delivery Delivery) {
+	ws.mu.Lock()
+	listeners := append([]DeliveryListener(nil), ws.listeners...)
+	ws.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener(delivery)
+	}
+}
+
+// isRetryable treats transport errors, 5xx, 408 and 429 as transient; other 4xx responses, blocked
+// destinations and TLS configuration or verification failures will not succeed on retry
+func isRetryable(statusCode int, err error) bool {
+	if errors.Is(err, ErrDestinationBlocked) || errors.Is(err, ErrInvalidTLSSettings) || errors.Is(err, ErrTLSHandshake) {
+		return false
+	}
+	if statusCode == 0 || statusCode >= 500 {
+		return true
+	}
+	return statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests
+}
+
+func newDeliveryID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,279 @@
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/madhatkul/CxWrapper-v2/api/v1/webhooks/signature"
+	bolt "go.etcd.io/bbolt"
+)
+
+type testLogger struct{ t *testing.T }
+
+func (l testLogger) Infof(format string, args ...interface{})  { l.t.Logf("INFO "+format, args...) }
+func (l testLogger) Warnf(format string, args ...interface{})  { l.t.Logf("WARN "+format, args...) }
+func (l testLogger) Errorf(format string, args ...interface{}) { l.t.Logf("ERROR "+format, args...) }
+func (l testLogger) Debugf(format string, args ...interface{}) { l.t.Logf("DEBUG "+format, args...) }
+
+// newTestWebhookService returns a webhook service backed by the bolt file at path. env replaces the
+// retry, signing and destination settings; WEBHOOK_ALLOWED_NETWORKS defaults to loopback so deliveries
+// can reach test receivers.
+func newTestWebhookService(t *testing.T, path string, env map[string]string) (*WebhookService, *bolt.DB) {
+	t.Helper()
+
+	t.Setenv("STATIC_WEBHOOK_URL", "")
+	for _, key := range []string{"WEBHOOK_MAX_ATTEMPTS", "WEBHOOK_BACKOFF_BASE", "WEBHOOK_BACKOFF_MAX", "WEBHOOK_SIGNING_SECRET"} {
+		t.Setenv(key, env[key])
+	}
+	networks, ok := env["WEBHOOK_ALLOWED_NETWORKS"]
+	if !ok {
+		networks = "127.0.0.0/8"
+	}
+	t.Setenv("WEBHOOK_ALLOWED_NETWORKS", networks)
+
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ws, err := NewWebhookService(db, testLogger{t})
+	if err != nil {
+		t.Fatalf("NewWebhookService() = %v", err)
+	}
+	return ws, db
+}
+
+// testReceiver answers each request with the next of its statuses, repeating the last one, and
+// records the headers it was sent
+type testReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	received []http.Header
+}
+
+func newTestReceiver(t *testing.T, statuses ...int) *testReceiver {
+	t.Helper()
+
+	receiver := &testReceiver{statuses: statuses}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+
+		status := receiver.statuses[len(receiver.statuses)-1]
+		if n := len(receiver.received); n < len(receiver.statuses) {
+			status = receiver.statuses[n]
+		}
+		receiver.received = append(receiver.received, r.Header.Clone())
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+func (r *testReceiver) deliveryIDs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, len(r.received))
+	for i, header := range r.received {
+		ids[i] = header.Get(signature.HeaderDeliveryID)
+	}
+	return ids
+}
+
+func enqueueTestDelivery(t *testing.T, ws *WebhookService, url string) *Delivery {
+	t.Helper()
+
+	event := Event{Type: EventScanCompleted, ScanID: "scan-1", CommitID: "commit-1"}
+	payload := &renderedPayload{Body: []byte(`{"scan_id":"scan-1"}`), ContentType: contentTypeJSON}
+	delivery, err := ws.enqueue(WebhookConfig{URL: url}, "", event, payload)
+	if err != nil {
+		t.Fatalf("enqueue() = %v", err)
+	}
+	return delivery
+}
+
+// attemptDue makes one attempt, in the foreground, at every delivery due at the given time
+func attemptDue(t *testing.T, ws *WebhookService, at time.Time) int {
+	t.Helper()
+
+	due, err := ws.store.Due(at)
+	if err != nil {
+		t.Fatalf("Due() = %v", err)
+	}
+	for _, delivery := range due {
+		ws.attempt(&delivery)
+	}
+	return len(due)
+}
+
+func getTestDelivery(t *testing.T, ws *WebhookService, id string) *Delivery {
+	t.Helper()
+
+	delivery, err := ws.store.Get(id)
+	if err != nil {
+		t.Fatalf("Get(%s) = %v", id, err)
+	}
+	return delivery
+}
+
+func TestDeliveryBackoff(t *testing.T) {
+	ws, _ := newTestWebhookService(t, filepath.Join(t.TempDir(), "test.db"), map[string]string{
+		"WEBHOOK_MAX_ATTEMPTS": "10",
+		"WEBHOOK_BACKOFF_BASE": "1m",
+		"WEBHOOK_BACKOFF_MAX":  "4m",
+	})
+	receiver := newTestReceiver(t, http.StatusServiceUnavailable)
+	delivery := enqueueTestDelivery(t, ws, receiver.URL)
+
+	// The delay doubles from the base up to the cap, then is jittered between half and all of it
+	next := delivery.NextAttemptAt
+	for attempt, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+		if n := attemptDue(t, ws, next); n != 1 {
+			t.Fatalf("attempt %d: %d deliveries due, want 1", attempt+1, n)
+		}
+
+		stored := getTestDelivery(t, ws, delivery.ID)
+		if stored.Status != DeliveryStatusPending || stored.Attempts != attempt+1 {
+			t.Fatalf("attempt %d: delivery is %s after %d attempts, want it pending", attempt+1, stored.Status, stored.Attempts)
+		}
+		if delay := stored.NextAttemptAt.Sub(stored.UpdatedAt); delay < want/2 || delay > want {
+			t.Fatalf("attempt %d: retrying after %s, want between %s and %s", attempt+1, delay, want/2, want)
+		}
+		// Nothing is sent again until the retry is due
+		if n := attemptDue(t, ws, stored.NextAttemptAt.Add(-time.Second)); n != 0 {
+			t.Fatalf("attempt %d: %d deliveries due before the retry time", attempt+1, n)
+		}
+		next = stored.NextAttemptAt
+	}
+
+	if got := len(receiver.deliveryIDs()); got != 5 {
+		t.Fatalf("receiver got %d requests, want 5", got)
+	}
+}
+
+func TestDeliveryRetryLimit(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		blocked      bool
+		wantStatus   DeliveryStatus
+		wantAttempts int
+		wantReason   string
+	}{
+		{name: "succeeds after transient failures", statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}, wantStatus: DeliveryStatusDelivered, wantAttempts: 3},
+		{name: "request timeout is retried", statuses: []int{http.StatusRequestTimeout, http.StatusNoContent}, wantStatus: DeliveryStatusDelivered, wantAttempts: 2},
+		{name: "gives up at the retry limit", statuses: []int{http.StatusInternalServerError}, wantStatus: DeliveryStatusDeadLettered, wantAttempts: 4, wantReason: "500"},
+		{name: "client error is not retried", statuses: []int{http.StatusNotFound}, wantStatus: DeliveryStatusDeadLettered, wantAttempts: 1, wantReason: "404"},
+		{name: "blocked destination is not retried", statuses: []int{http.StatusOK}, blocked: true, wantStatus: DeliveryStatusDeadLettered, wantAttempts: 1, wantReason: "destination blocked"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{"WEBHOOK_MAX_ATTEMPTS": "4", "WEBHOOK_BACKOFF_BASE": "1s"}
+			if tt.blocked {
+				env["WEBHOOK_ALLOWED_NETWORKS"] = ""
+			}
+			ws, _ := newTestWebhookService(t, filepath.Join(t.TempDir(), "test.db"), env)
+			var finished []Delivery
+			ws.OnFinished(func(delivery Delivery) { finished = append(finished, delivery) })
+
+			receiver := newTestReceiver(t, tt.statuses...)
+			delivery := enqueueTestDelivery(t, ws, receiver.URL)
+
+			// Retry as soon as each attempt allows, well past the limit
+			for i := 0; i < 10; i++ {
+				attemptDue(t, ws, time.Now().Add(time.Hour))
+			}
+
+			stored := getTestDelivery(t, ws, delivery.ID)
+			if stored.Status != tt.wantStatus || stored.Attempts != tt.wantAttempts {
+				t.Fatalf("delivery is %s after %d attempts, want %s after %d", stored.Status, stored.Attempts, tt.wantStatus, tt.wantAttempts)
+			}
+			attempts, err := ws.Attempts(delivery.ID)
+			if err != nil || len(attempts) != tt.wantAttempts {
+				t.Fatalf("Attempts() = %d attempts, %v; want %d", len(attempts), err, tt.wantAttempts)
+			}
+			if len(finished) != 1 || finished[0].Status != tt.wantStatus {
+				t.Fatalf("listeners were told about %+v, want one %s delivery", finished, tt.wantStatus)
+			}
+
+			wantRequests := tt.wantAttempts
+			if tt.blocked {
+				wantRequests = 0
+			}
+			if got := len(receiver.deliveryIDs()); got != wantRequests {
+				t.Fatalf("receiver got %d requests, want %d", got, wantRequests)
+			}
+
+			letters, err := ws.DeadLetters()
+			if err != nil {
+				t.Fatalf("DeadLetters() = %v", err)
+			}
+			if tt.wantStatus != DeliveryStatusDeadLettered {
+				if len(letters) != 0 {
+					t.Fatalf("DeadLetters() = %+v, want none", letters)
+				}
+				return
+			}
+			if len(letters) != 1 || letters[0].Delivery.ID != delivery.ID || !strings.Contains(letters[0].Reason, tt.wantReason) {
+				t.Fatalf("DeadLetters() = %+v, want %s dead-lettered for %q", letters, delivery.ID, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestQueuedDeliveriesSurviveRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	env := map[string]string{"WEBHOOK_SIGNING_SECRET": "s3cret"}
+	receiver := newTestReceiver(t, http.StatusOK)
+
+	ws, db := newTestWebhookService(t, path, env)
+	delivered := enqueueTestDelivery(t, ws, receiver.URL)
+	attemptDue(t, ws, time.Now())
+	// The worker never ran before the process stopped
+	pending := enqueueTestDelivery(t, ws, receiver.URL)
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ws, _ = newTestWebhookService(t, path, env)
+	finished := make(chan Delivery, 4)
+	ws.OnFinished(func(delivery Delivery) { finished <- delivery })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ws.Start(ctx)
+
+	select {
+	case delivery := <-finished:
+		if delivery.ID != pending.ID || delivery.Status != DeliveryStatusDelivered {
+			t.Fatalf("finished %s (%s), want %s delivered", delivery.ID, delivery.Status, pending.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the queued delivery was not sent after the restart")
+	}
+
+	// Only the delivery still queued is sent again, and it is signed like any other
+	select {
+	case delivery := <-finished:
+		t.Fatalf("finished %s as well", delivery.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if got := receiver.deliveryIDs(); len(got) != 2 || got[0] != delivered.ID || got[1] != pending.ID {
+		t.Fatalf("receiver got deliveries %v, want [%s %s]", got, delivered.ID, pending.ID)
+	}
+	receiver.mu.Lock()
+	header := receiver.received[1]
+	receiver.mu.Unlock()
+	if header.Get(signature.HeaderSignature) == "" || header.Get(signature.HeaderTimestamp) == "" {
+		t.Fatalf("the resent delivery was not signed")
+	}
+}
@@ -0,0 +1,156 @@
+// Package signature signs webhook payloads sent by the wrapper and verifies them on the receiving side.
+//
+// The signature is an HMAC-SHA256 over "<timestamp>.<body>" using a shared secret, sent as
+// "sha256=<hex>" in the X-CxWrapper-Signature header alongside X-CxWrapper-Timestamp (unix seconds)
+// and X-CxWrapper-Delivery (a stable ID reused across retries of the same delivery). The delivery ID is
+// not covered by the signature, so it identifies retries but must not be relied on to detect replays.
+package signature
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderSignature  = "X-CxWrapper-Signature"
+	HeaderTimestamp  = "X-CxWrapper-Timestamp"
+	HeaderDeliveryID = "X-CxWrapper-Delivery"
+
+	// DefaultTolerance is how far a timestamp may drift from the receiver's clock before it is rejected
+	DefaultTolerance = 5 * time.Minute
+
+	signaturePrefix = "sha256="
+)
+
+var (
+	ErrMissingHeaders   = errors.New("missing webhook signature headers")
+	ErrInvalidTimestamp = errors.New("invalid webhook timestamp")
+	ErrExpired          = errors.New("webhook timestamp outside tolerance")
+	ErrInvalidSignature = errors.New("webhook signature mismatch")
+	ErrReplayed         = errors.New("webhook delivery already received")
+)
+
+// Sign returns the signature header value for body sent at timestamp
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest sets the signature, timestamp and delivery ID headers on req
+func SignRequest(req *http.Request, secret []byte, deliveryID string, body []byte, now time.Time) {
+	timestamp := now.Unix()
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderDeliveryID, deliveryID)
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+}
+
+// Verify checks that signatureHeader matches body and that timestampHeader is within tolerance of now
+func Verify(secret []byte, signatureHeader, timestampHeader string, body []byte, tolerance time.Duration, now time.Time) error {
+	if signatureHeader == "" || timestampHeader == "" {
+		return ErrMissingHeaders
+	}
+
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTimestamp, err)
+	}
+
+	drift := now.Sub(time.Unix(timestamp, 0))
+	if drift < 0 {
+		drift = -drift
+	}
+	if drift > tolerance {
+		return ErrExpired
+	}
+
+	if !strings.HasPrefix(signatureHeader, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+	expected := Sign(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(signatureHeader)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// VerifyRequest reads and verifies the body of an incoming webhook request. The body is returned and
+// also restored on req so handlers can decode it again.
+func VerifyRequest(req *http.Request, secret []byte, tolerance time.Duration) ([]byte, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook body: %w", err)
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	err = Verify(secret, req.Header.Get(HeaderSignature), req.Header.Get(HeaderTimestamp), body, tolerance, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// ReplayGuard remembers verified signatures for a window so a captured request cannot be sent again
+// while its timestamp is still within tolerance. The signature binds the timestamp and body, unlike the
+// delivery ID header, which anyone replaying a request could change. Each retry is signed afresh, so
+// receivers that also want to skip retries of a delivery they already handled have to track delivery
+// IDs themselves, and should only call Seen after they have successfully handled a delivery.
+type ReplayGuard struct {
+	window time.Duration
+	mu     sync.Mutex
+	seen   map[string]time.Time
+}
+
+// NewReplayGuard creates a guard that remembers signatures for window, which should be at least twice
+// the signature tolerance since a timestamp is accepted that far either side of the receiver's clock
+func NewReplayGuard(window time.Duration) *ReplayGuard {
+	return &ReplayGuard{
+		window: window,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// Check returns ErrReplayed if signatureHeader, already checked by Verify, was recorded within the window
+func (g *ReplayGuard) Check(signatureHeader string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.evict(time.Now())
+	if _, ok := g.seen[signatureHeader]; ok {
+		return ErrReplayed
+	}
+	return nil
+}
+
+// Seen records signatureHeader as processed
+func (g *ReplayGuard) Seen(signatureHeader string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	g.evict(now)
+	g.seen[signatureHeader] = now
+}
+
+func (g *ReplayGuard) evict(now time.Time) {
+	for signature, at := range g.seen {
+		if now.Sub(at) > g.window {
+			delete(g.seen, signature)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package signature
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignKnownValue(t *testing.T) {
+	// HMAC-SHA256 of "1700000000.{}" keyed with "secret"
+	want := "sha256=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163"
+	if got := Sign([]byte("secret"), 1700000000, []byte("{}")); got != want {
+		t.Fatalf("Sign() = %q, want %q", got, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	secret := []byte("s3cret")
+	body := []byte(`{"event":"scan.completed","scan_id":"abc"}`)
+	now := time.Unix(1700000000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	sig := Sign(secret, now.Unix(), body)
+
+	tests := []struct {
+		name      string
+		secret    []byte
+		signature string
+		timestamp string
+		body      []byte
+		tolerance time.Duration
+		now       time.Time
+		want      error
+	}{
+		{name: "valid", secret: secret, signature: sig, timestamp: ts, body: body, tolerance: DefaultTolerance, now: now},
+		{name: "within tolerance in the past", secret: secret, signature: sig, timestamp: ts, body: body, tolerance: DefaultTolerance, now: now.Add(4 * time.Minute)},
+		{name: "within tolerance in the future", secret: secret, signature: sig, timestamp: ts, body: body, tolerance: DefaultTolerance, now: now.Add(-4 * time.Minute)},
+		{name: "exactly at tolerance", secret: secret, signature: sig, timestamp: ts, body: body, tolerance: DefaultTolerance, now: now.Add(DefaultTolerance)},
+		{name: "too old", secret: secret, signature: sig, timestamp: ts, body: body, tolerance: DefaultTolerance, now: now.Add(DefaultTolerance + time.Second), want: ErrExpired},
+		{name: "too far in the future", secret: secret, signature: sig, timestamp: ts, body: body, tolerance: DefaultTolerance, now: now.Add(-DefaultTolerance - time.Second), want: ErrExpired},
+		{name: "tampered body", secret: secret, signature: sig, timestamp: ts, body: []byte(`{"event":"scan.completed","scan_id":"abd"}`), tolerance: DefaultTolerance, now: now, want: ErrInvalidSignature},
+		{name: "empty body", secret: secret, signature: sig, timestamp: ts, body: nil, tolerance: DefaultTolerance, now: now, want: ErrInvalidSignature},
+		{name: "wrong secret", secret: []byte("other"), signature: sig, timestamp: ts, body: body, tolerance: DefaultTolerance, now: now, want: ErrInvalidSignature},
+		{name: "timestamp replaced", secret: secret, signature: sig, timestamp: strconv.FormatInt(now.Unix()+1, 10), body: body, tolerance: DefaultTolerance, now: now, want: ErrInvalidSignature},
+		{name: "missing prefix", secret: secret, signature: strings.TrimPrefix(sig, "sha256="), timestamp: ts, body: body, tolerance: DefaultTolerance, now: now, want: ErrInvalidSignature},
+		{name: "upper-case digest", secret: secret, signature: "sha256=" + strings.ToUpper(strings.TrimPrefix(sig, "sha256=")), timestamp: ts, body: body, tolerance: DefaultTolerance, now: now, want: ErrInvalidSignature},
+		{name: "missing signature", secret: secret, timestamp: ts, body: body, tolerance: DefaultTolerance, now: now, want: ErrMissingHeaders},
+		{name: "missing timestamp", secret: secret, signature: sig, body: body, tolerance: DefaultTolerance, now: now, want: ErrMissingHeaders},
+		{name: "malformed timestamp", secret: secret, signature: sig, timestamp: "yesterday", body: body, tolerance: DefaultTolerance, now: now, want: ErrInvalidTimestamp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.signature, tt.timestamp, tt.body, tt.tolerance, tt.now)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Verify() = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignAndVerifyRequest(t *testing.T) {
+	secret := []byte("s3cret")
+	body := []byte(`{"scan_id":"abc"}`)
+
+	tests := []struct {
+		name   string
+		tamper func(req *http.Request)
+		body   string
+		want   error
+	}{
+		{name: "round trip", body: string(body)},
+		{name: "tampered body", body: `{"scan_id":"xyz"}`, want: ErrInvalidSignature},
+		{name: "stripped signature", body: string(body), tamper: func(req *http.Request) { req.Header.Del(HeaderSignature) }, want: ErrMissingHeaders},
+		{name: "stale timestamp", body: string(body), tamper: func(req *http.Request) {
+			stale := time.Now().Add(-time.Hour)
+			SignRequest(req, secret, "delivery-1", body, stale)
+		}, want: ErrExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(tt.body))
+			SignRequest(req, secret, "delivery-1", body, time.Now())
+			if tt.tamper != nil {
+				tt.tamper(req)
+			}
+
+			got, err := VerifyRequest(req, secret, DefaultTolerance)
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("VerifyRequest() = %v, want %v", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyRequest() = %v", err)
+			}
+			if string(got) != tt.body {
+				t.Fatalf("VerifyRequest() body = %q, want %q", got, tt.body)
+			}
+			if req.Header.Get(HeaderDeliveryID) != "delivery-1" {
+				t.Fatalf("delivery ID header = %q", req.Header.Get(HeaderDeliveryID))
+			}
+
+			// The body is restored so handlers can read it again
+			again := make([]byte, len(tt.body)+1)
+			n, _ := req.Body.Read(again)
+			if string(again[:n]) != tt.body {
+				t.Fatalf("restored body = %q, want %q", again[:n], tt.body)
+			}
+		})
+	}
+}
+
+func TestReplayGuard(t *testing.T) {
+	secret := []byte("s3cret")
+	body := []byte(`{"scan_id":"abc"}`)
+	guard := NewReplayGuard(2 * DefaultTolerance)
+
+	// handle stands in for a receiver that verifies a request, rejects replays and records what it handled
+	handle := func(req *http.Request) error {
+		if _, err := VerifyRequest(req, secret, DefaultTolerance); err != nil {
+			return err
+		}
+		sig := req.Header.Get(HeaderSignature)
+		if err := guard.Check(sig); err != nil {
+			return err
+		}
+		guard.Seen(sig)
+		return nil
+	}
+	newRequest := func(deliveryID string, now time.Time) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/hook", bytes.NewReader(body))
+		SignRequest(req, secret, deliveryID, body, now)
+		return req
+	}
+
+	now := time.Now()
+	original := newRequest("d1", now)
+	if err := handle(original); err != nil {
+		t.Fatalf("handling a new delivery = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want error
+	}{
+		{name: "same request again", req: newRequest("d1", now), want: ErrReplayed},
+		{name: "captured request with a new delivery ID", req: func() *http.Request {
+			req := newRequest("d1", now)
+			req.Header.Set(HeaderDeliveryID, "d2")
+			return req
+		}(), want: ErrReplayed},
+		{name: "retry signed at a later time", req: newRequest("d1", now.Add(time.Second))},
+		{name: "another delivery", req: newRequest("d3", now.Add(2*time.Second))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := handle(tt.req); !errors.Is(err, tt.want) {
+				t.Fatalf("handle() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Entries older than the window are forgotten
+	sig := original.Header.Get(HeaderSignature)
+	guard.mu.Lock()
+	guard.seen[sig] = now.Add(-3 * DefaultTolerance)
+	guard.mu.Unlock()
+	if err := guard.Check(sig); err != nil {
+		t.Fatalf("Check() after the window = %v", err)
+	}
+}
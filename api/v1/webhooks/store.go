@@ -0,0 +1,289 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	deliveriesBucket    = []byte("webhook_deliveries")
+	queueBucket         = []byte("webhook_queue")
+	attemptsBucket      = []byte("webhook_attempts")
+	deadLettersBucket   = []byte("webhook_dead_letters")
+	subscriptionsBucket = []byte("webhook_subscriptions")
+)
+
+// DeliveryStore persists deliveries, the outbound queue, attempt records and dead letters
+type DeliveryStore struct {
+	db *bolt.DB
+}
+
+func NewDeliveryStore(db *bolt.DB) (*DeliveryStore, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{deliveriesBucket, queueBucket, attemptsBucket, deadLettersBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize webhook delivery store: %w", err)
+	}
+
+	return &DeliveryStore{db: db}, nil
+}
+
+// Enqueue stores a new pending delivery and adds it to the outbound queue
+func (s *DeliveryStore) Enqueue(delivery *Delivery) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := putJSON(tx.Bucket(deliveriesBucket), delivery.ID, delivery); err != nil {
+			return err
+		}
+		return tx.Bucket(queueBucket).Put([]byte(delivery.ID), []byte{1})
+	})
+}
+
+// Get returns the delivery with the given ID
+func (s *DeliveryStore) Get(id string) (*Delivery, error) {
+	var delivery Delivery
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getJSON(tx.Bucket(deliveriesBucket), id, &delivery)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// Due returns queued deliveries whose next attempt is at or before now
+func (s *DeliveryStore) Due(now time.Time) ([]Delivery, error) {
+	var due []Delivery
+	err := s.db.View(func(tx *bolt.Tx) error {
+		deliveries := tx.Bucket(deliveriesBucket)
+		return tx.Bucket(queueBucket).ForEach(func(id, _ []byte) error {
+			var delivery Delivery
+			if err := getJSON(deliveries, string(id), &delivery); err != nil {
+				return err
+			}
+			if !delivery.NextAttemptAt.After(now) {
+				due = append(due, delivery)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook queue: %w", err)
+	}
+
+	return due, nil
+}
+
+// RecordAttempt stores the attempt and the updated delivery, removing it from the queue once it is no longer pending
+func (s *DeliveryStore) RecordAttempt(delivery *Delivery, attempt DeliveryAttempt) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := putJSON(tx.Bucket(attemptsBucket), attemptKey(delivery.ID, attempt.Number), attempt); err != nil {
+			return err
+		}
+		if err := putJSON(tx.Bucket(deliveriesBucket), delivery.ID, delivery); err != nil {
+			return err
+		}
+		if delivery.Status != DeliveryStatusPending {
+			return tx.Bucket(queueBucket).Delete([]byte(delivery.ID))
+		}
+		return nil
+	})
+}
+
+// Attempts returns the recorded attempts for a delivery in order
+func (s *DeliveryStore) Attempts(deliveryID string) ([]DeliveryAttempt, error) {
+	var attempts []DeliveryAttempt
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(attemptsBucket).Cursor()
+		prefix := []byte(deliveryID + "/")
+		for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			var attempt DeliveryAttempt
+			if err := json.Unmarshal(v, &attempt); err != nil {
+				return err
+			}
+			attempts = append(attempts, attempt)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attempts for delivery %s: %w", deliveryID, err)
+	}
+
+	return attempts, nil
+}
+
+// List returns deliveries matching the filter, newest first
+func (s *DeliveryStore) List(filter DeliveryFilter) ([]Delivery, error) {
+	var deliveries []Delivery
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(deliveriesBucket).ForEach(func(_, v []byte) error {
+			var delivery Delivery
+			if err := json.Unmarshal(v, &delivery); err != nil {
+				return err
+			}
+			if filter.matches(&delivery) {
+				deliveries = append(deliveries, delivery)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+
+	return deliveries, nil
+}
+
+func (f DeliveryFilter) matches(delivery *Delivery) bool {
+	if f.ScanID != "" && delivery.ScanID != f.ScanID {
+		return false
+	}
+	if f.CommitID != "" && delivery.CommitID != f.CommitID {
+		return false
+	}
+	if f.SubscriptionID != "" && delivery.SubscriptionID != f.SubscriptionID {
+		return false
+	}
+	if f.Status != "" && delivery.Status != f.Status {
+		return false
+	}
+	return true
+}
+
+// DeadLetter moves a delivery out of the queue and into the dead-letter store
+func (s *DeliveryStore) DeadLetter(delivery *Delivery, reason string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		letter := DeadLetter{
+			Delivery:       *delivery,
+			Reason:         reason,
+			DeadLetteredAt: time.Now().UTC(),
+		}
+		if err := putJSON(tx.Bucket(deadLettersBucket), delivery.ID, letter); err != nil {
+			return err
+		}
+		return tx.Bucket(queueBucket).Delete([]byte(delivery.ID))
+	})
+}
+
+// DeadLetters returns every dead-lettered delivery
+func (s *DeliveryStore) DeadLetters() ([]DeadLetter, error) {
+	var letters []DeadLetter
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(deadLettersBucket).ForEach(func(_, v []byte) error {
+			var letter DeadLetter
+			if err := json.Unmarshal(v, &letter); err != nil {
+				return err
+			}
+			letters = append(letters, letter)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letters: %w", err)
+	}
+
+	return letters, nil
+}
+
+// SubscriptionStore persists webhook subscriptions
+type SubscriptionStore struct {
+	db *bolt.DB
+}
+
+func NewSubscriptionStore(db *bolt.DB) (*SubscriptionStore, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(subscriptionsBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize webhook subscription store: %w", err)
+	}
+
+	return &SubscriptionStore{db: db}, nil
+}
+
+// Save creates or replaces a subscription
+func (s *SubscriptionStore) Save(sub *Subscription) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(subscriptionsBucket), sub.ID, sub)
+	})
+}
+
+// Get returns the subscription with the given ID
+func (s *SubscriptionStore) Get(id string) (*Subscription, error) {
+	var sub Subscription
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getJSON(tx.Bucket(subscriptionsBucket), id, &sub)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("subscription not found: %s", id)
+	}
+
+	return &sub, nil
+}
+
+// Delete removes a subscription
+func (s *SubscriptionStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(subscriptionsBucket)
+		if bucket.Get([]byte(id)) == nil {
+			return fmt.Errorf("subscription not found: %s", id)
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
+
+// List returns every subscription
+func (s *SubscriptionStore) List() ([]Subscription, error) {
+	var subs []Subscription
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(subscriptionsBucket).ForEach(func(_, v []byte) error {
+			var sub Subscription
+			if err := json.Unmarshal(v, &sub); err != nil {
+				return err
+			}
+			subs = append(subs, sub)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
+
+func attemptKey(deliveryID string, number int) string {
+	return fmt.Sprintf("%s/%06d", deliveryID, number)
+}
+
+func putJSON(bucket *bolt.Bucket, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", key, err)
+	}
+	return bucket.Put([]byte(key), data)
+}
+
+func getJSON(bucket *bolt.Bucket, key string, value interface{}) error {
+	data := bucket.Get([]byte(key))
+	if data == nil {
+		return fmt.Errorf("not found: %s", key)
+	}
+	return json.Unmarshal(data, value)
+}
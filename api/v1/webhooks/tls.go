@@ -0,0 +1,158 @@
+// api/v1/webhooks/tls.go
+package webhooks
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	// ErrInvalidTLSSettings and ErrTLSHandshake mark TLS failures that retrying will not fix, so the
+	// delivery is dead-lettered at once with the reason rather than retried through the backoff schedule
+	ErrInvalidTLSSettings = errors.New("invalid webhook TLS settings")
+	ErrTLSHandshake       = errors.New("TLS handshake failed")
+)
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// defaultTLSFromEnv reads the TLS settings used for receivers without their own: WEBHOOK_TLS_CA_FILE,
+// WEBHOOK_TLS_CERT_FILE and WEBHOOK_TLS_KEY_FILE (PEM files) and WEBHOOK_TLS_MIN_VERSION ("1.2" or "1.3")
+func defaultTLSFromEnv() (*TLSSettings, error) {
+	settings := &TLSSettings{
+		MinVersion: os.Getenv("WEBHOOK_TLS_MIN_VERSION"),
+	}
+
+	files := map[string]*string{
+		"WEBHOOK_TLS_CA_FILE":   &settings.CABundle,
+		"WEBHOOK_TLS_CERT_FILE": &settings.ClientCert,
+		"WEBHOOK_TLS_KEY_FILE":  &settings.ClientKey,
+	}
+	for env, field := range files {
+		file := os.Getenv(env)
+		if file == "" {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", env, err)
+		}
+		*field = string(data)
+	}
+
+	if _, err := buildTLSConfig(settings); err != nil {
+		return nil, fmt.Errorf("invalid webhook TLS settings: %w", err)
+	}
+
+	return settings, nil
+}
+
+// tlsSettingsKey identifies a set of TLS settings without keeping the client key in memory as a map key
+func tlsSettingsKey(settings *TLSSettings) (string, error) {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// buildTLSConfig turns TLS settings into a client config. Certificates are always verified against the
+// system roots plus the configured CA bundle unless verification is explicitly disabled.
+func buildTLSConfig(settings *TLSSettings) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if settings == nil {
+		return config, nil
+	}
+
+	if settings.MinVersion != "" {
+		version, ok := tlsVersions[settings.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported min_version %q: use 1.2 or 1.3", settings.MinVersion)
+		}
+		config.MinVersion = version
+	}
+
+	if settings.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(settings.CABundle)) {
+			return nil, fmt.Errorf("ca_bundle does not contain any PEM certificates")
+		}
+		config.RootCAs = pool
+	}
+
+	if settings.ClientCert != "" || settings.ClientKey != "" {
+		if settings.ClientCert == "" || settings.ClientKey == "" {
+			return nil, fmt.Errorf("client_cert and client_key must be set together")
+		}
+		cert, err := tls.X509KeyPair([]byte(settings.ClientCert), []byte(settings.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	config.InsecureSkipVerify = settings.InsecureSkipVerify
+
+	return config, nil
+}
+
+func validateTLSSettings(settings *TLSSettings) error {
+	if _, err := buildTLSConfig(settings); err != nil {
+		return fmt.Errorf("invalid tls settings: %w", err)
+	}
+	return nil
+}
+
+// tlsSettingsFor returns the TLS settings for a delivery: the subscription's own, or the global defaults
+func (ws *WebhookService) tlsSettingsFor(delivery *Delivery) *TLSSettings {
+	if delivery.SubscriptionID != "" {
+		if sub, err := ws.subscriptions.Get(delivery.SubscriptionID); err == nil && sub.TLS != nil {
+			return sub.TLS
+		}
+	}
+	return ws.defaultTLS
+}
+
+// describeTLSError rewrites certificate and handshake failures into a message that says what to fix,
+// keeping the original error wrapped. Other errors are returned unchanged.
+func describeTLSError(err error) error {
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostname         x509.HostnameError
+		invalid          x509.CertificateInvalidError
+		verification     *tls.CertificateVerificationError
+		alert            tls.AlertError
+		recordHeader     tls.RecordHeaderError
+	)
+
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return fmt.Errorf("%w: receiver certificate is signed by an unknown authority (set tls.ca_bundle): %w", ErrTLSHandshake, err)
+	case errors.As(err, &hostname):
+		return fmt.Errorf("%w: receiver certificate is not valid for host %s: %w", ErrTLSHandshake, hostname.Host, err)
+	case errors.As(err, &invalid):
+		return fmt.Errorf("%w: receiver certificate is invalid: %w", ErrTLSHandshake, err)
+	case errors.As(err, &verification):
+		return fmt.Errorf("%w: receiver certificate could not be verified: %w", ErrTLSHandshake, err)
+	case errors.As(err, &alert):
+		return fmt.Errorf("%w: receiver rejected the connection (check tls.client_cert and tls.min_version): %w", ErrTLSHandshake, err)
+	case errors.As(err, &recordHeader):
+		return fmt.Errorf("%w: receiver did not answer with TLS (is it plain http?): %w", ErrTLSHandshake, err)
+	}
+
+	return err
+}
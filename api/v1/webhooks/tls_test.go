@@ -0,0 +1,86 @@
+package webhooks
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTLSFailuresAreNotRetried(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The test server's certificate is self-signed, so verifying it against the system roots fails
+	config, err := buildTLSConfig(&TLSSettings{})
+	if err != nil {
+		t.Fatalf("buildTLSConfig() = %v", err)
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+	_, err = client.Get(server.URL)
+	if err == nil {
+		t.Fatal("expected the handshake to fail")
+	}
+	handshakeErr := describeTLSError(err)
+
+	_, configErr := buildTLSConfig(&TLSSettings{CABundle: "not a certificate"})
+	if configErr == nil {
+		t.Fatal("expected an invalid ca_bundle to be rejected")
+	}
+
+	tests := []struct {
+		name       string
+		statusCode int
+		err        error
+		want       bool
+	}{
+		{name: "handshake failure", err: handshakeErr, want: false},
+		{name: "invalid TLS settings", err: fmt.Errorf("%w: %w", ErrInvalidTLSSettings, configErr), want: false},
+		{name: "blocked destination", err: fmt.Errorf("%w: address is private", ErrDestinationBlocked), want: false},
+		{name: "connection refused", err: errors.New("dial tcp: connection refused"), want: true},
+		{name: "server error", statusCode: http.StatusBadGateway, err: errors.New("502"), want: true},
+		{name: "rate limited", statusCode: http.StatusTooManyRequests, err: errors.New("429"), want: true},
+		{name: "request timeout", statusCode: http.StatusRequestTimeout, err: errors.New("408"), want: true},
+		{name: "client error", statusCode: http.StatusNotFound, err: errors.New("404"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.statusCode, tt.err); got != tt.want {
+				t.Fatalf("isRetryable(%d, %v) = %v, want %v", tt.statusCode, tt.err, got, tt.want)
+			}
+		})
+	}
+
+	if !errors.Is(handshakeErr, ErrTLSHandshake) {
+		t.Fatalf("describeTLSError() = %v, want it to wrap ErrTLSHandshake", handshakeErr)
+	}
+}
+
+func TestTransportsAreSharedPerTLSSettings(t *testing.T) {
+	ws := &WebhookService{destinations: &DestinationPolicy{}, transports: make(map[string]*http.Transport)}
+
+	first, err := ws.transportFor(nil)
+	if err != nil {
+		t.Fatalf("transportFor() = %v", err)
+	}
+	if again, _ := ws.transportFor(nil); again != first {
+		t.Fatalf("transportFor() built a second transport for the same settings")
+	}
+	pinned, err := ws.transportFor(&TLSSettings{MinVersion: "1.3"})
+	if err != nil {
+		t.Fatalf("transportFor() = %v", err)
+	}
+	if pinned == first || pinned.TLSClientConfig.MinVersion != tls.VersionTLS13 {
+		t.Fatalf("transportFor() shared a transport across different TLS settings")
+	}
+
+	if _, err := ws.transportFor(&TLSSettings{MinVersion: "1.0"}); !errors.Is(err, ErrInvalidTLSSettings) {
+		t.Fatalf("transportFor() = %v, want %v", err, ErrInvalidTLSSettings)
+	}
+	if len(ws.transports) != 2 {
+		t.Fatalf("cached %d transports, want 2", len(ws.transports))
+	}
+}
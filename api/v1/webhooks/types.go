@@ -0,0 +1,305 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookConfig holds webhook configuration
+type WebhookConfig struct {
+	URL     string            `json:"url"`
+	Timeout time.Duration     `json:"timeout"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// TLSSettings controls how https receivers are connected to. Certificates are PEM encoded.
+type TLSSettings struct {
+	CABundle           string `json:"ca_bundle,omitempty"`
+	ClientCert         string `json:"client_cert,omitempty"`
+	ClientKey          string `json:"client_key,omitempty"`
+	MinVersion         string `json:"min_version,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// Event types a subscription can filter on
+const (
+	EventScanQueued         = "scan.queued"
+	EventScanRunning        = "scan.running"
+	EventScanCompleted      = "scan.completed"
+	EventScanPartial        = "scan.partial"
+	EventScanFailed         = "scan.failed"
+	EventScanCanceled       = "scan.canceled"
+	EventScanEngineProgress = "scan.engine_progress"
+	// EventScanSuperseded is sent instead of scan.canceled when a newer scan of the branch replaced the scan
+	EventScanSuperseded = "scan.superseded"
+)
+
+var knownEvents = map[string]bool{
+	EventScanQueued:         true,
+	EventScanRunning:        true,
+	EventScanCompleted:      true,
+	EventScanPartial:        true,
+	EventScanFailed:         true,
+	EventScanCanceled:       true,
+	EventScanEngineProgress: true,
+	EventScanSuperseded:     true,
+}
+
+// Subscription routes events for matching scans to a receiver URL
+type Subscription struct {
+	ID             string            `json:"id"`
+	Name           string            `json:"name"`
+	URL            string            `json:"url"`
+	AppName        string            `json:"app_name,omitempty"`
+	ProjectName    string            `json:"project_name,omitempty"`
+	BranchPattern  string            `json:"branch_pattern,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Secret         string            `json:"secret,omitempty"`
+	TLS            *TLSSettings      `json:"tls,omitempty"`
+	Events         []string          `json:"events,omitempty"`
+	PayloadFormat  string            `json:"payload_format,omitempty"`
+	PayloadVersion string            `json:"payload_version,omitempty"`
+	Enabled        bool              `json:"enabled"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// Event is something that happened to a scan, published to every matching subscription
+type Event struct {
+	ID          string
+	Type        string
+	ScanID      string
+	CommitID    string
+	AppName     string
+	ProjectName string
+	Branch      string
+	Time        time.Time
+	// Payloads holds the JSON data of the event for each payload version
+	Payloads map[string][]byte
+}
+
+// ScanEventV2 is the data of every scan event from payload version v2 on
+type ScanEventV2 struct {
+	SchemaVersion  string           `json:"schema_version"`
+	Event          string           `json:"event"`
+	ScanID         string           `json:"scan_id"`
+	CommitID       string           `json:"commit_id"`
+	AppName        string           `json:"app_name,omitempty"`
+	ProjectID      string           `json:"project_id,omitempty"`
+	ProjectName    string           `json:"project_name"`
+	Branch         string           `json:"branch"`
+	Status         string           `json:"status"`
+	PreviousStatus string           `json:"previous_status,omitempty"`
+	OccurredAt     time.Time        `json:"occurred_at"`
+	Engines        []EngineStatusV2 `json:"engines,omitempty"`
+	Engine         *EngineStatusV2  `json:"engine,omitempty"`
+	Result         *ScanResultV2    `json:"result,omitempty"`
+	// SupersededBy is the commit whose scan replaced this one, set on scan.superseded
+	SupersededBy string `json:"superseded_by,omitempty"`
+}
+
+type EngineStatusV2 struct {
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	PreviousStatus string `json:"previous_status,omitempty"`
+	Details        string `json:"details,omitempty"`
+}
+
+// ScanResultV2 summarises a completed scan without the raw findings
+type ScanResultV2 struct {
+	Link            string `json:"link"`
+	IsFastScan      bool   `json:"is_fast_scan"`
+	IsPolicyBlocked bool   `json:"is_policy_blocked"`
+	TotalResults    int    `json:"total_results"`
+	PolicyWarning   string `json:"policy_warning,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending      DeliveryStatus = "pending"
+	DeliveryStatusDelivered    DeliveryStatus = "delivered"
+	DeliveryStatusDeadLettered DeliveryStatus = "dead_lettered"
+)
+
+// Delivery is a single outbound webhook call and its retry state
+type Delivery struct {
+	ID             string            `json:"id"`
+	ScanID         string            `json:"scan_id"`
+	CommitID       string            `json:"commit_id,omitempty"`
+	EventType      string            `json:"event_type"`
+	SubscriptionID string            `json:"subscription_id,omitempty"`
+	Target         WebhookConfig     `json:"target"`
+	ContentType    string            `json:"content_type"`
+	EventHeaders   map[string]string `json:"event_headers,omitempty"`
+	Payload        json.RawMessage   `json:"payload"`
+	Status         DeliveryStatus    `json:"status"`
+	Attempts       int               `json:"attempts"`
+	NextAttemptAt  time.Time         `json:"next_attempt_at"`
+	LastError      string            `json:"last_error,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	DeliveredAt    *time.Time        `json:"delivered_at,omitempty"`
+	// ReplayOf is the ID of the delivery this one re-sends, if it was created by a replay
+	ReplayOf string `json:"replay_of,omitempty"`
+}
+
+// DeliveryFilter narrows a delivery listing; empty fields match everything
+type DeliveryFilter struct {
+	ScanID         string
+	CommitID       string
+	SubscriptionID string
+	Status         DeliveryStatus
+}
+
+// DeliveryAttempt records the outcome of one HTTP call made for a delivery
+type DeliveryAttempt struct {
+	DeliveryID string    `json:"delivery_id"`
+	Number     int       `json:"number"`
+	StartedAt  time.Time `json:"started_at"`
+	LatencyMs  int64     `json:"latency_ms"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// DeadLetter is a delivery that was given up on after its final attempt
+type DeadLetter struct {
+	Delivery       Delivery  `json:"delivery"`
+	Reason         string    `json:"reason"`
+	DeadLetteredAt time.Time `json:"dead_lettered_at"`
+}
+
+// RetryPolicy controls how failed deliveries are retried
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DeliveryListener is notified when a delivery succeeds or is dead-lettered
+type DeliveryListener func(delivery Delivery)
+
+// Request/response models for the subscriptions API
+
+type CreateSubscriptionRequest struct {
+	Name           string            `json:"name" binding:"required"`
+	URL            string            `json:"url" binding:"required"`
+	AppName        string            `json:"app_name,omitempty"`
+	ProjectName    string            `json:"project_name,omitempty"`
+	BranchPattern  string            `json:"branch_pattern,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Secret         string            `json:"secret,omitempty"`
+	TLS            *TLSSettings      `json:"tls,omitempty"`
+	Events         []string          `json:"events,omitempty"`
+	PayloadFormat  string            `json:"payload_format,omitempty"`
+	PayloadVersion string            `json:"payload_version,omitempty"`
+	Enabled        *bool             `json:"enabled,omitempty"`
+}
+
+type UpdateSubscriptionRequest struct {
+	Name           *string            `json:"name,omitempty"`
+	URL            *string            `json:"url,omitempty"`
+	AppName        *string            `json:"app_name,omitempty"`
+	ProjectName    *string            `json:"project_name,omitempty"`
+	BranchPattern  *string            `json:"branch_pattern,omitempty"`
+	Headers        *map[string]string `json:"headers,omitempty"`
+	Secret         *string            `json:"secret,omitempty"`
+	TLS            *TLSSettings       `json:"tls,omitempty"`
+	Events         *[]string          `json:"events,omitempty"`
+	PayloadFormat  *string            `json:"payload_format,omitempty"`
+	PayloadVersion *string            `json:"payload_version,omitempty"`
+	Enabled        *bool              `json:"enabled,omitempty"`
+}
+
+// SubscriptionResponse is a subscription as returned by the API, with the secret and header values withheld
+type SubscriptionResponse struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	URL           string `json:"url"`
+	AppName       string `json:"app_name,omitempty"`
+	ProjectName   string `json:"project_name,omitempty"`
+	BranchPattern string `json:"branch_pattern,omitempty"`
+	// HeaderNames lists the custom headers sent with every delivery; their values often carry credentials
+	HeaderNames    []string  `json:"header_names,omitempty"`
+	HasSecret      bool      `json:"has_secret"`
+	TLS            *TLSInfo  `json:"tls,omitempty"`
+	Events         []string  `json:"events,omitempty"`
+	PayloadFormat  string    `json:"payload_format"`
+	PayloadVersion string    `json:"payload_version"`
+	Enabled        bool      `json:"enabled"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TLSInfo summarises a subscription's TLS settings without exposing the client key
+type TLSInfo struct {
+	HasCABundle        bool   `json:"has_ca_bundle"`
+	HasClientCert      bool   `json:"has_client_cert"`
+	MinVersion         string `json:"min_version,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+}
+
+type SubscriptionListResponse struct {
+	Subscriptions []SubscriptionResponse `json:"subscriptions"`
+	Total         int                    `json:"total"`
+}
+
+// DeliveryResponse is a delivery with its attempt history, with the target's header values withheld
+type DeliveryResponse struct {
+	ID             string            `json:"id"`
+	ScanID         string            `json:"scan_id"`
+	CommitID       string            `json:"commit_id,omitempty"`
+	EventType      string            `json:"event_type"`
+	SubscriptionID string            `json:"subscription_id,omitempty"`
+	Target         TargetResponse    `json:"target"`
+	ContentType    string            `json:"content_type"`
+	EventHeaders   map[string]string `json:"event_headers,omitempty"`
+	Payload        json.RawMessage   `json:"payload"`
+	Status         DeliveryStatus    `json:"status"`
+	Attempts       int               `json:"attempts"`
+	NextAttemptAt  time.Time         `json:"next_attempt_at"`
+	LastError      string            `json:"last_error,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	DeliveredAt    *time.Time        `json:"delivered_at,omitempty"`
+	ReplayOf       string            `json:"replay_of,omitempty"`
+	LastStatusCode int               `json:"last_status_code,omitempty"`
+	LastLatencyMs  int64             `json:"last_latency_ms,omitempty"`
+	AttemptHistory []DeliveryAttempt `json:"attempt_history"`
+}
+
+// TargetResponse is where a delivery is sent. Like SubscriptionResponse it lists only the names of the
+// custom headers, whose values are the subscription's credentials.
+type TargetResponse struct {
+	URL string `json:"url"`
+	// Timeout is a duration string such as "30s"
+	Timeout     string   `json:"timeout,omitempty"`
+	HeaderNames []string `json:"header_names,omitempty"`
+}
+
+type DeliveryListResponse struct {
+	Deliveries []DeliveryResponse `json:"deliveries"`
+	Total      int                `json:"total"`
+	Limit      int                `json:"limit"`
+	Offset     int                `json:"offset"`
+}
+
+// ReplayDeliveryRequest optionally overrides the URL a stored payload is re-sent to
+type ReplayDeliveryRequest struct {
+	URL string `json:"url,omitempty"`
+}
+
+// SchemaInfo describes one published payload schema
+type SchemaInfo struct {
+	Event      string `json:"event"`
+	Version    string `json:"version"`
+	DataSchema string `json:"dataschema"`
+}
+
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	Details   string `json:"details,omitempty"`
+	Timestamp string `json:"timestamp"`
+	Path      string `json:"path"`
+}